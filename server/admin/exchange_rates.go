@@ -0,0 +1,66 @@
+package admin
+
+import (
+	"errors"
+	"net/http"
+	"raffle_web_server/exchange"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+)
+
+// ExchangeRateInput representa la tasa cargada por un administrador
+type ExchangeRateInput struct {
+	Date string          `json:"date"` // YYYY-MM-DD; vacío para el día actual
+	From string          `json:"from"`
+	To   string          `json:"to"`
+	Rate decimal.Decimal `json:"rate"`
+}
+
+// listExchangeRatesEndpoint maneja el endpoint GET /api/v1/admin/exchange-rates?from=USD&to=VES
+func listExchangeRatesEndpoint(c *gin.Context) {
+	rates, err := exchangeService.History(c.DefaultQuery("from", "USD"), c.DefaultQuery("to", "VES"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal Server Error",
+			"message": "Unable to list exchange rates",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, rates)
+}
+
+// setExchangeRateEndpoint maneja el endpoint PUT /api/v1/admin/exchange-rates
+func setExchangeRateEndpoint(c *gin.Context) {
+	var input ExchangeRateInput
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"message": "Please check your request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	rate, err := exchangeService.SetRate(input.Date, input.From, input.To, input.Rate)
+	if errors.Is(err, exchange.ErrInvalidRate) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid exchange rate",
+			"message": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal Server Error",
+			"message": "Unable to save the exchange rate",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, rate)
+}
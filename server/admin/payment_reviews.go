@@ -0,0 +1,131 @@
+package admin
+
+import (
+	"errors"
+	"net/http"
+	"raffle_web_server/booking"
+	"raffle_web_server/store"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PaymentReview es un pago manual pendiente de revisión, con los datos del comprador
+type PaymentReview struct {
+	store.Booking
+	Participant *store.Participant `json:"participant,omitempty"`
+}
+
+// PaymentReviewInput representa la decisión de un administrador sobre un comprobante
+type PaymentReviewInput struct {
+	Note string `json:"note"` // obligatoria al rechazar
+}
+
+// respondReviewError traduce los errores de la revisión de pagos a respuestas HTTP
+func respondReviewError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Booking not found",
+			"message": "No booking found with the given ID",
+		})
+	case errors.Is(err, booking.ErrInvalidTransition):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Invalid booking status",
+			"message": err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal Server Error",
+			"message": "Unable to process the payment review",
+			"details": err.Error(),
+		})
+	}
+}
+
+// listPaymentReviewsEndpoint maneja el endpoint GET /api/v1/admin/payment-reviews
+func listPaymentReviewsEndpoint(c *gin.Context) {
+	bookings, err := bookingService.PendingReviews()
+	if err != nil {
+		respondReviewError(c, err)
+		return
+	}
+
+	reviews := make([]PaymentReview, 0, len(bookings))
+	for _, pending := range bookings {
+		review := PaymentReview{Booking: pending}
+
+		participant, err := raffleRepository.GetParticipant(pending.ParticipantId)
+		if err == nil {
+			review.Participant = participant
+		} else if !errors.Is(err, store.ErrNotFound) {
+			respondReviewError(c, err)
+			return
+		}
+
+		reviews = append(reviews, review)
+	}
+
+	c.JSON(http.StatusOK, reviews)
+}
+
+// getPaymentReceiptEndpoint maneja el endpoint GET /api/v1/admin/payment-reviews/:id/receipt
+func getPaymentReceiptEndpoint(c *gin.Context) {
+	current, err := bookingService.Get(c.Param("id"))
+	if err != nil {
+		respondReviewError(c, err)
+		return
+	}
+
+	if current.Proof == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Receipt not found",
+			"message": "The booking has no payment proof",
+		})
+		return
+	}
+
+	path, err := paymentReceipts.Path(current.Proof.ReceiptFile)
+	if err != nil {
+		respondReviewError(c, err)
+		return
+	}
+
+	c.Header("Content-Type", current.Proof.ReceiptType)
+	c.File(path)
+}
+
+// reviewPaymentEndpoint maneja los endpoints POST /api/v1/admin/payment-reviews/:id/approve y /reject
+func reviewPaymentEndpoint(approved bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input PaymentReviewInput
+
+		if c.Request.ContentLength != 0 {
+			if err := c.ShouldBindJSON(&input); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error":   "Invalid request format",
+					"message": "Please check your request data",
+					"details": err.Error(),
+				})
+				return
+			}
+		}
+
+		input.Note = strings.TrimSpace(input.Note)
+		if !approved && input.Note == "" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid request data",
+				"message": "note is required to reject a payment",
+			})
+			return
+		}
+
+		reviewed, err := bookingService.Review(c.Param("id"), approved, input.Note)
+		if err != nil {
+			respondReviewError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, reviewed)
+	}
+}
@@ -0,0 +1,251 @@
+package admin
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"raffle_web_server/booking"
+	"raffle_web_server/payment"
+	"raffle_web_server/store"
+	"raffle_web_server/store/storetest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+)
+
+var pngReceipt = append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0}, 32)...)
+
+// newReviewRouter registra los endpoints de revisión de pagos sobre un almacén con la rifa
+// "raffle-test", de 10 VES por ticket
+func newReviewRouter(t *testing.T) (*gin.Engine, *store.FileRepository) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	repository := storetest.NewRepository(t)
+
+	now := time.Now().UTC()
+	err := repository.SaveRaffle(store.Raffle{
+		ID:            "raffle-test",
+		Title:         "Rifa de prueba",
+		Price:         10,
+		Currency:      "VES",
+		InitialTicket: 1,
+		TicketsTotal:  100,
+		EndsAt:        now.Add(24 * time.Hour),
+		Status:        store.RafflePublished,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	})
+	if err != nil {
+		t.Fatalf("SaveRaffle: %v", err)
+	}
+
+	receipts, err := payment.NewReceiptStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewReceiptStore: %v", err)
+	}
+
+	rates := func(from, to string) (store.ExchangeRate, error) {
+		return store.ExchangeRate{}, store.ErrNotFound
+	}
+
+	raffleRepository = repository
+	bookingService = booking.NewService(repository, storetest.NewSecretBox(t), rates)
+	paymentReceipts = receipts
+
+	router := gin.New()
+	router.GET("/payment-reviews", listPaymentReviewsEndpoint)
+	router.GET("/payment-reviews/:id/receipt", getPaymentReceiptEndpoint)
+	router.POST("/payment-reviews/:id/approve", reviewPaymentEndpoint(true))
+	router.POST("/payment-reviews/:id/reject", reviewPaymentEndpoint(false))
+
+	return router, repository
+}
+
+// submitProof reserva los tickets y envía el comprobante de una transferencia de 10 VES por ticket
+func submitProof(t *testing.T, repository *store.FileRepository, bookingId string, tickets []int) {
+	t.Helper()
+
+	now := time.Now().UTC()
+	heldUntil := now.Add(10 * time.Minute)
+
+	conflicts, err := repository.HoldTickets("raffle-test", bookingId, tickets, heldUntil)
+	if err != nil || len(conflicts) > 0 {
+		t.Fatalf("HoldTickets(%s): conflicts %v, err %v", bookingId, conflicts, err)
+	}
+
+	err = repository.SaveBooking(store.Booking{
+		ID:            bookingId,
+		RaffleId:      "raffle-test",
+		ParticipantId: "P-" + bookingId,
+		Tickets:       tickets,
+		Provider:      payment.ManualTransfer,
+		Status:        store.BookingReserved,
+		ExpiresAt:     heldUntil,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	})
+	if err != nil {
+		t.Fatalf("SaveBooking(%s): %v", bookingId, err)
+	}
+
+	receiptFile, receiptType, err := paymentReceipts.Save(bookingId, bytes.NewReader(pngReceipt), 1024)
+	if err != nil {
+		t.Fatalf("Save receipt: %v", err)
+	}
+
+	_, err = bookingService.SubmitProof(bookingId, store.PaymentProof{
+		Reference:   "REF-" + bookingId,
+		Amount:      decimal.NewFromInt(int64(10 * len(tickets))),
+		Currency:    "VES",
+		Bank:        "0102",
+		ReceiptFile: receiptFile,
+		ReceiptType: receiptType,
+	})
+	if err != nil {
+		t.Fatalf("SubmitProof(%s): %v", bookingId, err)
+	}
+}
+
+func serve(router *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestListPaymentReviews(t *testing.T) {
+	router, repository := newReviewRouter(t)
+
+	if err := repository.SaveParticipant(store.Participant{ID: "P-BK-A", Name: "Ana"}); err != nil {
+		t.Fatalf("SaveParticipant: %v", err)
+	}
+	submitProof(t, repository, "BK-A", []int{1, 2})
+	submitProof(t, repository, "BK-B", []int{3})
+
+	recorder := serve(router, http.MethodGet, "/payment-reviews", "")
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", recorder.Code, http.StatusOK, recorder.Body)
+	}
+
+	var reviews []PaymentReview
+	if err := json.Unmarshal(recorder.Body.Bytes(), &reviews); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+
+	if len(reviews) != 2 || reviews[0].ID != "BK-A" || reviews[1].ID != "BK-B" {
+		t.Fatalf("reviews = %v, want BK-A and BK-B, oldest first", reviews)
+	}
+	if reviews[0].Participant == nil || reviews[0].Participant.Name != "Ana" {
+		t.Fatalf("participant of BK-A = %v, want Ana", reviews[0].Participant)
+	}
+	if reviews[1].Participant != nil {
+		t.Fatalf("participant of BK-B = %v, want none", reviews[1].Participant)
+	}
+	if !reviews[0].Proof.Amount.Equal(decimal.NewFromInt(20)) {
+		t.Fatalf("proof amount = %s, want 20", reviews[0].Proof.Amount)
+	}
+}
+
+func TestGetPaymentReceipt(t *testing.T) {
+	router, repository := newReviewRouter(t)
+
+	submitProof(t, repository, "BK-A", []int{1})
+
+	recorder := serve(router, http.MethodGet, "/payment-reviews/BK-A/receipt", "")
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", recorder.Code, http.StatusOK, recorder.Body)
+	}
+	if contentType := recorder.Header().Get("Content-Type"); contentType != "image/png" {
+		t.Fatalf("content type = %s, want image/png", contentType)
+	}
+	if !bytes.Equal(recorder.Body.Bytes(), pngReceipt) {
+		t.Fatalf("receipt = %d bytes, want the uploaded image", recorder.Body.Len())
+	}
+
+	// Una reserva sin comprobante o inexistente no tiene imagen
+	if err := repository.SaveBooking(store.Booking{ID: "BK-B", RaffleId: "raffle-test", Status: store.BookingReserved}); err != nil {
+		t.Fatalf("SaveBooking: %v", err)
+	}
+	for _, bookingId := range []string{"BK-B", "BK-X"} {
+		if recorder := serve(router, http.MethodGet, "/payment-reviews/"+bookingId+"/receipt", ""); recorder.Code != http.StatusNotFound {
+			t.Errorf("receipt of %s: status = %d, want %d", bookingId, recorder.Code, http.StatusNotFound)
+		}
+	}
+}
+
+func TestApprovePaymentReview(t *testing.T) {
+	router, repository := newReviewRouter(t)
+
+	submitProof(t, repository, "BK-A", []int{1, 2})
+
+	recorder := serve(router, http.MethodPost, "/payment-reviews/BK-A/approve", "")
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", recorder.Code, http.StatusOK, recorder.Body)
+	}
+
+	var approved store.Booking
+	if err := json.Unmarshal(recorder.Body.Bytes(), &approved); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if approved.Status != store.BookingPaid || approved.RefIbp != "REF-BK-A" || approved.Proof.ReviewedAt == nil {
+		t.Fatalf("booking = %s with ref %q, want it paid with REF-BK-A and reviewed", approved.Status, approved.RefIbp)
+	}
+
+	for _, number := range []int{1, 2} {
+		ticket, err := repository.GetTicket("raffle-test", number)
+		if err != nil {
+			t.Fatalf("GetTicket(%d): %v", number, err)
+		}
+		if ticket.Status != store.TicketSold || ticket.BookingId != "BK-A" {
+			t.Errorf("ticket %d = %s by %s, want sold to BK-A", number, ticket.Status, ticket.BookingId)
+		}
+	}
+
+	// Un comprobante ya revisado no se vuelve a revisar
+	if recorder := serve(router, http.MethodPost, "/payment-reviews/BK-A/reject", `{"note":"too late"}`); recorder.Code != http.StatusConflict {
+		t.Fatalf("second review: status = %d, want %d", recorder.Code, http.StatusConflict)
+	}
+	if recorder := serve(router, http.MethodPost, "/payment-reviews/BK-X/approve", ""); recorder.Code != http.StatusNotFound {
+		t.Fatalf("unknown booking: status = %d, want %d", recorder.Code, http.StatusNotFound)
+	}
+}
+
+func TestRejectPaymentReview(t *testing.T) {
+	router, repository := newReviewRouter(t)
+
+	submitProof(t, repository, "BK-A", []int{1, 2})
+
+	for _, body := range []string{"", `{"note":"   "}`, `{`} {
+		if recorder := serve(router, http.MethodPost, "/payment-reviews/BK-A/reject", body); recorder.Code != http.StatusBadRequest {
+			t.Errorf("reject with body %q: status = %d, want %d", body, recorder.Code, http.StatusBadRequest)
+		}
+	}
+
+	recorder := serve(router, http.MethodPost, "/payment-reviews/BK-A/reject", `{"note":" transfer not received "}`)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", recorder.Code, http.StatusOK, recorder.Body)
+	}
+
+	rejected, err := repository.GetBooking("BK-A")
+	if err != nil {
+		t.Fatalf("GetBooking: %v", err)
+	}
+	if rejected.Status != store.BookingRejected || rejected.Proof.ReviewNote != "transfer not received" {
+		t.Fatalf("booking = %s with note %q, want it rejected with the trimmed note", rejected.Status, rejected.Proof.ReviewNote)
+	}
+
+	// Los tickets vuelven a estar disponibles
+	for _, number := range []int{1, 2} {
+		if ticket, err := repository.GetTicket("raffle-test", number); err == nil && ticket.IsHeld(time.Now().UTC()) {
+			t.Errorf("ticket %d is still held by %s", number, ticket.BookingId)
+		}
+	}
+}
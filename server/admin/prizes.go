@@ -0,0 +1,272 @@
+package admin
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"raffle_web_server/store"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+var errInvalidPrize = errors.New("invalid prize")
+
+// PrizeInput representa los campos editables de un premio. Los campos nulos no se modifican.
+type PrizeInput struct {
+	Kind             *store.PrizeKind `json:"kind"`
+	Rank             *int             `json:"rank"`
+	Title            *string          `json:"title"`
+	ShortDescription *string          `json:"shortDescription"`
+	ImageUrl         *string          `json:"imageUrl"`
+}
+
+// generatePrizeId genera un prize ID único
+func generatePrizeId() string {
+	id := uuid.New()
+	return "PZ-" + strings.ToUpper(strings.ReplaceAll(id.String(), "-", ""))
+}
+
+// applyPrizeInput aplica los campos recibidos. El tipo y la posición determinan
+// la cantidad de ganadores del sorteo, por eso solo se cambian con la rifa en borrador.
+func applyPrizeInput(raffle *store.Raffle, prize *store.Prize, input PrizeInput) error {
+	if raffle.Status == store.RaffleArchived {
+		return fmt.Errorf("%w: prizes of archived raffles cannot be edited", errNotEditable)
+	}
+
+	kindChanged := input.Kind != nil && *input.Kind != prize.Kind
+	rankChanged := input.Rank != nil && *input.Rank != prize.Rank
+
+	if (kindChanged || rankChanged) && raffle.Status != store.RaffleDraft {
+		return fmt.Errorf("%w: prize kind and rank can only be edited while the raffle is in draft", errNotEditable)
+	}
+
+	if input.Kind != nil {
+		prize.Kind = *input.Kind
+	}
+	if input.Rank != nil {
+		prize.Rank = *input.Rank
+	}
+	if input.Title != nil {
+		prize.Title = strings.TrimSpace(*input.Title)
+	}
+	if input.ShortDescription != nil {
+		prize.ShortDescription = strings.TrimSpace(*input.ShortDescription)
+	}
+	if input.ImageUrl != nil {
+		prize.ImageUrl = strings.TrimSpace(*input.ImageUrl)
+	}
+
+	return nil
+}
+
+// validatePrize valida el premio contra el resto del catálogo de la rifa
+func validatePrize(prize *store.Prize) error {
+	if !prize.Kind.IsValid() {
+		return fmt.Errorf("%w: invalid kind: %s (valid: main, secondary, bless)", errInvalidPrize, prize.Kind)
+	}
+
+	if prize.Rank < 1 {
+		return fmt.Errorf("%w: rank must be at least 1", errInvalidPrize)
+	}
+
+	if prize.Title == "" {
+		return fmt.Errorf("%w: title is required", errInvalidPrize)
+	}
+
+	prizes, err := raffleRepository.ListPrizes(prize.RaffleId)
+	if err != nil {
+		return err
+	}
+
+	for _, other := range prizes {
+		if other.ID == prize.ID {
+			continue
+		}
+		if prize.Kind == store.PrizeMain && other.Kind == store.PrizeMain {
+			return fmt.Errorf("%w: raffle already has a main prize (%s)", errInvalidPrize, other.ID)
+		}
+		if other.Kind == prize.Kind && other.Rank == prize.Rank {
+			return fmt.Errorf("%w: rank %d is already used by %s prize %s", errInvalidPrize, prize.Rank, prize.Kind, other.ID)
+		}
+	}
+
+	return nil
+}
+
+// respondPrizeError traduce los errores del catálogo a respuestas HTTP
+func respondPrizeError(c *gin.Context, err error) {
+	if errors.Is(err, errInvalidPrize) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid prize data",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	respondAdminError(c, err)
+}
+
+// loadRafflePrize obtiene el premio verificando que pertenezca a la rifa
+func loadRafflePrize(raffleId, prizeId string) (*store.Prize, error) {
+	prize, err := raffleRepository.GetPrize(prizeId)
+	if err != nil {
+		return nil, err
+	}
+
+	if prize.RaffleId != raffleId {
+		return nil, store.ErrNotFound
+	}
+
+	return prize, nil
+}
+
+// listPrizesEndpoint maneja el endpoint GET /api/v1/admin/raffles/:id/prizes
+func listPrizesEndpoint(c *gin.Context) {
+	raffle, err := raffleRepository.GetRaffle(c.Param("id"))
+	if err != nil {
+		respondAdminError(c, err)
+		return
+	}
+
+	prizes, err := raffleRepository.ListPrizes(raffle.ID)
+	if err != nil {
+		respondAdminError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, prizes)
+}
+
+// createPrizeEndpoint maneja el endpoint POST /api/v1/admin/raffles/:id/prizes
+func createPrizeEndpoint(c *gin.Context) {
+	var input PrizeInput
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"message": "Please check your request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	raffleMutex.Lock()
+	defer raffleMutex.Unlock()
+
+	raffle, err := raffleRepository.GetRaffle(c.Param("id"))
+	if err != nil {
+		respondAdminError(c, err)
+		return
+	}
+
+	if raffle.Status != store.RaffleDraft {
+		respondAdminError(c, fmt.Errorf("%w: prizes can only be added while the raffle is in draft", errNotEditable))
+		return
+	}
+
+	now := time.Now().UTC()
+	prize := store.Prize{
+		ID:        generatePrizeId(),
+		RaffleId:  raffle.ID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	if err := applyPrizeInput(raffle, &prize, input); err != nil {
+		respondPrizeError(c, err)
+		return
+	}
+
+	if err := validatePrize(&prize); err != nil {
+		respondPrizeError(c, err)
+		return
+	}
+
+	if err := raffleRepository.SavePrize(prize); err != nil {
+		respondAdminError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, prize)
+}
+
+// updatePrizeEndpoint maneja el endpoint PUT /api/v1/admin/raffles/:id/prizes/:prizeId
+func updatePrizeEndpoint(c *gin.Context) {
+	var input PrizeInput
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"message": "Please check your request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	raffleMutex.Lock()
+	defer raffleMutex.Unlock()
+
+	raffle, err := raffleRepository.GetRaffle(c.Param("id"))
+	if err != nil {
+		respondAdminError(c, err)
+		return
+	}
+
+	prize, err := loadRafflePrize(raffle.ID, c.Param("prizeId"))
+	if err != nil {
+		respondAdminError(c, err)
+		return
+	}
+
+	if err := applyPrizeInput(raffle, prize, input); err != nil {
+		respondPrizeError(c, err)
+		return
+	}
+
+	if err := validatePrize(prize); err != nil {
+		respondPrizeError(c, err)
+		return
+	}
+
+	prize.UpdatedAt = time.Now().UTC()
+
+	if err := raffleRepository.SavePrize(*prize); err != nil {
+		respondAdminError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, prize)
+}
+
+// deletePrizeEndpoint maneja el endpoint DELETE /api/v1/admin/raffles/:id/prizes/:prizeId
+func deletePrizeEndpoint(c *gin.Context) {
+	raffleMutex.Lock()
+	defer raffleMutex.Unlock()
+
+	raffle, err := raffleRepository.GetRaffle(c.Param("id"))
+	if err != nil {
+		respondAdminError(c, err)
+		return
+	}
+
+	if raffle.Status != store.RaffleDraft {
+		respondAdminError(c, fmt.Errorf("%w: prizes can only be removed while the raffle is in draft", errNotEditable))
+		return
+	}
+
+	prize, err := loadRafflePrize(raffle.ID, c.Param("prizeId"))
+	if err != nil {
+		respondAdminError(c, err)
+		return
+	}
+
+	if err := raffleRepository.DeletePrize(prize.ID); err != nil {
+		respondAdminError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,550 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"raffle_web_server/draw"
+	"raffle_web_server/store"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const maxTicketsPerRaffle = 1000000
+
+var errInvalidRaffle = errors.New("invalid raffle")
+var errRangeOverlap = errors.New("ticket range overlaps another raffle")
+var errInvalidTransition = errors.New("invalid status transition")
+var errNotEditable = errors.New("raffle not editable")
+
+// raffleMutex serializa las modificaciones para que la validación de rangos sea consistente
+var raffleMutex sync.Mutex
+
+// RaffleInput representa los campos editables de una rifa. Los campos nulos no se modifican.
+type RaffleInput struct {
+	Title            *string   `json:"title"`
+	ShortDescription *string   `json:"shortDescription"`
+	CoverImageUrl    *string   `json:"coverImageUrl"`
+	Price            *float64  `json:"price"`
+	Currency         *string   `json:"currency"`
+	InitialTicket    *int      `json:"initialTicket"`
+	TicketsTotal     *int      `json:"ticketsTotal"`
+	EndsAt           *string   `json:"endsAt"` // RFC3339
+	IsMain           *bool     `json:"isMain"`
+	PaymentProviders *[]string `json:"paymentProviders"` // lista vacía vuelve a los proveedores por defecto
+}
+
+// RaffleStatusInput representa el cambio de estado solicitado
+type RaffleStatusInput struct {
+	Status store.RaffleStatus `json:"status"`
+}
+
+// AdminRaffle es la vista de administración de una rifa
+type AdminRaffle struct {
+	store.Raffle
+	TotalSold     int `json:"totalSold"`
+	TotalReserved int `json:"totalReserved"`
+}
+
+// toAdminRaffle agrega los contadores de tickets a la rifa
+func toAdminRaffle(raffle store.Raffle) (AdminRaffle, error) {
+	tickets, err := raffleRepository.ListTickets(raffle.ID)
+	if err != nil {
+		return AdminRaffle{}, err
+	}
+
+	now := time.Now().UTC()
+	view := AdminRaffle{Raffle: raffle}
+
+	for _, ticket := range tickets {
+		if !ticket.IsHeld(now) {
+			continue
+		}
+		if ticket.Status == store.TicketSold {
+			view.TotalSold++
+		} else {
+			view.TotalReserved++
+		}
+	}
+
+	return view, nil
+}
+
+// generateRaffleId genera un raffle ID único
+func generateRaffleId() string {
+	id := uuid.New()
+	return "raffle-" + strings.ToLower(strings.ReplaceAll(id.String(), "-", "")[:12])
+}
+
+// applyRaffleInput aplica los campos recibidos respetando qué se puede editar en cada estado
+func applyRaffleInput(raffle *store.Raffle, input RaffleInput) error {
+	if raffle.Status == store.RaffleArchived || raffle.Status == store.RaffleDrawn || raffle.Status == store.RaffleCancelled {
+		return fmt.Errorf("%w: raffles in status %s cannot be edited", errNotEditable, raffle.Status)
+	}
+
+	salesFieldsChanged := input.Price != nil || input.Currency != nil ||
+		input.InitialTicket != nil || input.TicketsTotal != nil
+
+	if salesFieldsChanged && raffle.Status != store.RaffleDraft {
+		return fmt.Errorf("%w: price, currency and ticket range can only be edited in draft", errNotEditable)
+	}
+
+	if input.EndsAt != nil && raffle.Status != store.RaffleDraft && raffle.Status != store.RafflePublished {
+		return fmt.Errorf("%w: end date can only be edited in draft or published", errNotEditable)
+	}
+
+	if input.Title != nil {
+		raffle.Title = strings.TrimSpace(*input.Title)
+	}
+	if input.ShortDescription != nil {
+		raffle.ShortDescription = strings.TrimSpace(*input.ShortDescription)
+	}
+	if input.CoverImageUrl != nil {
+		raffle.CoverImageUrl = strings.TrimSpace(*input.CoverImageUrl)
+	}
+	if input.Price != nil {
+		raffle.Price = *input.Price
+	}
+	if input.Currency != nil {
+		raffle.Currency = strings.ToUpper(strings.TrimSpace(*input.Currency))
+	}
+	if input.InitialTicket != nil {
+		raffle.InitialTicket = *input.InitialTicket
+	}
+	if input.TicketsTotal != nil {
+		raffle.TicketsTotal = *input.TicketsTotal
+	}
+	if input.IsMain != nil {
+		raffle.IsMain = *input.IsMain
+	}
+	if input.PaymentProviders != nil {
+		if err := paymentRegistry.Validate(*input.PaymentProviders); err != nil {
+			return fmt.Errorf("%w: %v", errInvalidRaffle, err)
+		}
+		raffle.PaymentProviders = *input.PaymentProviders
+	}
+	if input.EndsAt != nil {
+		endsAt, err := time.Parse(time.RFC3339, *input.EndsAt)
+		if err != nil {
+			return fmt.Errorf("%w: endsAt must be a RFC3339 date", errInvalidRaffle)
+		}
+		if !endsAt.After(time.Now()) {
+			return fmt.Errorf("%w: endsAt must be in the future", errInvalidRaffle)
+		}
+		raffle.EndsAt = endsAt.UTC()
+	}
+
+	return nil
+}
+
+// validateRaffle valida los campos obligatorios de una rifa
+func validateRaffle(raffle *store.Raffle) error {
+	if raffle.Title == "" {
+		return fmt.Errorf("%w: title is required", errInvalidRaffle)
+	}
+
+	if raffle.Price <= 0 {
+		return fmt.Errorf("%w: price must be greater than 0", errInvalidRaffle)
+	}
+
+	validCurrencies := map[string]bool{"VES": true, "USD": true}
+	if !validCurrencies[raffle.Currency] {
+		return fmt.Errorf("%w: invalid currency: %s (valid: VES, USD)", errInvalidRaffle, raffle.Currency)
+	}
+
+	if raffle.InitialTicket < 1 {
+		return fmt.Errorf("%w: initialTicket must be at least 1", errInvalidRaffle)
+	}
+
+	if raffle.TicketsTotal <= 0 || raffle.TicketsTotal > maxTicketsPerRaffle {
+		return fmt.Errorf("%w: ticketsTotal must be between 1 and %d", errInvalidRaffle, maxTicketsPerRaffle)
+	}
+
+	if raffle.EndsAt.IsZero() {
+		return fmt.Errorf("%w: endsAt is required", errInvalidRaffle)
+	}
+
+	return nil
+}
+
+// checkRangeOverlap verifica que el rango de tickets no se solape con el de otra rifa
+func checkRangeOverlap(raffle *store.Raffle) error {
+	raffles, err := raffleRepository.ListRaffles()
+	if err != nil {
+		return err
+	}
+
+	for _, other := range raffles {
+		// Las rifas archivadas o canceladas ya no venden sus tickets
+		if other.ID == raffle.ID || other.Status == store.RaffleArchived || other.Status == store.RaffleCancelled {
+			continue
+		}
+		if raffle.OverlapsWith(&other) {
+			return fmt.Errorf("%w: range %d-%d overlaps raffle %s (%d-%d)", errRangeOverlap,
+				raffle.InitialTicket, raffle.LastTicket(), other.ID, other.InitialTicket, other.LastTicket())
+		}
+	}
+
+	return nil
+}
+
+// saveRaffle guarda la rifa y, si es la principal, desmarca a las demás
+func saveRaffle(raffle store.Raffle) error {
+	if raffle.IsMain {
+		raffles, err := raffleRepository.ListRaffles()
+		if err != nil {
+			return err
+		}
+
+		for _, other := range raffles {
+			if other.ID != raffle.ID && other.IsMain {
+				other.IsMain = false
+				if err := raffleRepository.SaveRaffle(other); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return raffleRepository.SaveRaffle(raffle)
+}
+
+// respondAdminError traduce los errores de administración a respuestas HTTP
+func respondAdminError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Raffle not found",
+			"message": "No raffle found with the given ID",
+		})
+	case errors.Is(err, errInvalidRaffle):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid raffle data",
+			"message": err.Error(),
+		})
+	case errors.Is(err, errRangeOverlap):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Ticket range overlap",
+			"message": err.Error(),
+		})
+	case errors.Is(err, errInvalidTransition), errors.Is(err, errNotEditable):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Invalid raffle status",
+			"message": err.Error(),
+		})
+	case errors.Is(err, draw.ErrBeaconPending):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Draw pending",
+			"message": "Sales are closed and the draw is waiting for the beacon round, please try again in a few seconds",
+			"details": err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal Server Error",
+			"message": "Unable to process the raffle request",
+			"details": err.Error(),
+		})
+	}
+}
+
+// respondRaffle responde con la vista de administración de la rifa
+func respondRaffle(c *gin.Context, status int, raffle store.Raffle) {
+	view, err := toAdminRaffle(raffle)
+	if err != nil {
+		respondAdminError(c, err)
+		return
+	}
+
+	c.JSON(status, view)
+}
+
+// listRafflesEndpoint maneja el endpoint GET /api/v1/admin/raffles
+func listRafflesEndpoint(c *gin.Context) {
+	raffles, err := raffleRepository.ListRaffles()
+	if err != nil {
+		respondAdminError(c, err)
+		return
+	}
+
+	views := make([]AdminRaffle, 0, len(raffles))
+	for _, raffle := range raffles {
+		view, err := toAdminRaffle(raffle)
+		if err != nil {
+			respondAdminError(c, err)
+			return
+		}
+		views = append(views, view)
+	}
+
+	c.JSON(http.StatusOK, views)
+}
+
+// getRaffleEndpoint maneja el endpoint GET /api/v1/admin/raffles/:id
+func getRaffleEndpoint(c *gin.Context) {
+	raffle, err := raffleRepository.GetRaffle(c.Param("id"))
+	if err != nil {
+		respondAdminError(c, err)
+		return
+	}
+
+	respondRaffle(c, http.StatusOK, *raffle)
+}
+
+// createRaffleEndpoint maneja el endpoint POST /api/v1/admin/raffles.
+// Las rifas se crean siempre en borrador.
+func createRaffleEndpoint(c *gin.Context) {
+	var input RaffleInput
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"message": "Please check your request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	raffleMutex.Lock()
+	defer raffleMutex.Unlock()
+
+	now := time.Now().UTC()
+	raffle := store.Raffle{
+		ID:        generateRaffleId(),
+		Status:    store.RaffleDraft,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	if err := applyRaffleInput(&raffle, input); err != nil {
+		respondAdminError(c, err)
+		return
+	}
+
+	if err := validateRaffle(&raffle); err != nil {
+		respondAdminError(c, err)
+		return
+	}
+
+	if err := checkRangeOverlap(&raffle); err != nil {
+		respondAdminError(c, err)
+		return
+	}
+
+	if err := saveRaffle(raffle); err != nil {
+		respondAdminError(c, err)
+		return
+	}
+
+	respondRaffle(c, http.StatusCreated, raffle)
+}
+
+// updateRaffleEndpoint maneja el endpoint PUT /api/v1/admin/raffles/:id
+func updateRaffleEndpoint(c *gin.Context) {
+	var input RaffleInput
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"message": "Please check your request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	raffleMutex.Lock()
+	defer raffleMutex.Unlock()
+
+	raffle, err := raffleRepository.GetRaffle(c.Param("id"))
+	if err != nil {
+		respondAdminError(c, err)
+		return
+	}
+
+	if err := applyRaffleInput(raffle, input); err != nil {
+		respondAdminError(c, err)
+		return
+	}
+
+	if err := validateRaffle(raffle); err != nil {
+		respondAdminError(c, err)
+		return
+	}
+
+	if err := checkRangeOverlap(raffle); err != nil {
+		respondAdminError(c, err)
+		return
+	}
+
+	raffle.UpdatedAt = time.Now().UTC()
+
+	if err := saveRaffle(*raffle); err != nil {
+		respondAdminError(c, err)
+		return
+	}
+
+	respondRaffle(c, http.StatusOK, *raffle)
+}
+
+// deleteRaffleEndpoint maneja el endpoint DELETE /api/v1/admin/raffles/:id.
+// Solo se pueden eliminar rifas en borrador; las demás se archivan.
+func deleteRaffleEndpoint(c *gin.Context) {
+	raffleMutex.Lock()
+	defer raffleMutex.Unlock()
+
+	raffle, err := raffleRepository.GetRaffle(c.Param("id"))
+	if err != nil {
+		respondAdminError(c, err)
+		return
+	}
+
+	if raffle.Status != store.RaffleDraft {
+		respondAdminError(c, fmt.Errorf("%w: only draft raffles can be deleted, archive it instead", errNotEditable))
+		return
+	}
+
+	if err := raffleRepository.DeleteRaffle(raffle.ID); err != nil {
+		respondAdminError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// checkPrizeCatalog verifica que la rifa tenga definido su premio principal antes de publicarse
+func checkPrizeCatalog(raffleId string) error {
+	prizes, err := raffleRepository.ListPrizes(raffleId)
+	if err != nil {
+		return err
+	}
+
+	for _, prize := range prizes {
+		if prize.Kind == store.PrizeMain {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: a main prize must be defined before publishing", errInvalidRaffle)
+}
+
+// hasActiveHolds indica si la rifa tiene tickets retenidos por pagos en curso
+func hasActiveHolds(raffleId string) (bool, error) {
+	tickets, err := raffleRepository.ListTickets(raffleId)
+	if err != nil {
+		return false, err
+	}
+
+	now := time.Now().UTC()
+	for _, ticket := range tickets {
+		if ticket.Status == store.TicketReserved && ticket.IsHeld(now) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// transitionRaffle aplica el cambio de estado con sus validaciones y efectos
+func transitionRaffle(ctx context.Context, raffle *store.Raffle, next store.RaffleStatus) error {
+	if !next.IsValid() {
+		return fmt.Errorf("%w: unknown status %s", errInvalidRaffle, next)
+	}
+
+	if !raffle.Status.CanTransitionTo(next) {
+		return fmt.Errorf("%w: %s -> %s", errInvalidTransition, raffle.Status, next)
+	}
+
+	switch next {
+	case store.RafflePublished:
+		if err := validateRaffle(raffle); err != nil {
+			return err
+		}
+		if !raffle.EndsAt.After(time.Now()) {
+			return fmt.Errorf("%w: endsAt must be in the future to publish", errInvalidRaffle)
+		}
+		if err := checkRangeOverlap(raffle); err != nil {
+			return err
+		}
+		if err := checkPrizeCatalog(raffle.ID); err != nil {
+			return err
+		}
+		// El compromiso del sorteo se publica antes de abrir las ventas
+		if _, err := drawService.Commit(raffle.ID); err != nil {
+			return err
+		}
+
+	case store.RaffleDrawn:
+		if time.Now().Before(raffle.EndsAt) {
+			return fmt.Errorf("%w: raffle ends at %s", errInvalidTransition, raffle.EndsAt.Format(time.RFC3339))
+		}
+		active, err := hasActiveHolds(raffle.ID)
+		if err != nil {
+			return err
+		}
+		if active {
+			return fmt.Errorf("%w: raffle still has reservations pending payment", errInvalidTransition)
+		}
+		// Execute persiste los ganadores y deja la rifa en estado drawn
+		if _, err := drawService.Execute(ctx, *raffle); err != nil {
+			return err
+		}
+		drawn, err := raffleRepository.GetRaffle(raffle.ID)
+		if err != nil {
+			return err
+		}
+		*raffle = *drawn
+		return nil
+	}
+
+	raffle.Status = next
+	raffle.UpdatedAt = time.Now().UTC()
+
+	if err := raffleRepository.SaveRaffle(*raffle); err != nil {
+		return err
+	}
+
+	// Con la rifa ya cancelada no se aceptan reservas nuevas y se liberan las que no pagaron
+	if next == store.RaffleCancelled {
+		expired, err := bookingService.ExpireRaffle(raffle.ID)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Raffle %s cancelled, expired bookings %v\n", raffle.ID, expired)
+	}
+
+	return nil
+}
+
+// changeRaffleStatusEndpoint maneja el endpoint POST /api/v1/admin/raffles/:id/status
+func changeRaffleStatusEndpoint(c *gin.Context) {
+	var input RaffleStatusInput
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"message": "Please check your request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	raffleMutex.Lock()
+	defer raffleMutex.Unlock()
+
+	raffle, err := raffleRepository.GetRaffle(c.Param("id"))
+	if err != nil {
+		respondAdminError(c, err)
+		return
+	}
+
+	if err := transitionRaffle(c.Request.Context(), raffle, input.Status); err != nil {
+		respondAdminError(c, err)
+		return
+	}
+
+	respondRaffle(c, http.StatusOK, *raffle)
+}
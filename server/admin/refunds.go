@@ -0,0 +1,228 @@
+package admin
+
+import (
+	"errors"
+	"net/http"
+	"raffle_web_server/payment"
+	"raffle_web_server/refund"
+	"raffle_web_server/store"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+)
+
+// RefundInput representa la devolución de una reserva solicitada por un administrador
+type RefundInput struct {
+	BookingId   string                `json:"bookingId"`
+	Amount      decimal.Decimal       `json:"amount"` // 0 devuelve el monto por defecto
+	Reason      string                `json:"reason"`
+	Method      store.RefundMethod    `json:"method"`      // vacío usa el medio del proveedor con el que se cobró
+	Beneficiary *store.PaymentAccount `json:"beneficiary"` // vacía usa la cuenta desde la que se pagó
+}
+
+// RaffleRefundInput representa la devolución de todos los pagos de una rifa cancelada
+type RaffleRefundInput struct {
+	Method store.RefundMethod `json:"method"`
+	Reason string             `json:"reason"`
+}
+
+// RefundUpdateInput representa el cierre manual o la cancelación de una devolución
+type RefundUpdateInput struct {
+	Reference string `json:"reference"` // obligatoria al completar un pago manual
+	Note      string `json:"note"`
+}
+
+// respondRefundError traduce los errores de las devoluciones a respuestas HTTP
+func respondRefundError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Not found",
+			"message": err.Error(),
+		})
+	case errors.Is(err, refund.ErrInvalidRefund), errors.Is(err, payment.ErrUnknownProvider):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid refund",
+			"message": err.Error(),
+		})
+	case errors.Is(err, refund.ErrNotRefundable), errors.Is(err, refund.ErrInvalidTransition):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Invalid refund status",
+			"message": err.Error(),
+		})
+	case errors.Is(err, refund.ErrProviderFailed):
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error":   "Payment provider error",
+			"message": "Unable to send the refund, it can be retried",
+			"details": err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal Server Error",
+			"message": "Unable to process the refund",
+			"details": err.Error(),
+		})
+	}
+}
+
+// bindRefundInput lee el body opcional de los endpoints de devoluciones
+func bindRefundInput(c *gin.Context, input any) bool {
+	if c.Request.ContentLength == 0 {
+		return true
+	}
+
+	if err := c.ShouldBindJSON(input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"message": "Please check your request data",
+			"details": err.Error(),
+		})
+		return false
+	}
+
+	return true
+}
+
+// listRefundsEndpoint maneja el endpoint GET /api/v1/admin/refunds?booking_id=X&raffle_id=Y&status=Z
+func listRefundsEndpoint(c *gin.Context) {
+	refunds, err := refundService.List(c.Query("booking_id"), c.Query("raffle_id"), store.RefundStatus(strings.ToUpper(c.Query("status"))))
+	if err != nil {
+		respondRefundError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, refunds)
+}
+
+// listLostTicketBookingsEndpoint maneja el endpoint GET /api/v1/admin/refunds/lost-tickets.
+// Devuelve las reservas pagadas que perdieron tickets y todavía no tienen devolución.
+func listLostTicketBookingsEndpoint(c *gin.Context) {
+	bookings, err := refundService.LostTicketBookings()
+	if err != nil {
+		respondRefundError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, bookings)
+}
+
+// createRefundEndpoint maneja el endpoint POST /api/v1/admin/refunds
+func createRefundEndpoint(c *gin.Context) {
+	var input RefundInput
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"message": "Please check your request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if input.BookingId == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"message": "bookingId is required",
+		})
+		return
+	}
+
+	request := refund.Request{
+		BookingId:   input.BookingId,
+		Amount:      input.Amount,
+		Reason:      input.Reason,
+		Method:      input.Method,
+		Beneficiary: input.Beneficiary,
+	}
+
+	var created *store.Refund
+	var err error
+
+	// Sin método la devolución la hace el proveedor con el que se cobró la reserva
+	if input.Method == "" {
+		var current *store.Booking
+		current, err = bookingService.Get(input.BookingId)
+		if err == nil {
+			created, err = paymentRegistry.Refund(c.Request.Context(), current, request)
+		}
+	} else {
+		created, err = refundService.Request(request)
+	}
+	if err != nil {
+		respondRefundError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, created)
+}
+
+// executeRefundEndpoint maneja el endpoint POST /api/v1/admin/refunds/:id/execute
+func executeRefundEndpoint(c *gin.Context) {
+	executed, err := refundService.Execute(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondRefundError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, executed)
+}
+
+// refreshRefundEndpoint maneja el endpoint POST /api/v1/admin/refunds/:id/refresh
+func refreshRefundEndpoint(c *gin.Context) {
+	refreshed, err := refundService.Refresh(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondRefundError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, refreshed)
+}
+
+// completeRefundEndpoint maneja el endpoint POST /api/v1/admin/refunds/:id/complete
+func completeRefundEndpoint(c *gin.Context) {
+	var input RefundUpdateInput
+	if !bindRefundInput(c, &input) {
+		return
+	}
+
+	completed, err := refundService.Complete(c.Param("id"), strings.TrimSpace(input.Reference), strings.TrimSpace(input.Note))
+	if err != nil {
+		respondRefundError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, completed)
+}
+
+// cancelRefundEndpoint maneja el endpoint POST /api/v1/admin/refunds/:id/cancel
+func cancelRefundEndpoint(c *gin.Context) {
+	var input RefundUpdateInput
+	if !bindRefundInput(c, &input) {
+		return
+	}
+
+	cancelled, err := refundService.Cancel(c.Param("id"), strings.TrimSpace(input.Note))
+	if err != nil {
+		respondRefundError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, cancelled)
+}
+
+// refundRaffleEndpoint maneja el endpoint POST /api/v1/admin/raffles/:id/refunds
+func refundRaffleEndpoint(c *gin.Context) {
+	input := RaffleRefundInput{Method: store.RefundSypagoCredit}
+	if !bindRefundInput(c, &input) {
+		return
+	}
+
+	result, err := refundService.RefundRaffle(c.Request.Context(), c.Param("id"), input.Method, input.Reason)
+	if err != nil {
+		respondRefundError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,88 @@
+package admin
+
+import (
+	"raffle_web_server/booking"
+	"raffle_web_server/draw"
+	"raffle_web_server/exchange"
+	"raffle_web_server/middlewares"
+	"raffle_web_server/payment"
+	"raffle_web_server/refund"
+	"raffle_web_server/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// raffleRepository es el almacén persistente de rifas
+var raffleRepository store.RaffleRepository
+
+// drawService publica el compromiso y ejecuta el sorteo de las rifas
+var drawService *draw.Service
+
+// exchangeService guarda el historial de tasas de cambio
+var exchangeService *exchange.Service
+
+// paymentRegistry valida los proveedores de pago que se habilitan en cada rifa
+var paymentRegistry *payment.Registry
+
+// bookingService finaliza las reservas con pagos manuales revisados
+var bookingService *booking.Service
+
+// paymentReceipts guarda las imágenes de los comprobantes de pagos manuales
+var paymentReceipts *payment.ReceiptStore
+
+// refundService devuelve los pagos de rifas canceladas y de tickets perdidos
+var refundService *refund.Service
+
+// Services agrupa las dependencias que usan los handlers de administración
+type Services struct {
+	Repository store.RaffleRepository
+	Draws      *draw.Service
+	Exchange   *exchange.Service
+	Payments   *payment.Registry
+	Bookings   *booking.Service
+	Receipts   *payment.ReceiptStore
+	Refunds    *refund.Service
+}
+
+// ActivateRoutes registra la API de administración bajo /api/v1/admin, protegida por clave
+func ActivateRoutes(r *gin.Engine, services Services) {
+
+	raffleRepository = services.Repository
+	drawService = services.Draws
+	exchangeService = services.Exchange
+	paymentRegistry = services.Payments
+	bookingService = services.Bookings
+	paymentReceipts = services.Receipts
+	refundService = services.Refunds
+
+	group := r.Group("api/v1/admin", middlewares.RequireAdminKey())
+
+	group.GET("raffles", listRafflesEndpoint)
+	group.POST("raffles", createRaffleEndpoint)
+	group.GET("raffles/:id", getRaffleEndpoint)
+	group.PUT("raffles/:id", updateRaffleEndpoint)
+	group.DELETE("raffles/:id", deleteRaffleEndpoint)
+	group.POST("raffles/:id/status", changeRaffleStatusEndpoint)
+	group.POST("raffles/:id/refunds", refundRaffleEndpoint)
+
+	group.GET("raffles/:id/prizes", listPrizesEndpoint)
+	group.POST("raffles/:id/prizes", createPrizeEndpoint)
+	group.PUT("raffles/:id/prizes/:prizeId", updatePrizeEndpoint)
+	group.DELETE("raffles/:id/prizes/:prizeId", deletePrizeEndpoint)
+
+	group.GET("exchange-rates", listExchangeRatesEndpoint)
+	group.PUT("exchange-rates", setExchangeRateEndpoint)
+
+	group.GET("payment-reviews", listPaymentReviewsEndpoint)
+	group.GET("payment-reviews/:id/receipt", getPaymentReceiptEndpoint)
+	group.POST("payment-reviews/:id/approve", reviewPaymentEndpoint(true))
+	group.POST("payment-reviews/:id/reject", reviewPaymentEndpoint(false))
+
+	group.GET("refunds", listRefundsEndpoint)
+	group.GET("refunds/lost-tickets", listLostTicketBookingsEndpoint)
+	group.POST("refunds", createRefundEndpoint)
+	group.POST("refunds/:id/execute", executeRefundEndpoint)
+	group.POST("refunds/:id/refresh", refreshRefundEndpoint)
+	group.POST("refunds/:id/complete", completeRefundEndpoint)
+	group.POST("refunds/:id/cancel", cancelRefundEndpoint)
+}
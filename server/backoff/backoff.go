@@ -0,0 +1,129 @@
+// Package backoff programa los reintentos de las operaciones que esperan una respuesta de SyPago:
+// cada una se vuelve a intentar con una espera que se duplica hasta un máximo.
+package backoff
+
+import (
+	"raffle_web_server/config"
+	"time"
+)
+
+const defaultInterval = 10 * time.Second
+const defaultInitial = 5 * time.Second
+const defaultMax = 5 * time.Minute
+const defaultMaxPending = 2 * time.Hour
+
+// Settings son los tiempos de ReconcileConfig
+type Settings struct {
+	Interval   time.Duration // cada cuánto se revisan las operaciones pendientes
+	Initial    time.Duration // espera después del primer intento sin resultado
+	Max        time.Duration // espera máxima entre intentos
+	MaxPending time.Duration // tiempo sin resultado tras el que la operación pasa a revisión manual
+}
+
+// SettingsFromConfig devuelve los tiempos de ReconcileConfig, con los valores por defecto para
+// los que no se indican
+func SettingsFromConfig() Settings {
+	reconcileConfig := config.GetConfig().ReconcileConfig
+
+	settings := Settings{
+		Interval:   defaultInterval,
+		Initial:    defaultInitial,
+		Max:        defaultMax,
+		MaxPending: defaultMaxPending,
+	}
+
+	if reconcileConfig.IntervalSeconds > 0 {
+		settings.Interval = time.Duration(reconcileConfig.IntervalSeconds) * time.Second
+	}
+
+	if reconcileConfig.InitialBackoffSeconds > 0 {
+		settings.Initial = time.Duration(reconcileConfig.InitialBackoffSeconds) * time.Second
+	}
+
+	if reconcileConfig.MaxBackoffSeconds > 0 {
+		settings.Max = time.Duration(reconcileConfig.MaxBackoffSeconds) * time.Second
+	}
+
+	if reconcileConfig.MaxPendingSeconds > 0 {
+		settings.MaxPending = time.Duration(reconcileConfig.MaxPendingSeconds) * time.Second
+	}
+
+	return settings
+}
+
+// Delay devuelve la espera antes del siguiente intento: se duplica en cada intento hasta el máximo
+func Delay(count int, initial, max time.Duration) time.Duration {
+	wait := initial
+	for i := 1; i < count && wait < max; i++ {
+		wait *= 2
+	}
+	if wait > max {
+		wait = max
+	}
+	return wait
+}
+
+// attempt guarda cuántas veces se intentó una operación y cuándo volver a intentarla
+type attempt struct {
+	count  int
+	nextAt time.Time
+}
+
+// Schedule guarda el próximo intento de cada operación pendiente. No es seguro para uso
+// concurrente: lo usa el worker que revisa las operaciones.
+type Schedule struct {
+	attempts map[string]*attempt
+	seen     map[string]bool
+}
+
+func NewSchedule() *Schedule {
+	return &Schedule{
+		attempts: make(map[string]*attempt),
+		seen:     make(map[string]bool),
+	}
+}
+
+// Due indica si ya toca intentar la operación y la marca como pendiente en la revisión actual
+func (s *Schedule) Due(id string, now time.Time) bool {
+	s.seen[id] = true
+
+	state, exists := s.attempts[id]
+	return !exists || !now.Before(state.nextAt)
+}
+
+// Retry programa el siguiente intento de una operación que sigue sin resultado y devuelve
+// cuántos intentos lleva
+func (s *Schedule) Retry(id string, now time.Time, settings Settings) int {
+	state, exists := s.attempts[id]
+	if !exists {
+		state = &attempt{}
+		s.attempts[id] = state
+	}
+
+	state.count++
+	state.nextAt = now.Add(Delay(state.count, settings.Initial, settings.Max))
+
+	return state.count
+}
+
+// Scheduled indica si la operación tiene un intento programado
+func (s *Schedule) Scheduled(id string) bool {
+	_, exists := s.attempts[id]
+	return exists
+}
+
+// Forget olvida la operación; si sigue pendiente vuelve a empezar su backoff
+func (s *Schedule) Forget(id string) {
+	delete(s.attempts, id)
+}
+
+// Sweep olvida las operaciones que no estuvieron pendientes en la revisión que termina, porque
+// se finalizaron por otra vía (webhook, polling del frontend o administrador)
+func (s *Schedule) Sweep() {
+	for id := range s.attempts {
+		if !s.seen[id] {
+			delete(s.attempts, id)
+		}
+	}
+	s.seen = make(map[string]bool)
+}
@@ -0,0 +1,80 @@
+package backoff
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDelayDoublesUpToTheMaximum(t *testing.T) {
+	cases := []struct {
+		count int
+		want  time.Duration
+	}{
+		{1, 5 * time.Second},
+		{2, 10 * time.Second},
+		{3, 20 * time.Second},
+		{6, 160 * time.Second},
+		{7, 5 * time.Minute},
+		{50, 5 * time.Minute},
+	}
+
+	for _, c := range cases {
+		if got := Delay(c.count, 5*time.Second, 5*time.Minute); got != c.want {
+			t.Errorf("Delay(%d) = %s, want %s", c.count, got, c.want)
+		}
+	}
+}
+
+func TestScheduleWaitsTheBackoff(t *testing.T) {
+	settings := Settings{Initial: 5 * time.Second, Max: time.Minute}
+	schedule := NewSchedule()
+	now := time.Now().UTC()
+
+	if !schedule.Due("OP-A", now) {
+		t.Fatalf("a new operation is not due")
+	}
+	if count := schedule.Retry("OP-A", now, settings); count != 1 {
+		t.Fatalf("count = %d, want 1", count)
+	}
+
+	if schedule.Due("OP-A", now.Add(4*time.Second)) {
+		t.Fatalf("OP-A is due before its backoff")
+	}
+	if !schedule.Due("OP-A", now.Add(5*time.Second)) {
+		t.Fatalf("OP-A is not due after its backoff")
+	}
+	if count := schedule.Retry("OP-A", now.Add(5*time.Second), settings); count != 2 {
+		t.Fatalf("count = %d, want 2", count)
+	}
+	if schedule.Due("OP-A", now.Add(14*time.Second)) {
+		t.Fatalf("OP-A is due before its doubled backoff")
+	}
+
+	schedule.Forget("OP-A")
+	if schedule.Scheduled("OP-A") || !schedule.Due("OP-A", now) {
+		t.Fatalf("a forgotten operation is not due again")
+	}
+}
+
+func TestSweepForgetsOperationsNoLongerPending(t *testing.T) {
+	settings := Settings{Initial: 5 * time.Second, Max: time.Minute}
+	schedule := NewSchedule()
+	now := time.Now().UTC()
+
+	for _, id := range []string{"OP-A", "OP-B"} {
+		schedule.Due(id, now)
+		schedule.Retry(id, now, settings)
+	}
+	schedule.Sweep()
+
+	// En la siguiente revisión solo OP-A sigue pendiente
+	schedule.Due("OP-A", now)
+	schedule.Sweep()
+
+	if !schedule.Scheduled("OP-A") {
+		t.Fatalf("OP-A is no longer scheduled")
+	}
+	if schedule.Scheduled("OP-B") {
+		t.Fatalf("OP-B is still scheduled after it was settled")
+	}
+}
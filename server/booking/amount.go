@@ -0,0 +1,98 @@
+package booking
+
+import (
+	"fmt"
+	"raffle_web_server/config"
+	"raffle_web_server/store"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// amountDecimals son los decimales con los que se cobra un pago
+const amountDecimals = 2
+
+// RateSource devuelve la tasa del día para convertir de from a to
+type RateSource func(from, to string) (store.ExchangeRate, error)
+
+// quote calcula el monto de la reserva en la moneda del pago: cantidad de tickets por el
+// precio de la rifa, convertido con la tasa del día si la moneda es distinta. La cotización
+// se mantiene hasta QuoteExpiresAt aunque la tasa cambie; después se vuelve a calcular.
+func (s *Service) quote(booking *store.Booking, currency string, now time.Time) error {
+	currency = strings.ToUpper(currency)
+
+	isQuoted := booking.Currency == currency && !booking.Amount.IsZero()
+	if isQuoted && (booking.QuoteExpiresAt == nil || now.Before(*booking.QuoteExpiresAt)) {
+		return nil
+	}
+
+	raffle, err := s.repository.GetRaffle(booking.RaffleId)
+	if err != nil {
+		return err
+	}
+
+	amount := decimal.NewFromFloat(raffle.Price).Mul(decimal.NewFromInt(int64(len(booking.Tickets))))
+
+	booking.Rate = decimal.NewFromInt(1)
+	booking.RateDate = ""
+	booking.QuoteExpiresAt = nil
+
+	if !strings.EqualFold(raffle.Currency, currency) {
+		rate, err := s.rates(raffle.Currency, currency)
+		if err != nil {
+			return err
+		}
+
+		expiresAt := now.Add(config.GetConfig().ExchangeRateConfig.QuoteTTL())
+
+		amount = amount.Mul(rate.Rate)
+		booking.Rate = rate.Rate
+		booking.RateDate = rate.Date
+		booking.QuoteExpiresAt = &expiresAt
+	}
+
+	booking.Amount = amount.Round(amountDecimals)
+	booking.Currency = currency
+
+	return nil
+}
+
+// bindAmount verifica que el monto enviado por el cliente sea el cotizado para la reserva y
+// deja registrados en ella el monto y la tasa del pago
+func (s *Service) bindAmount(booking *store.Booking, amount float64, currency string) error {
+	if err := s.quote(booking, currency, time.Now().UTC()); err != nil {
+		return err
+	}
+
+	received := decimal.NewFromFloat(amount).Round(amountDecimals)
+	if !received.Equal(booking.Amount) {
+		return fmt.Errorf("%w: amount %s %s does not match booking amount %s %s",
+			ErrMismatch, received.StringFixed(amountDecimals), booking.Currency, booking.Amount.StringFixed(amountDecimals), booking.Currency)
+	}
+
+	return nil
+}
+
+// Quote cotiza la reserva en la moneda del pago y guarda la cotización, que se respeta
+// hasta su vencimiento
+func (s *Service) Quote(bookingId, currency string) (*store.Booking, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	booking, err := s.load(bookingId, store.BookingDebitSubmitted)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.quote(booking, currency, time.Now().UTC()); err != nil {
+		return nil, err
+	}
+
+	booking.UpdatedAt = time.Now().UTC()
+	if err := s.repository.SaveBooking(*booking); err != nil {
+		return nil, err
+	}
+
+	return booking, nil
+}
@@ -0,0 +1,124 @@
+package booking
+
+import (
+	"context"
+	"fmt"
+	"raffle_web_server/backoff"
+	"raffle_web_server/store"
+	"time"
+)
+
+// StatusFetcher consulta en SyPago el estado de una transacción
+type StatusFetcher func(ctx context.Context, booking store.Booking) (PaymentResult, error)
+
+// Reconciler consulta periódicamente las transacciones enviadas a SyPago que siguen
+// pendientes (PEND, PROC, AC00) y finaliza las reservas cuando llegan a ACCP o RJCT.
+// Cada transacción se reintenta con backoff exponencial; las que siguen sin resultado después
+// de ReconcileConfig.MaxPendingSeconds pasan a revisión manual y dejan de consultarse.
+type Reconciler struct {
+	service  *Service
+	fetch    StatusFetcher
+	schedule *backoff.Schedule
+}
+
+func NewReconciler(service *Service, fetch StatusFetcher) *Reconciler {
+	return &Reconciler{
+		service:  service,
+		fetch:    fetch,
+		schedule: backoff.NewSchedule(),
+	}
+}
+
+// pendingDebits devuelve las reservas que esperan el resultado de un débito, también las que
+// perdieron la retención de sus tickets antes de la confirmación. Las que ya están en revisión
+// manual no se consultan más.
+func (r *Reconciler) pendingDebits() ([]store.Booking, error) {
+	bookings, err := r.service.repository.ListBookingsByStatus(store.BookingDebitSubmitted)
+	if err != nil {
+		return nil, err
+	}
+
+	pending := bookings[:0]
+	for _, booking := range bookings {
+		if booking.ManualReview == nil {
+			pending = append(pending, booking)
+		}
+	}
+
+	return pending, nil
+}
+
+// pendingSince devuelve desde cuándo el débito espera su resultado
+func pendingSince(booking store.Booking) time.Time {
+	if booking.DebitSubmittedAt != nil {
+		return *booking.DebitSubmittedAt
+	}
+	return booking.UpdatedAt
+}
+
+// reconcile consulta las transacciones pendientes cuyo próximo intento ya llegó
+func (r *Reconciler) reconcile(ctx context.Context) {
+	bookings, err := r.pendingDebits()
+	if err != nil {
+		fmt.Printf("Error listing pending bookings for reconciliation: %v\n", err)
+		return
+	}
+
+	settings := backoff.SettingsFromConfig()
+	now := time.Now().UTC()
+
+	for _, current := range bookings {
+		if ctx.Err() != nil {
+			return
+		}
+
+		if !r.schedule.Due(current.ID, now) || current.TransactionId == "" {
+			continue
+		}
+
+		result, err := r.fetch(ctx, current)
+		if err == nil && (result.Status == PaymentAccepted || result.Status == PaymentRejected) {
+			if _, err := r.service.ApplyPaymentResult(current.ID, result); err != nil {
+				fmt.Printf("Error finalizing booking %s: %v\n", current.ID, err)
+			} else {
+				r.schedule.Forget(current.ID)
+				continue
+			}
+		}
+
+		if err != nil {
+			fmt.Printf("Error fetching transaction %s for booking %s: %v\n", current.TransactionId, current.ID, err)
+		}
+
+		count := r.schedule.Retry(current.ID, now, settings)
+
+		if now.Sub(pendingSince(current)) > settings.MaxPending {
+			reason := fmt.Sprintf("transaction %s without a final status after %d attempts", current.TransactionId, count)
+			if _, err := r.service.RequestReview(current.ID, reason); err != nil {
+				fmt.Printf("Error sending booking %s to manual review: %v\n", current.ID, err)
+			} else {
+				r.schedule.Forget(current.ID)
+			}
+		}
+	}
+
+	// Olvidar las reservas que ya se finalizaron por otra vía (polling del frontend o webhook)
+	r.schedule.Sweep()
+}
+
+// Run reconcilia periódicamente las transacciones pendientes hasta que se cancele el contexto
+func (r *Reconciler) Run(ctx context.Context) {
+	ticker := time.NewTicker(backoff.SettingsFromConfig().Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.reconcile(ctx)
+
+			ticker.Reset(backoff.SettingsFromConfig().Interval)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package booking
+
+import (
+	"context"
+	"errors"
+	"raffle_web_server/backoff"
+	"raffle_web_server/store"
+	"testing"
+	"time"
+)
+
+func TestReconcileSettlesFinalResults(t *testing.T) {
+	service, repository := newTestService(t)
+	now := time.Now().UTC()
+
+	reserveBooking(t, repository, "BK-A", []int{1}, now.Add(time.Minute))
+	reserveBooking(t, repository, "BK-B", []int{2}, now.Add(time.Minute))
+
+	for _, bookingId := range []string{"BK-A", "BK-B"} {
+		if _, err := service.MarkDebitSubmitted(bookingId, "TX-"+bookingId, "secret"); err != nil {
+			t.Fatalf("MarkDebitSubmitted(%s): %v", bookingId, err)
+		}
+	}
+
+	fetches := 0
+	reconciler := NewReconciler(service, func(ctx context.Context, booking store.Booking) (PaymentResult, error) {
+		fetches++
+		if booking.ID == "BK-A" {
+			return PaymentResult{Status: PaymentAccepted, RefIbp: "REF-A"}, nil
+		}
+		return PaymentResult{Status: "PEND"}, nil
+	})
+
+	reconciler.reconcile(context.Background())
+
+	paid, err := repository.GetBooking("BK-A")
+	if err != nil {
+		t.Fatalf("GetBooking: %v", err)
+	}
+	if paid.Status != store.BookingPaid || paid.RefIbp != "REF-A" {
+		t.Fatalf("BK-A = %s with ref %q, want %s with REF-A", paid.Status, paid.RefIbp, store.BookingPaid)
+	}
+
+	pending, err := repository.GetBooking("BK-B")
+	if err != nil {
+		t.Fatalf("GetBooking: %v", err)
+	}
+	if pending.Status != store.BookingDebitSubmitted || pending.ManualReview != nil {
+		t.Fatalf("BK-B = %s, review %v, want it still waiting for the debit", pending.Status, pending.ManualReview)
+	}
+
+	// El siguiente intento de BK-B espera el backoff
+	reconciler.reconcile(context.Background())
+	if fetches != 2 {
+		t.Fatalf("fetches = %d, want 2", fetches)
+	}
+	if reconciler.schedule.Scheduled("BK-A") {
+		t.Fatalf("the settled booking is still tracked")
+	}
+}
+
+func TestStaleDebitGoesToManualReview(t *testing.T) {
+	service, repository := newTestService(t)
+	now := time.Now().UTC()
+
+	reserveBooking(t, repository, "BK-A", []int{1, 2}, now.Add(time.Minute))
+
+	booking, err := service.MarkDebitSubmitted("BK-A", "TX-1", "secret")
+	if err != nil {
+		t.Fatalf("MarkDebitSubmitted: %v", err)
+	}
+
+	// El débito lleva más del máximo sin resultado
+	submittedAt := now.Add(-backoff.SettingsFromConfig().MaxPending - time.Minute)
+	booking.DebitSubmittedAt = &submittedAt
+	if err := repository.SaveBooking(*booking); err != nil {
+		t.Fatalf("SaveBooking: %v", err)
+	}
+
+	fetches := 0
+	reconciler := NewReconciler(service, func(ctx context.Context, booking store.Booking) (PaymentResult, error) {
+		fetches++
+		return PaymentResult{}, errors.New("sypago unavailable")
+	})
+
+	reconciler.reconcile(context.Background())
+
+	flagged, err := repository.GetBooking("BK-A")
+	if err != nil {
+		t.Fatalf("GetBooking: %v", err)
+	}
+	if flagged.Status != store.BookingDebitSubmitted || !flagged.ManualReview.IsPending() {
+		t.Fatalf("BK-A = %s, review %v, want it pending manual review", flagged.Status, flagged.ManualReview)
+	}
+
+	// Ya no se consulta a SyPago
+	reconciler.schedule = backoff.NewSchedule()
+	reconciler.reconcile(context.Background())
+	if fetches != 1 {
+		t.Fatalf("fetches = %d, want 1", fetches)
+	}
+
+	reviews, err := service.PendingReviews()
+	if err != nil {
+		t.Fatalf("PendingReviews: %v", err)
+	}
+	if len(reviews) != 1 || reviews[0].ID != "BK-A" {
+		t.Fatalf("pending reviews = %v, want [BK-A]", reviews)
+	}
+
+	approved, err := service.Review("BK-A", true, "confirmed with the bank")
+	if err != nil {
+		t.Fatalf("Review: %v", err)
+	}
+	if approved.Status != store.BookingPaid || approved.ManualReview.ReviewedAt == nil {
+		t.Fatalf("BK-A = %s, review %v, want it paid and reviewed", approved.Status, approved.ManualReview)
+	}
+
+	ticket, err := repository.GetTicket("raffle-test", 1)
+	if err != nil {
+		t.Fatalf("GetTicket: %v", err)
+	}
+	if ticket.Status != store.TicketSold || ticket.BookingId != "BK-A" {
+		t.Fatalf("ticket 1 = %s by %s, want sold to BK-A", ticket.Status, ticket.BookingId)
+	}
+
+	if _, err := service.Review("BK-A", false, "again"); !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("second Review error = %v, want %v", err, ErrInvalidTransition)
+	}
+}
+
+func TestLateResultSettlesADebitInManualReview(t *testing.T) {
+	service, repository := newTestService(t)
+
+	reserveBooking(t, repository, "BK-A", []int{1}, time.Now().UTC().Add(time.Minute))
+
+	if _, err := service.MarkDebitSubmitted("BK-A", "TX-1", "secret"); err != nil {
+		t.Fatalf("MarkDebitSubmitted: %v", err)
+	}
+	if _, err := service.RequestReview("BK-A", "no final status"); err != nil {
+		t.Fatalf("RequestReview: %v", err)
+	}
+
+	// El webhook de SyPago llega después de enviar la reserva a revisión
+	rejected, err := service.ApplyPaymentResult("BK-A", PaymentResult{Status: PaymentRejected, RejectedCode: "AB01"})
+	if err != nil {
+		t.Fatalf("ApplyPaymentResult: %v", err)
+	}
+	if rejected.Status != store.BookingRejected {
+		t.Fatalf("status = %s, want %s", rejected.Status, store.BookingRejected)
+	}
+
+	reviews, err := service.PendingReviews()
+	if err != nil {
+		t.Fatalf("PendingReviews: %v", err)
+	}
+	if len(reviews) != 0 {
+		t.Fatalf("pending reviews = %v, want none", reviews)
+	}
+}
@@ -0,0 +1,222 @@
+package booking
+
+import (
+	"fmt"
+	"raffle_web_server/reservation"
+	"raffle_web_server/store"
+	"sort"
+	"time"
+)
+
+// SubmitProof registra el comprobante de un pago manual. El monto debe ser el cotizado para la
+// reserva; los tickets quedan retenidos en PENDING_REVIEW hasta que un administrador lo revise.
+// Mientras no se haya revisado, el comprador puede reemplazar el comprobante.
+func (s *Service) SubmitProof(bookingId string, proof store.PaymentProof) (*store.Booking, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	booking, err := s.load(bookingId, store.BookingPendingReview)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.bindAmount(booking, proof.Amount.InexactFloat64(), proof.Currency); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	heldUntil := now.Add(reservation.ReviewHoldTTL())
+
+	if err := s.extendHold(booking, heldUntil); err != nil {
+		return nil, err
+	}
+
+	proof.Amount = booking.Amount
+	proof.Currency = booking.Currency
+	proof.SubmittedAt = now
+
+	booking.Status = store.BookingPendingReview
+	booking.Proof = &proof
+	booking.ExpiresAt = heldUntil
+	booking.UpdatedAt = now
+
+	if err := s.repository.SaveBooking(*booking); err != nil {
+		return nil, err
+	}
+
+	return booking, nil
+}
+
+// referenceUsed indica si otra reserva pagada o en revisión ya presentó la referencia del banco
+func (s *Service) referenceUsed(bookingId, bank, reference string) (bool, error) {
+	for _, status := range []store.BookingStatus{store.BookingPaid, store.BookingPendingReview} {
+		bookings, err := s.repository.ListBookingsByStatus(status)
+		if err != nil {
+			return false, err
+		}
+
+		for _, other := range bookings {
+			if other.ID != bookingId && other.Proof != nil && other.Proof.Bank == bank && other.Proof.Reference == reference {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// ConfirmTransfer registra un pago cuya referencia se verificó contra los movimientos de la cuenta
+// del comercio. El monto debe ser el cotizado y la referencia no puede haber pagado otra reserva;
+// la reserva pasa por PENDING_REVIEW y se aprueba en el mismo paso, con las reglas de Review.
+func (s *Service) ConfirmTransfer(bookingId string, proof store.PaymentProof, payer store.PaymentAccount) (*store.Booking, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	booking, err := s.load(bookingId, store.BookingPendingReview)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.submitting[booking.ID] {
+		return nil, fmt.Errorf("%w: the debit of booking %s is being submitted", ErrInvalidTransition, booking.ID)
+	}
+
+	if err := s.bindAmount(booking, proof.Amount.InexactFloat64(), proof.Currency); err != nil {
+		return nil, err
+	}
+
+	used, err := s.referenceUsed(booking.ID, proof.Bank, proof.Reference)
+	if err != nil {
+		return nil, err
+	}
+	if used {
+		return nil, fmt.Errorf("%w: reference %s of bank %s", ErrReferenceUsed, proof.Reference, proof.Bank)
+	}
+
+	now := time.Now().UTC()
+
+	proof.Amount = booking.Amount
+	proof.Currency = booking.Currency
+	proof.SubmittedAt = now
+	proof.ReviewedAt = &now
+	proof.ReviewNote = "verified against the account movements"
+
+	booking.Status = store.BookingPendingReview
+	booking.Proof = &proof
+	booking.Payer = &payer
+
+	if err := s.settle(booking, PaymentResult{Status: PaymentAccepted, RefIbp: proof.Reference}, now); err != nil {
+		return nil, err
+	}
+
+	return booking, nil
+}
+
+// RequestReview deja un débito sin resultado en la cola de revisión manual. El reconciliador
+// deja de consultarlo, pero el resultado que notifique SyPago se sigue aplicando.
+func (s *Service) RequestReview(bookingId, reason string) (*store.Booking, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	booking, err := s.repository.GetBooking(bookingId)
+	if err != nil {
+		return nil, err
+	}
+
+	if booking.Status != store.BookingDebitSubmitted || booking.ManualReview != nil {
+		return booking, nil
+	}
+
+	now := time.Now().UTC()
+	booking.ManualReview = &store.ManualReview{Reason: reason, RequestedAt: now}
+	booking.UpdatedAt = now
+
+	if err := s.repository.SaveBooking(*booking); err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("Booking %s sent to manual review: %s\n", booking.ID, reason)
+
+	return booking, nil
+}
+
+// reviewSince devuelve desde cuándo la reserva espera la revisión
+func reviewSince(booking store.Booking) time.Time {
+	if booking.Proof != nil {
+		return booking.Proof.SubmittedAt
+	}
+	return booking.ManualReview.RequestedAt
+}
+
+// PendingReviews devuelve las reservas con comprobantes por revisar y los débitos enviados
+// a revisión manual, las más antiguas primero
+func (s *Service) PendingReviews() ([]store.Booking, error) {
+	bookings, err := s.repository.ListBookingsByStatus(store.BookingPendingReview)
+	if err != nil {
+		return nil, err
+	}
+
+	debits, err := s.repository.ListBookingsByStatus(store.BookingDebitSubmitted)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, debit := range debits {
+		if debit.ManualReview.IsPending() {
+			bookings = append(bookings, debit)
+		}
+	}
+
+	sort.Slice(bookings, func(i, j int) bool {
+		return reviewSince(bookings[i]).Before(reviewSince(bookings[j]))
+	})
+
+	return bookings, nil
+}
+
+// Review aprueba o rechaza el comprobante de un pago manual o un débito enviado a revisión manual.
+// Se aplican las mismas reglas que a un débito de SyPago: aprobar vende los tickets como ACCP y
+// rechazar los libera como RJCT, también si la retención venció esperando la revisión.
+func (s *Service) Review(bookingId string, approved bool, note string) (*store.Booking, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	booking, err := s.repository.GetBooking(bookingId)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	result := PaymentResult{Status: PaymentRejected}
+
+	switch {
+	case booking.Status == store.BookingPendingReview:
+		proof := *booking.Proof
+		proof.ReviewedAt = &now
+		proof.ReviewNote = note
+		booking.Proof = &proof
+
+		if approved {
+			result = PaymentResult{Status: PaymentAccepted, RefIbp: proof.Reference}
+		}
+
+	case booking.Status == store.BookingDebitSubmitted && booking.ManualReview.IsPending():
+		review := *booking.ManualReview
+		review.ReviewedAt = &now
+		review.Note = note
+		booking.ManualReview = &review
+
+		if approved {
+			result = PaymentResult{Status: PaymentAccepted}
+		}
+
+	default:
+		return nil, fmt.Errorf("%w: booking %s is %s", ErrInvalidTransition, booking.ID, booking.Status)
+	}
+
+	if err := s.settle(booking, result, now); err != nil {
+		return nil, err
+	}
+
+	return booking, nil
+}
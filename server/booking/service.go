@@ -0,0 +1,455 @@
+package booking
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"raffle_web_server/reservation"
+	"raffle_web_server/store"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Estados de transacción de SyPago que finalizan una reserva
+const (
+	PaymentAccepted = "ACCP"
+	PaymentRejected = "RJCT"
+)
+
+// ErrInvalidTransition se devuelve cuando la reserva no admite la operación en su estado actual
+var ErrInvalidTransition = errors.New("invalid booking transition")
+
+// ErrExpired se devuelve cuando la retención de los tickets de la reserva ya venció
+var ErrExpired = errors.New("booking expired")
+
+// ErrMismatch se devuelve cuando los datos del pago no corresponden a la reserva
+var ErrMismatch = errors.New("payment data does not match the booking")
+
+// ErrReferenceUsed se devuelve cuando la referencia del pago ya se usó para pagar otra reserva
+var ErrReferenceUsed = errors.New("payment reference already used")
+
+// ErrTicketsLost se devuelve cuando otra reserva tomó alguno de los tickets antes de enviar el débito
+var ErrTicketsLost = errors.New("booking tickets are no longer held")
+
+// PaymentResult es el estado de un pago informado por su proveedor, con los estados de SyPago
+type PaymentResult struct {
+	Status       string
+	RefIbp       string
+	RejectedCode string
+	Reason       string // descripción legible del estado
+}
+
+// Service administra el ciclo de vida de las reservas:
+// RESERVED → OTP_REQUESTED → DEBIT_SUBMITTED → PAID / REJECTED / EXPIRED, o
+// RESERVED → PENDING_REVIEW → PAID / REJECTED para los pagos manuales.
+// Los tickets solo se venden cuando se acepta el pago y se liberan si se rechaza.
+type Service struct {
+	repository store.RaffleRepository
+	secrets    *store.SecretBox
+	rates      RateSource
+	submitting map[string]bool // reservas cuyo débito se está enviando a SyPago
+	mu         sync.Mutex
+}
+
+func NewService(repository store.RaffleRepository, secrets *store.SecretBox, rates RateSource) *Service {
+	return &Service{repository: repository, secrets: secrets, rates: rates, submitting: make(map[string]bool)}
+}
+
+// Get devuelve la reserva persistida
+func (s *Service) Get(bookingId string) (*store.Booking, error) {
+	return s.repository.GetBooking(bookingId)
+}
+
+// expireIfDue marca la reserva como expirada si su retención venció antes de enviar el débito
+func (s *Service) expireIfDue(booking *store.Booking, now time.Time) error {
+	if !booking.Status.IsAwaitingPayment() || now.Before(booking.ExpiresAt) {
+		return nil
+	}
+
+	if _, err := s.repository.ReleaseTickets(booking.RaffleId, booking.ID); err != nil {
+		return err
+	}
+
+	booking.Status = store.BookingExpired
+	booking.UpdatedAt = now
+	if err := s.repository.SaveBooking(*booking); err != nil {
+		return err
+	}
+
+	return fmt.Errorf("%w: booking %s expired at %s", ErrExpired, booking.ID, booking.ExpiresAt.Format(time.RFC3339))
+}
+
+// ExpireRaffle expira las reservas de una rifa cancelada que todavía no enviaron el pago y libera
+// sus tickets. Las reservas con un pago en curso conservan la retención hasta conocer el resultado;
+// si el pago se acepta, la devolución de la rifa cancelada lo reintegra.
+func (s *Service) ExpireRaffle(raffleId string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	bookings, err := s.repository.ListBookings(raffleId)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	expired := make([]string, 0)
+
+	for _, booking := range bookings {
+		if !booking.Status.IsAwaitingPayment() || s.submitting[booking.ID] {
+			continue
+		}
+
+		if _, err := s.repository.ReleaseTickets(booking.RaffleId, booking.ID); err != nil {
+			return expired, err
+		}
+
+		booking.Status = store.BookingExpired
+		booking.UpdatedAt = now
+		if err := s.repository.SaveBooking(booking); err != nil {
+			return expired, err
+		}
+
+		expired = append(expired, booking.ID)
+	}
+
+	return expired, nil
+}
+
+// load obtiene la reserva y verifica que admita el estado indicado
+func (s *Service) load(bookingId string, next store.BookingStatus) (*store.Booking, error) {
+	booking, err := s.repository.GetBooking(bookingId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.expireIfDue(booking, time.Now().UTC()); err != nil {
+		return nil, err
+	}
+
+	if !booking.Status.CanTransitionTo(next) {
+		return nil, fmt.Errorf("%w: booking %s is %s", ErrInvalidTransition, booking.ID, booking.Status)
+	}
+
+	return booking, nil
+}
+
+// MarkOtpRequested verifica el monto del débito, lo liga a la reserva y registra que se
+// solicitó el OTP y la cuenta del pagador, a la que se devuelve el dinero si hace falta
+func (s *Service) MarkOtpRequested(bookingId string, amount float64, currency string, payer store.PaymentAccount) (*store.Booking, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	booking, err := s.load(bookingId, store.BookingOtpRequested)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.bindAmount(booking, amount, currency); err != nil {
+		return nil, err
+	}
+
+	booking.Status = store.BookingOtpRequested
+	booking.Payer = &payer
+	booking.UpdatedAt = time.Now().UTC()
+
+	if err := s.repository.SaveBooking(*booking); err != nil {
+		return nil, err
+	}
+
+	return booking, nil
+}
+
+// SelectProvider registra el proveedor con el que se pagará la reserva. Se puede cambiar de
+// proveedor mientras el pago no se haya enviado.
+func (s *Service) SelectProvider(bookingId, provider string) (*store.Booking, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	booking, err := s.repository.GetBooking(bookingId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.expireIfDue(booking, time.Now().UTC()); err != nil {
+		return nil, err
+	}
+
+	if booking.Provider == provider {
+		return booking, nil
+	}
+
+	if !booking.Status.IsAwaitingPayment() || s.submitting[booking.ID] {
+		return nil, fmt.Errorf("%w: booking %s is %s with provider %s", ErrInvalidTransition, booking.ID, booking.Status, booking.Provider)
+	}
+
+	booking.Provider = provider
+	booking.UpdatedAt = time.Now().UTC()
+
+	if err := s.repository.SaveBooking(*booking); err != nil {
+		return nil, err
+	}
+
+	return booking, nil
+}
+
+// FindByPayment busca la reserva asociada a una transacción de SyPago
+func (s *Service) FindByPayment(internalId, transactionId string) (*store.Booking, error) {
+	return s.repository.FindBookingByPayment(internalId, transactionId)
+}
+
+// PrepareDebit verifica que la reserva pueda enviar el débito y que los datos del pago, incluido
+// el monto, le correspondan, y guarda el internal_id de la operación para poder asociar las
+// notificaciones de SyPago. Los tickets se retienen por el plazo del pago antes de llamar a SyPago
+// y, si otra reserva tomó alguno, el débito se rechaza: no se cobra el monto completo por menos
+// tickets. La reserva queda tomada para el envío hasta MarkDebitSubmitted o ReleaseDebit, de modo
+// que un segundo envío simultáneo se rechaza sin llegar a SyPago.
+func (s *Service) PrepareDebit(bookingId, raffleId, participantId string, tickets []int, amount float64, currency, internalId string) (*store.Booking, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	booking, err := s.load(bookingId, store.BookingDebitSubmitted)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.submitting[booking.ID] {
+		return nil, fmt.Errorf("%w: the debit of booking %s is already being submitted", ErrInvalidTransition, booking.ID)
+	}
+
+	if booking.RaffleId != raffleId || booking.ParticipantId != participantId {
+		return nil, fmt.Errorf("%w: raffle or participant differs from booking %s", ErrMismatch, booking.ID)
+	}
+
+	if !sameTickets(booking.Tickets, tickets) {
+		return nil, fmt.Errorf("%w: tickets differ from booking %s", ErrMismatch, booking.ID)
+	}
+
+	if err := s.bindAmount(booking, amount, currency); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	heldUntil := now.Add(reservation.PaymentHoldTTL())
+
+	if err := s.extendHold(booking, heldUntil); err != nil {
+		return nil, err
+	}
+	if len(booking.LostTickets) > 0 {
+		return nil, fmt.Errorf("%w: tickets %v of booking %s were taken by another booking", ErrTicketsLost, booking.LostTickets, booking.ID)
+	}
+
+	booking.InternalId = internalId
+	booking.ExpiresAt = heldUntil
+	booking.UpdatedAt = now
+
+	if err := s.repository.SaveBooking(*booking); err != nil {
+		return nil, err
+	}
+
+	s.submitting[booking.ID] = true
+
+	return booking, nil
+}
+
+// ReleaseDebit libera la reserva tomada por PrepareDebit cuando el débito no llegó a enviarse,
+// para que el comprador pueda reintentarlo
+func (s *Service) ReleaseDebit(bookingId string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.submitting, bookingId)
+}
+
+// DebitInternalId deriva el internal_id de SyPago a partir de la reserva, de modo que un
+// débito reenviado para la misma reserva lleve el mismo identificador y SyPago lo reconozca
+// como duplicado en lugar de cobrarlo dos veces
+func DebitInternalId(bookingId string) string {
+	hash := sha256.Sum256([]byte("debit\x00" + bookingId))
+	return strings.ToUpper(hex.EncodeToString(hash[:16]))
+}
+
+// OperationSecret descifra el operation_secret de la transacción de la reserva
+func (s *Service) OperationSecret(booking *store.Booking) (string, error) {
+	return s.secrets.Open(booking.OperationSecret)
+}
+
+// extendHold retiene los tickets de la reserva hasta heldUntil. Los que otra reserva tomó mientras
+// tanto se registran en LostTickets; si el pago se confirma se devuelve su parte.
+func (s *Service) extendHold(booking *store.Booking, heldUntil time.Time) error {
+	conflicts, err := s.repository.ExtendHold(booking.RaffleId, booking.ID, booking.Tickets, heldUntil)
+	if err != nil {
+		return err
+	}
+
+	booking.LostTickets = nil
+	if len(conflicts) > 0 {
+		fmt.Printf("Booking %s lost tickets %v before the payment was submitted\n", booking.ID, conflicts)
+		booking.LostTickets = conflicts
+	}
+
+	return nil
+}
+
+// MarkDebitSubmitted registra la transacción creada en SyPago, guarda cifrado su operation_secret
+// y extiende la retención de los tickets mientras se espera la confirmación del pago. Si no se
+// puede registrar, la reserva sigue tomada para el envío hasta ReleaseDebit.
+func (s *Service) MarkDebitSubmitted(bookingId, transactionId, operationSecret string) (*store.Booking, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	booking, err := s.repository.GetBooking(bookingId)
+	if err != nil {
+		return nil, err
+	}
+
+	// La reserva pudo expirar mientras SyPago respondía, porque la retención vence aunque el envío
+	// esté en curso. El débito ya existe en SyPago, así que se registra igual: sin la transacción no
+	// se podría conciliar el cobro. Es seguro porque solo se vuelven a retener los tickets que sigan
+	// libres y, si el pago se acepta, los que otra reserva tomó quedan en LostTickets para devolverlos.
+	if !booking.Status.CanTransitionTo(store.BookingDebitSubmitted) && booking.Status != store.BookingExpired {
+		return nil, fmt.Errorf("%w: booking %s is %s", ErrInvalidTransition, booking.ID, booking.Status)
+	}
+
+	sealedSecret, err := s.secrets.Seal(operationSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	heldUntil := now.Add(reservation.PaymentHoldTTL())
+
+	if err := s.extendHold(booking, heldUntil); err != nil {
+		return nil, err
+	}
+
+	booking.Status = store.BookingDebitSubmitted
+	booking.TransactionId = transactionId
+	booking.OperationSecret = sealedSecret
+	booking.ExpiresAt = heldUntil
+	booking.DebitSubmittedAt = &now
+	booking.UpdatedAt = now
+
+	if err := s.repository.SaveBooking(*booking); err != nil {
+		return nil, err
+	}
+
+	delete(s.submitting, bookingId)
+
+	return booking, nil
+}
+
+// ApplyPaymentResult avanza la reserva según el estado de la transacción en SyPago.
+// ACCP vende los tickets y RJCT los libera; los estados intermedios no la modifican.
+// Un resultado que llega después de vencida la retención se aplica igual, porque el débito
+// ya se envió. Es idempotente: una reserva ya finalizada se devuelve sin cambios.
+func (s *Service) ApplyPaymentResult(bookingId string, result PaymentResult) (*store.Booking, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	booking, err := s.repository.GetBooking(bookingId)
+	if err != nil {
+		return nil, err
+	}
+
+	if booking.Status != store.BookingDebitSubmitted {
+		if !booking.Status.IsFinal() && (result.Status == PaymentAccepted || result.Status == PaymentRejected) {
+			fmt.Printf("Ignoring payment status %s for booking %s in status %s\n", result.Status, booking.ID, booking.Status)
+		}
+		return booking, nil
+	}
+
+	if result.Status != PaymentAccepted && result.Status != PaymentRejected {
+		return booking, nil
+	}
+
+	if err := s.settle(booking, result, time.Now().UTC()); err != nil {
+		return nil, err
+	}
+
+	return booking, nil
+}
+
+// isDrawn indica si la rifa ya se sorteó
+func (s *Service) isDrawn(raffleId string) (bool, error) {
+	draw, err := s.repository.GetDraw(raffleId)
+	if errors.Is(err, store.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return draw.IsDrawn(), nil
+}
+
+// settle finaliza la reserva con el resultado del pago: ACCP vende los tickets y RJCT los libera.
+// Si otra reserva tomó alguno de los tickets mientras tanto, se vende el resto y se registra el
+// conflicto. Si la rifa ya se sorteó no se vende ninguno y se registran todos como perdidos.
+func (s *Service) settle(booking *store.Booking, result PaymentResult, now time.Time) error {
+	switch result.Status {
+	case PaymentAccepted:
+		drawn, err := s.isDrawn(booking.RaffleId)
+		if err != nil {
+			return err
+		}
+
+		conflicts := append([]int(nil), booking.Tickets...)
+		if drawn {
+			if _, err := s.repository.ReleaseTickets(booking.RaffleId, booking.ID); err != nil {
+				return err
+			}
+		} else if conflicts, err = s.repository.SellTickets(booking.RaffleId, booking.ID, booking.Tickets); err != nil {
+			return err
+		}
+
+		booking.LostTickets = nil
+		if len(conflicts) > 0 {
+			fmt.Printf("Booking %s was paid but tickets %v could not be sold\n", booking.ID, conflicts)
+			booking.LostTickets = conflicts
+		}
+
+		booking.Status = store.BookingPaid
+		booking.RefIbp = result.RefIbp
+		booking.PaidAt = &now
+
+	case PaymentRejected:
+		if _, err := s.repository.ReleaseTickets(booking.RaffleId, booking.ID); err != nil {
+			return err
+		}
+
+		booking.Status = store.BookingRejected
+		booking.RejectedCode = result.RejectedCode
+	}
+
+	booking.UpdatedAt = now
+
+	if err := s.repository.SaveBooking(*booking); err != nil {
+		return err
+	}
+
+	fmt.Printf("Booking %s finalized as %s\n", booking.ID, booking.Status)
+
+	return nil
+}
+
+// sameTickets indica si ambas listas contienen los mismos números
+func sameTickets(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	sortedA := append([]int(nil), a...)
+	sortedB := append([]int(nil), b...)
+	sort.Ints(sortedA)
+	sort.Ints(sortedB)
+
+	for i := range sortedA {
+		if sortedA[i] != sortedB[i] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,253 @@
+package booking
+
+import (
+	"errors"
+	"raffle_web_server/store"
+	"raffle_web_server/store/storetest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func newTestService(t *testing.T) (*Service, *store.FileRepository) {
+	t.Helper()
+
+	repository := storetest.NewRepository(t)
+	secrets := storetest.NewSecretBox(t)
+
+	rates := func(from, to string) (store.ExchangeRate, error) {
+		return store.ExchangeRate{}, store.ErrNotFound
+	}
+
+	return NewService(repository, secrets, rates), repository
+}
+
+// reserveBooking retiene los tickets para la reserva y la guarda con el OTP ya solicitado
+func reserveBooking(t *testing.T, repository *store.FileRepository, bookingId string, tickets []int, heldUntil time.Time) {
+	t.Helper()
+
+	conflicts, err := repository.HoldTickets("raffle-test", bookingId, tickets, heldUntil)
+	if err != nil || len(conflicts) > 0 {
+		t.Fatalf("HoldTickets(%s): conflicts %v, err %v", bookingId, conflicts, err)
+	}
+
+	now := time.Now().UTC()
+	err = repository.SaveBooking(store.Booking{
+		ID:        bookingId,
+		RaffleId:  "raffle-test",
+		Tickets:   tickets,
+		Status:    store.BookingOtpRequested,
+		ExpiresAt: heldUntil,
+		CreatedAt: now,
+		UpdatedAt: now,
+	})
+	if err != nil {
+		t.Fatalf("SaveBooking(%s): %v", bookingId, err)
+	}
+}
+
+func TestMarkDebitSubmittedExtendsFreeTickets(t *testing.T) {
+	service, repository := newTestService(t)
+	now := time.Now().UTC()
+
+	reserveBooking(t, repository, "BK-A", []int{1, 2, 3}, now.Add(time.Minute))
+
+	// Otra reserva toma el ticket 2 mientras el comprador completa el débito
+	if _, err := repository.ReleaseTickets("raffle-test", "BK-A"); err != nil {
+		t.Fatalf("ReleaseTickets: %v", err)
+	}
+	reserveBooking(t, repository, "BK-B", []int{2}, now.Add(time.Minute))
+
+	booking, err := service.MarkDebitSubmitted("BK-A", "TX-1", "secret")
+	if err != nil {
+		t.Fatalf("MarkDebitSubmitted: %v", err)
+	}
+
+	if booking.Status != store.BookingDebitSubmitted {
+		t.Fatalf("status = %s, want %s", booking.Status, store.BookingDebitSubmitted)
+	}
+	if !reflect.DeepEqual(booking.LostTickets, []int{2}) {
+		t.Fatalf("lost tickets = %v, want [2]", booking.LostTickets)
+	}
+
+	for _, number := range []int{1, 3} {
+		ticket, err := repository.GetTicket("raffle-test", number)
+		if err != nil {
+			t.Fatalf("GetTicket(%d): %v", number, err)
+		}
+		if ticket.BookingId != "BK-A" || !ticket.HeldUntil.Equal(booking.ExpiresAt) {
+			t.Errorf("ticket %d = %s until %s, want BK-A until %s", number, ticket.BookingId, ticket.HeldUntil, booking.ExpiresAt)
+		}
+	}
+}
+
+func TestLapsedDebitIsSettledWhenTheResultArrives(t *testing.T) {
+	service, repository := newTestService(t)
+	now := time.Now().UTC()
+
+	reserveBooking(t, repository, "BK-A", []int{1, 2}, now.Add(time.Minute))
+
+	if _, err := service.MarkDebitSubmitted("BK-A", "TX-1", "secret"); err != nil {
+		t.Fatalf("MarkDebitSubmitted: %v", err)
+	}
+
+	// Vence la retención de pago sin que llegue la confirmación
+	if _, err := repository.ReleaseExpiredHolds(now.Add(24 * time.Hour)); err != nil {
+		t.Fatalf("ReleaseExpiredHolds: %v", err)
+	}
+
+	// La reserva sigue esperando el resultado del débito aunque perdió la retención
+	waiting, err := repository.GetBooking("BK-A")
+	if err != nil {
+		t.Fatalf("GetBooking: %v", err)
+	}
+	if waiting.Status != store.BookingDebitSubmitted {
+		t.Fatalf("status after the payment hold = %s, want %s", waiting.Status, store.BookingDebitSubmitted)
+	}
+
+	// Otra reserva toma el ticket 2 antes de que llegue el ACCP
+	reserveBooking(t, repository, "BK-B", []int{2}, now.Add(time.Minute))
+
+	paid, err := service.ApplyPaymentResult("BK-A", PaymentResult{Status: PaymentAccepted, RefIbp: "REF-1"})
+	if err != nil {
+		t.Fatalf("ApplyPaymentResult: %v", err)
+	}
+
+	if paid.Status != store.BookingPaid {
+		t.Fatalf("status = %s, want %s", paid.Status, store.BookingPaid)
+	}
+	if !reflect.DeepEqual(paid.LostTickets, []int{2}) {
+		t.Fatalf("lost tickets = %v, want [2]", paid.LostTickets)
+	}
+
+	ticket, err := repository.GetTicket("raffle-test", 1)
+	if err != nil {
+		t.Fatalf("GetTicket: %v", err)
+	}
+	if ticket.Status != store.TicketSold || ticket.BookingId != "BK-A" {
+		t.Fatalf("ticket 1 = %s by %s, want sold to BK-A", ticket.Status, ticket.BookingId)
+	}
+}
+
+func TestPrepareDebitRejectsAConcurrentSubmission(t *testing.T) {
+	service, repository := newTestService(t)
+
+	reserveBooking(t, repository, "BK-A", []int{1, 2}, time.Now().UTC().Add(time.Minute))
+
+	quoted, err := repository.GetBooking("BK-A")
+	if err != nil {
+		t.Fatalf("GetBooking: %v", err)
+	}
+	quoted.ParticipantId = "P-1"
+	quoted.Amount = decimal.NewFromInt(20)
+	quoted.Currency = "VES"
+	if err := repository.SaveBooking(*quoted); err != nil {
+		t.Fatalf("SaveBooking: %v", err)
+	}
+
+	prepare := func() error {
+		_, err := service.PrepareDebit("BK-A", "raffle-test", "P-1", []int{2, 1}, 20, "VES", DebitInternalId("BK-A"))
+		return err
+	}
+
+	if err := prepare(); err != nil {
+		t.Fatalf("PrepareDebit: %v", err)
+	}
+
+	// Mientras el primer débito está en camino a SyPago, el segundo se rechaza localmente
+	if err := prepare(); !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("second PrepareDebit error = %v, want %v", err, ErrInvalidTransition)
+	}
+	if _, err := service.SelectProvider("BK-A", "manual"); !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("SelectProvider error = %v, want %v", err, ErrInvalidTransition)
+	}
+
+	// Si el envío falla el comprador puede reintentarlo
+	service.ReleaseDebit("BK-A")
+	if err := prepare(); err != nil {
+		t.Fatalf("PrepareDebit after ReleaseDebit: %v", err)
+	}
+
+	if _, err := service.MarkDebitSubmitted("BK-A", "TX-1", "secret"); err != nil {
+		t.Fatalf("MarkDebitSubmitted: %v", err)
+	}
+	if err := prepare(); !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("PrepareDebit after the debit was submitted: %v, want %v", err, ErrInvalidTransition)
+	}
+}
+
+func TestExpireRaffleKeepsPaymentsInFlight(t *testing.T) {
+	service, repository := newTestService(t)
+	now := time.Now().UTC()
+
+	reserveBooking(t, repository, "BK-A", []int{1}, now.Add(time.Minute))
+	reserveBooking(t, repository, "BK-B", []int{2}, now.Add(time.Minute))
+	reserveBooking(t, repository, "BK-C", []int{3}, now.Add(time.Minute))
+
+	if _, err := service.MarkDebitSubmitted("BK-B", "TX-B", "secret"); err != nil {
+		t.Fatalf("MarkDebitSubmitted: %v", err)
+	}
+	// El débito de BK-C se está enviando a SyPago
+	service.submitting["BK-C"] = true
+
+	expired, err := service.ExpireRaffle("raffle-test")
+	if err != nil {
+		t.Fatalf("ExpireRaffle: %v", err)
+	}
+	if !reflect.DeepEqual(expired, []string{"BK-A"}) {
+		t.Fatalf("expired bookings = %v, want [BK-A]", expired)
+	}
+
+	if _, err := repository.GetTicket("raffle-test", 1); !errors.Is(err, store.ErrNotFound) {
+		t.Fatalf("ticket 1 of the expired booking: err = %v, want %v", err, store.ErrNotFound)
+	}
+	for _, number := range []int{2, 3} {
+		ticket, err := repository.GetTicket("raffle-test", number)
+		if err != nil || ticket.Status != store.TicketReserved {
+			t.Fatalf("ticket %d of a payment in flight = %v, err %v, want it still reserved", number, ticket, err)
+		}
+	}
+}
+
+func TestPrepareDebitRefusesLostTickets(t *testing.T) {
+	service, repository := newTestService(t)
+	now := time.Now().UTC()
+
+	reserveBooking(t, repository, "BK-A", []int{1, 2}, now.Add(time.Minute))
+
+	quoted, err := repository.GetBooking("BK-A")
+	if err != nil {
+		t.Fatalf("GetBooking: %v", err)
+	}
+	quoted.ParticipantId = "P-1"
+	quoted.Amount = decimal.NewFromInt(20)
+	quoted.Currency = "VES"
+	if err := repository.SaveBooking(*quoted); err != nil {
+		t.Fatalf("SaveBooking: %v", err)
+	}
+
+	// Otra reserva toma el ticket 2 antes de que el comprador envíe el OTP
+	if _, err := repository.ReleaseTickets("raffle-test", "BK-A"); err != nil {
+		t.Fatalf("ReleaseTickets: %v", err)
+	}
+	reserveBooking(t, repository, "BK-B", []int{2}, now.Add(time.Minute))
+
+	_, err = service.PrepareDebit("BK-A", "raffle-test", "P-1", []int{1, 2}, 20, "VES", DebitInternalId("BK-A"))
+	if !errors.Is(err, ErrTicketsLost) {
+		t.Fatalf("PrepareDebit error = %v, want %v", err, ErrTicketsLost)
+	}
+
+	// El débito no quedó tomado y la reserva no cambió de estado
+	if service.submitting["BK-A"] {
+		t.Fatalf("the debit is still claimed after the refusal")
+	}
+	current, err := repository.GetBooking("BK-A")
+	if err != nil {
+		t.Fatalf("GetBooking: %v", err)
+	}
+	if current.Status != store.BookingOtpRequested || current.InternalId != "" {
+		t.Fatalf("booking = %s with internal id %q, want it unchanged", current.Status, current.InternalId)
+	}
+}
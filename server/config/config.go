@@ -164,12 +164,20 @@ func GetFolderOfExecutable() string {
 func GetConfig() *ConfigFile {
 	return appConfiguration.getConfig()
 }
-func init() {
+
+// Load lee el archivo de configuración junto al ejecutable y vigila sus cambios.
+// Se llama al inicio de main; hasta entonces GetConfig devuelve la configuración vacía,
+// que usa los valores por defecto.
+func Load() {
 
 	signal.Notify(done, syscall.SIGINT, syscall.SIGTERM)
 
-	appConfiguration = new(AppConfig)
 	loadExecutablePath()
 	loadGlobalConfig()
 	go appSettingsFileWatcher()
 }
+
+func init() {
+
+	appConfiguration = new(AppConfig)
+}
@@ -0,0 +1,24 @@
+package config
+
+import (
+	"path/filepath"
+	"time"
+)
+
+const defaultQuoteTTL = 15 * time.Minute
+
+// QuoteTTL devuelve cuánto tiempo se respeta el monto cotizado a una reserva
+func (c ExchangeRateConfig) QuoteTTL() time.Duration {
+	if c.QuoteTTLSeconds <= 0 {
+		return defaultQuoteTTL
+	}
+	return time.Duration(c.QuoteTTLSeconds) * time.Second
+}
+
+// RatesPath devuelve la ruta absoluta del archivo de tasas
+func (c ExchangeRateConfig) RatesPath() string {
+	if c.RatesFile == "" || filepath.IsAbs(c.RatesFile) {
+		return c.RatesFile
+	}
+	return filepath.Join(executableFolder, c.RatesFile)
+}
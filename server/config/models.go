@@ -1,17 +1,27 @@
 package config
 
 type ConfigFile struct {
-	ServiceInfo `json:"ServiceInfo"`
-	SslConfig   `json:"SslConfig"`
-	CORSConfig  `json:"CORSConfig"`
-	MockConfig  `json:"MockConfig"`
+	ServiceInfo        `json:"ServiceInfo"`
+	SslConfig          `json:"SslConfig"`
+	CORSConfig         `json:"CORSConfig"`
+	MockConfig         `json:"MockConfig"`
+	StoreConfig        `json:"StoreConfig"`
+	ReservationConfig  `json:"ReservationConfig"`
+	DrawConfig         `json:"DrawConfig"`
+	AdminConfig        `json:"AdminConfig"`
+	RateLimitConfig    `json:"RateLimitConfig"`
+	SypagoConfig       `json:"SypagoConfig"`
+	ReconcileConfig    `json:"ReconcileConfig"`
+	ExchangeRateConfig `json:"ExchangeRateConfig"`
+	PaymentConfig      `json:"PaymentConfig"`
 }
 
 type ServiceInfo struct {
-	Version     string `json:"Version"`
-	Descripcion string `json:"Descripcion"`
-	HttpPort    int    `json:"HttpPort"`
-	GrpcPort    int    `json:"GrpcPort"`
+	Version        string   `json:"Version"`
+	Descripcion    string   `json:"Descripcion"`
+	HttpPort       int      `json:"HttpPort"`
+	GrpcPort       int      `json:"GrpcPort"`
+	TrustedProxies []string `json:"TrustedProxies"` // IPs o CIDRs de los proxies cuyo X-Forwarded-For se acepta
 }
 
 type SslConfig struct {
@@ -27,3 +37,83 @@ type CORSConfig struct {
 type MockConfig struct {
 	Enabled bool `json:"Enabled"`
 }
+
+type StoreConfig struct {
+	Path          string `json:"Path"`
+	SecretKeyPath string `json:"SecretKeyPath"`
+}
+
+type ReservationConfig struct {
+	HoldTTLSeconds        int `json:"HoldTTLSeconds"`
+	PaymentHoldTTLSeconds int `json:"PaymentHoldTTLSeconds"`
+	ReviewHoldTTLSeconds  int `json:"ReviewHoldTTLSeconds"`
+	SweepIntervalSeconds  int `json:"SweepIntervalSeconds"`
+}
+
+type DrawConfig struct {
+	MainWinners          int    `json:"MainWinners"`
+	BlessWinners         int    `json:"BlessWinners"`
+	CheckIntervalSeconds int    `json:"CheckIntervalSeconds"`
+	BeaconUrl            string `json:"BeaconUrl"` // API HTTP de la cadena drand cuya aleatoriedad se mezcla con la semilla
+	BeaconTimeoutSeconds int    `json:"BeaconTimeoutSeconds"`
+}
+
+type AdminConfig struct {
+	ApiKey string `json:"ApiKey"`
+}
+
+type RateLimitConfig struct {
+	PrizeLookupPerIp       int `json:"PrizeLookupPerIp"`
+	PrizeLookupPerDocument int `json:"PrizeLookupPerDocument"`
+	WindowSeconds          int `json:"WindowSeconds"`
+}
+
+type SypagoConfig struct {
+	BaseUrl               string `json:"BaseUrl"`
+	ClientId              string `json:"ClientId"`
+	ApiKey                string `json:"ApiKey"`     // se prefiere la variable SYPAGO_API_KEY o ApiKeyFile
+	ApiKeyFile            string `json:"ApiKeyFile"` // archivo con la clave, relativo al ejecutable
+	CreditorAccount       string `json:"CreditorAccount"`
+	CreditorBankCode      string `json:"CreditorBankCode"`
+	RequestTimeoutSeconds int    `json:"RequestTimeoutSeconds"`
+	DebitTimeoutSeconds   int    `json:"DebitTimeoutSeconds"`
+	WebhookUrl            string `json:"WebhookUrl"`
+	WebhookSecret         string `json:"WebhookSecret"`
+	Fake                  bool   `json:"Fake"` // usa el servidor falso de SyPago en el proceso; se lee al iniciar
+	BanksCacheTTLSeconds  int    `json:"BanksCacheTTLSeconds"`
+	RejectCodesFile       string `json:"RejectCodesFile"` // códigos de rechazo adicionales, relativo al ejecutable
+}
+
+type ReconcileConfig struct {
+	IntervalSeconds       int `json:"IntervalSeconds"`
+	InitialBackoffSeconds int `json:"InitialBackoffSeconds"`
+	MaxBackoffSeconds     int `json:"MaxBackoffSeconds"`
+	MaxPendingSeconds     int `json:"MaxPendingSeconds"` // tiempo sin resultado tras el que una operación pasa a revisión manual
+}
+
+type ExchangeRateConfig struct {
+	Provider        string             `json:"Provider"`  // "config" (por defecto) o "file"
+	RatesFile       string             `json:"RatesFile"` // archivo de tasas del proveedor "file", relativo al ejecutable
+	Rates           map[string]float64 `json:"Rates"`     // unidades de la segunda moneda por una de la primera, ej. "USD_VES"
+	QuoteTTLSeconds int                `json:"QuoteTTLSeconds"`
+}
+
+type PaymentConfig struct {
+	DefaultProviders []string             `json:"DefaultProviders"` // proveedores de las rifas que no definen los suyos
+	ReceiptsDir      string               `json:"ReceiptsDir"`      // comprobantes de pagos manuales, relativo al ejecutable
+	MaxReceiptBytes  int64                `json:"MaxReceiptBytes"`
+	ManualTransfer   ManualTransferConfig `json:"ManualTransfer"`
+	PagoMovil        PagoMovilConfig      `json:"PagoMovil"`
+}
+
+type PagoMovilConfig struct {
+	MovementsFile string `json:"MovementsFile"` // Pago Móvil recibidos exportados del banco, relativo al ejecutable; vacío deshabilita el proveedor
+}
+
+type ManualTransferConfig struct {
+	Bank          string `json:"Bank"`
+	AccountNumber string `json:"AccountNumber"`
+	AccountHolder string `json:"AccountHolder"`
+	DocumentId    string `json:"DocumentId"`
+	Phone         string `json:"Phone"` // para Pago Móvil
+}
@@ -0,0 +1,21 @@
+package config
+
+import "path/filepath"
+
+const defaultMaxReceiptBytes = 5 << 20
+
+// MaxReceiptSize devuelve el tamaño máximo aceptado para la imagen de un comprobante de pago
+func (c PaymentConfig) MaxReceiptSize() int64 {
+	if c.MaxReceiptBytes <= 0 {
+		return defaultMaxReceiptBytes
+	}
+	return c.MaxReceiptBytes
+}
+
+// MovementsPath devuelve la ruta absoluta del archivo de movimientos de Pago Móvil
+func (c PagoMovilConfig) MovementsPath() string {
+	if c.MovementsFile == "" || filepath.IsAbs(c.MovementsFile) {
+		return c.MovementsFile
+	}
+	return filepath.Join(executableFolder, c.MovementsFile)
+}
@@ -0,0 +1,75 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// SypagoApiKeyEnv es la variable de entorno que reemplaza la clave de SyPago del archivo de configuración
+const SypagoApiKeyEnv = "SYPAGO_API_KEY"
+
+const defaultSypagoRequestTimeout = 15 * time.Second
+const defaultSypagoDebitTimeout = 30 * time.Second
+const defaultSypagoBanksCacheTTL = time.Hour
+
+// ResolveApiKey devuelve la clave de SyPago. Se toma, en orden, de la variable de entorno
+// SYPAGO_API_KEY, del archivo ApiKeyFile o del valor ApiKey del archivo de configuración.
+func (c SypagoConfig) ResolveApiKey() (string, error) {
+	if apiKey := strings.TrimSpace(os.Getenv(SypagoApiKeyEnv)); apiKey != "" {
+		return apiKey, nil
+	}
+
+	if c.ApiKeyFile != "" {
+		path := c.ApiKeyFile
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(executableFolder, path)
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("error reading SyPago API key file: %v", err)
+		}
+		return strings.TrimSpace(string(content)), nil
+	}
+
+	if c.ApiKey == "" {
+		return "", fmt.Errorf("SyPago API key is not configured (set %s, ApiKeyFile or ApiKey)", SypagoApiKeyEnv)
+	}
+
+	return c.ApiKey, nil
+}
+
+// RequestTimeout devuelve el timeout de las consultas a SyPago
+func (c SypagoConfig) RequestTimeout() time.Duration {
+	if c.RequestTimeoutSeconds <= 0 {
+		return defaultSypagoRequestTimeout
+	}
+	return time.Duration(c.RequestTimeoutSeconds) * time.Second
+}
+
+// DebitTimeout devuelve el timeout de las operaciones de débito, que suelen tardar más
+func (c SypagoConfig) DebitTimeout() time.Duration {
+	if c.DebitTimeoutSeconds <= 0 {
+		return defaultSypagoDebitTimeout
+	}
+	return time.Duration(c.DebitTimeoutSeconds) * time.Second
+}
+
+// BanksCacheTTL devuelve cuánto tiempo se considera vigente la lista de bancos en cache
+func (c SypagoConfig) BanksCacheTTL() time.Duration {
+	if c.BanksCacheTTLSeconds <= 0 {
+		return defaultSypagoBanksCacheTTL
+	}
+	return time.Duration(c.BanksCacheTTLSeconds) * time.Second
+}
+
+// RejectCodesPath devuelve la ruta absoluta del archivo de códigos de rechazo, o vacío si no se configuró
+func (c SypagoConfig) RejectCodesPath() string {
+	if c.RejectCodesFile == "" || filepath.IsAbs(c.RejectCodesFile) {
+		return c.RejectCodesFile
+	}
+	return filepath.Join(executableFolder, c.RejectCodesFile)
+}
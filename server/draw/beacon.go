@@ -0,0 +1,141 @@
+package draw
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"raffle_web_server/config"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultBeaconUrl es la cadena "quicknet" de drand (League of Entropy), con una ronda cada 3 segundos
+const defaultBeaconUrl = "https://api.drand.sh/52db9ba70e0cc0f6eaf7803dd07447a1f5477735fd3f661792ba94600c84e971"
+const defaultBeaconTimeout = 10 * time.Second
+
+// ErrBeaconPending indica que la ronda del beacon todavía no se publicó
+var ErrBeaconPending = errors.New("beacon round not published yet")
+
+// Beacon es una fuente pública de aleatoriedad: cada ronda se publica a una hora conocida
+// y nadie, tampoco el operador de la rifa, conoce su valor antes de esa hora.
+type Beacon interface {
+	// Source identifica el beacon en la prueba pública del sorteo
+	Source() string
+	// NextRound devuelve la primera ronda que se publica después de t y la hora de su publicación
+	NextRound(ctx context.Context, t time.Time) (uint64, time.Time, error)
+	// Randomness devuelve el valor publicado en la ronda
+	Randomness(ctx context.Context, round uint64) ([]byte, error)
+}
+
+// Drand consulta la API HTTP de una cadena drand
+type Drand struct {
+	client  *http.Client
+	baseUrl string
+}
+
+func NewDrand(client *http.Client, baseUrl string) *Drand {
+	return &Drand{client: client, baseUrl: strings.TrimRight(baseUrl, "/")}
+}
+
+// BeaconFromConfig devuelve el beacon indicado en DrawConfig.BeaconUrl
+func BeaconFromConfig() *Drand {
+	drawConfig := config.GetConfig().DrawConfig
+
+	baseUrl := drawConfig.BeaconUrl
+	if baseUrl == "" {
+		baseUrl = defaultBeaconUrl
+	}
+
+	timeout := defaultBeaconTimeout
+	if drawConfig.BeaconTimeoutSeconds > 0 {
+		timeout = time.Duration(drawConfig.BeaconTimeoutSeconds) * time.Second
+	}
+
+	return NewDrand(&http.Client{Timeout: timeout}, baseUrl)
+}
+
+func (d *Drand) Source() string {
+	return d.baseUrl
+}
+
+// drandInfo son los parámetros de la cadena: la ronda 1 se publica en GenesisTime y luego una cada Period segundos
+type drandInfo struct {
+	Period      int64 `json:"period"`
+	GenesisTime int64 `json:"genesis_time"`
+}
+
+type drandRound struct {
+	Round      uint64 `json:"round"`
+	Randomness string `json:"randomness"`
+}
+
+func (d *Drand) get(ctx context.Context, path string, target any) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, d.baseUrl+path, nil)
+	if err != nil {
+		return fmt.Errorf("error creating drand request: %v", err)
+	}
+
+	response, err := d.client.Do(request)
+	if err != nil {
+		return fmt.Errorf("error calling drand %s: %v", path, err)
+	}
+	defer response.Body.Close()
+
+	// drand responde 404 o 425 (Too Early) a las rondas futuras
+	if response.StatusCode == http.StatusNotFound || response.StatusCode == http.StatusTooEarly {
+		return fmt.Errorf("drand %s: %w", path, ErrBeaconPending)
+	}
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("drand %s returned status code %d", path, response.StatusCode)
+	}
+
+	if err := json.NewDecoder(response.Body).Decode(target); err != nil {
+		return fmt.Errorf("error parsing drand %s response: %v", path, err)
+	}
+
+	return nil
+}
+
+func (d *Drand) NextRound(ctx context.Context, t time.Time) (uint64, time.Time, error) {
+	var info drandInfo
+	if err := d.get(ctx, "/info", &info); err != nil {
+		return 0, time.Time{}, err
+	}
+
+	if info.Period <= 0 {
+		return 0, time.Time{}, fmt.Errorf("invalid drand period %d", info.Period)
+	}
+
+	genesis := time.Unix(info.GenesisTime, 0).UTC()
+	period := time.Duration(info.Period) * time.Second
+
+	// La ronda r se publica en genesis + (r-1)*period; la primera posterior a t es la siguiente a la vigente
+	round := uint64(1)
+	if !t.Before(genesis) {
+		round = uint64(t.Sub(genesis)/period) + 2
+	}
+
+	return round, genesis.Add(time.Duration(round-1) * period), nil
+}
+
+func (d *Drand) Randomness(ctx context.Context, round uint64) ([]byte, error) {
+	var published drandRound
+	if err := d.get(ctx, "/public/"+strconv.FormatUint(round, 10), &published); err != nil {
+		return nil, err
+	}
+
+	if published.Round != round {
+		return nil, fmt.Errorf("drand returned round %d, want %d", published.Round, round)
+	}
+
+	randomness, err := hex.DecodeString(published.Randomness)
+	if err != nil || len(randomness) == 0 {
+		return nil, fmt.Errorf("invalid drand randomness for round %d", round)
+	}
+
+	return randomness, nil
+}
@@ -0,0 +1,47 @@
+package draw
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDrandUsesTheFirstRoundAfterTheClose(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/chain/info":
+			w.Write([]byte(`{"period":3,"genesis_time":1000}`))
+		case "/chain/public/5":
+			w.Write([]byte(`{"round":5,"randomness":"00ff"}`))
+		default:
+			w.WriteHeader(http.StatusTooEarly)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	beacon := NewDrand(server.Client(), server.URL+"/chain/")
+
+	// La ronda 4 se publica en 1009; un cierre en 1009 usa la ronda 5, publicada en 1012
+	round, publishedAt, err := beacon.NextRound(context.Background(), time.Unix(1009, 0))
+	if err != nil {
+		t.Fatalf("NextRound: %v", err)
+	}
+	if round != 5 || !publishedAt.Equal(time.Unix(1012, 0)) {
+		t.Fatalf("next round = %d at %v, want 5 at 1012", round, publishedAt.Unix())
+	}
+
+	randomness, err := beacon.Randomness(context.Background(), 5)
+	if err != nil {
+		t.Fatalf("Randomness: %v", err)
+	}
+	if len(randomness) != 2 || randomness[1] != 0xff {
+		t.Fatalf("randomness = %x, want 00ff", randomness)
+	}
+
+	if _, err := beacon.Randomness(context.Background(), 6); !errors.Is(err, ErrBeaconPending) {
+		t.Fatalf("Randomness of a future round error = %v, want %v", err, ErrBeaconPending)
+	}
+}
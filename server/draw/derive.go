@@ -0,0 +1,165 @@
+package draw
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// Algorithm identifica el procedimiento de derivación de ganadores
+const Algorithm = "sha256-beacon-v2"
+
+// legacyAlgorithm deriva los ganadores solo de la semilla; lo usan los sorteos anteriores a v2
+const legacyAlgorithm = "sha256-counter-v1"
+
+// AlgorithmDescription explica cómo re-derivar los ganadores a partir de la semilla revelada
+const AlgorithmDescription = "Verificar que SHA256(seed) == seedHash y que SHA256(soldTickets) == soldTicketsHash, " +
+	"con soldTickets de menor a mayor en decimal separados por comas; soldTicketsHash se publicó en closedAt, " +
+	"antes de la ronda beaconRound del beacon beaconUrl. Obtener la aleatoriedad de esa ronda en beaconUrl + \"/public/\" + beaconRound " +
+	"y comprobar que coincide con beaconRandomness. Calcular drawSeed = SHA256(seed || beaconRandomness || soldTicketsHash), " +
+	"con seed y beaconRandomness como bytes y soldTicketsHash como texto hex. " + pickDescription
+
+// legacyDescription explica el procedimiento de los sorteos hechos con legacyAlgorithm
+const legacyDescription = "Verificar que SHA256(seed) == seedHash y usar drawSeed = seed. " + pickDescription
+
+const pickDescription = "Ordenar soldTickets de menor a mayor. " +
+	"Para cada elección k (0..mainCount+blessCount-1) y cada intento a (0, 1, ...) calcular " +
+	"d = SHA256(drawSeed || raffleId || \":\" || k || \":\" || a), tomar v = los primeros 8 bytes de d como uint64 big-endian " +
+	"y aceptar el intento si v < floor(2^64 / n) * n, donde n es la cantidad de tickets restantes. " +
+	"El ganador es restantes[v mod n], que se quita de la lista conservando el orden. " +
+	"Las primeras mainCount elecciones son los ganadores principales y las siguientes blessCount los números bendecidos."
+
+// Describe devuelve la descripción del procedimiento con el que se hizo un sorteo
+func Describe(algorithm string) string {
+	if algorithm == legacyAlgorithm {
+		return legacyDescription
+	}
+	return AlgorithmDescription
+}
+
+// HashSeed devuelve el compromiso (hash SHA-256 en hex) de una semilla
+func HashSeed(seed []byte) string {
+	sum := sha256.Sum256(seed)
+	return hex.EncodeToString(sum[:])
+}
+
+// HashTickets devuelve el hash SHA-256 en hex de la lista de tickets separada por comas
+func HashTickets(tickets []int) string {
+	hash := sha256.New()
+	for i, ticket := range tickets {
+		if i > 0 {
+			hash.Write([]byte(","))
+		}
+		hash.Write([]byte(strconv.Itoa(ticket)))
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// DrawSeed mezcla la semilla comprometida con la aleatoriedad del beacon y los vendidos fijados
+// al cierre: el operador conoce la semilla, pero no el beacon cuando se fijan los vendidos.
+func DrawSeed(seed, beaconRandomness []byte, soldTicketsHash string) []byte {
+	hash := sha256.New()
+	hash.Write(seed)
+	hash.Write(beaconRandomness)
+	hash.Write([]byte(soldTicketsHash))
+	return hash.Sum(nil)
+}
+
+// pickIndex elige de forma determinística un índice en [0, n) para la elección k
+func pickIndex(seed []byte, raffleId string, k, n int) int {
+	limit := math.MaxUint64 - (math.MaxUint64%uint64(n)+1)%uint64(n)
+
+	for attempt := 0; ; attempt++ {
+		hash := sha256.New()
+		hash.Write(seed)
+		hash.Write([]byte(raffleId))
+		hash.Write([]byte(":" + strconv.Itoa(k) + ":" + strconv.Itoa(attempt)))
+		value := binary.BigEndian.Uint64(hash.Sum(nil)[:8])
+
+		if value <= limit {
+			return int(value % uint64(n))
+		}
+	}
+}
+
+// DeriveWinners calcula los ganadores principales y los números bendecidos a partir
+// de la semilla del sorteo (ver DrawSeed) y de los tickets vendidos. El resultado es
+// determinístico: cualquiera con la prueba publicada obtiene los mismos ganadores.
+func DeriveWinners(seed []byte, raffleId string, soldTickets []int, mainCount, blessCount int) ([]int, []int) {
+	remaining := append([]int(nil), soldTickets...)
+	sort.Ints(remaining)
+
+	mainWinners := make([]int, 0, mainCount)
+	blessWinners := make([]int, 0, blessCount)
+
+	for k := 0; k < mainCount+blessCount && len(remaining) > 0; k++ {
+		index := pickIndex(seed, raffleId, k, len(remaining))
+		winner := remaining[index]
+		remaining = append(remaining[:index], remaining[index+1:]...)
+
+		if k < mainCount {
+			mainWinners = append(mainWinners, winner)
+		} else {
+			blessWinners = append(blessWinners, winner)
+		}
+	}
+
+	return mainWinners, blessWinners
+}
+
+// VerifyProof comprueba que la semilla revelada corresponde al compromiso publicado, que la
+// lista de vendidos es la fijada al cierre y que los ganadores se derivan de ellas y del beacon.
+// No consulta el beacon: quien verifica compara beaconRandomness con la ronda publicada en beaconUrl.
+func VerifyProof(proof Proof) error {
+	if proof.Seed == "" {
+		return fmt.Errorf("seed has not been revealed yet")
+	}
+
+	seed, err := hex.DecodeString(proof.Seed)
+	if err != nil {
+		return fmt.Errorf("invalid seed encoding: %v", err)
+	}
+
+	if HashSeed(seed) != proof.SeedHash {
+		return fmt.Errorf("seed does not match the published seed hash")
+	}
+
+	drawSeed := seed
+
+	if proof.Algorithm != legacyAlgorithm {
+		if HashTickets(proof.SoldTickets) != proof.SoldTicketsHash {
+			return fmt.Errorf("sold tickets do not match the published sold tickets hash")
+		}
+
+		randomness, err := hex.DecodeString(proof.BeaconRandomness)
+		if err != nil || len(randomness) == 0 {
+			return fmt.Errorf("invalid beacon randomness")
+		}
+
+		drawSeed = DrawSeed(seed, randomness, proof.SoldTicketsHash)
+	}
+
+	mainWinners, blessWinners := DeriveWinners(drawSeed, proof.RaffleId, proof.SoldTickets, proof.MainCount, proof.BlessCount)
+
+	if !equalTickets(mainWinners, proof.MainWinners) || !equalTickets(blessWinners, proof.BlessWinners) {
+		return fmt.Errorf("winners do not match the ones derived from the seed")
+	}
+
+	return nil
+}
+
+func equalTickets(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
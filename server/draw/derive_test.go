@@ -0,0 +1,161 @@
+package draw
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"math/big"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+// describedWinners sigue paso a paso AlgorithmDescription, sin reutilizar el código del paquete
+func describedWinners(seed, beaconRandomness []byte, raffleId string, soldTickets []int, mainCount, blessCount int) ([]int, []int) {
+	parts := make([]byte, 0)
+	for i, ticket := range soldTickets {
+		if i > 0 {
+			parts = append(parts, ',')
+		}
+		parts = append(parts, strconv.Itoa(ticket)...)
+	}
+	ticketsSum := sha256.Sum256(parts)
+	soldTicketsHash := hex.EncodeToString(ticketsSum[:])
+
+	drawSeed := sha256.Sum256(append(append(append([]byte{}, seed...), beaconRandomness...), soldTicketsHash...))
+
+	remaining := append([]int(nil), soldTickets...)
+	sort.Ints(remaining)
+
+	space := new(big.Int).Lsh(big.NewInt(1), 64)
+	picks := make([]int, 0)
+	for k := 0; k < mainCount+blessCount && len(remaining) > 0; k++ {
+		n := big.NewInt(int64(len(remaining)))
+		limit := new(big.Int).Mul(new(big.Int).Div(space, n), n)
+
+		for a := 0; ; a++ {
+			input := append([]byte{}, drawSeed[:]...)
+			input = append(input, raffleId+":"+strconv.Itoa(k)+":"+strconv.Itoa(a)...)
+			d := sha256.Sum256(input)
+			v := new(big.Int).SetBytes(d[:8])
+
+			if v.Cmp(limit) < 0 {
+				index := int(new(big.Int).Mod(v, n).Int64())
+				picks = append(picks, remaining[index])
+				remaining = append(remaining[:index], remaining[index+1:]...)
+				break
+			}
+		}
+	}
+
+	if len(picks) < mainCount {
+		return picks, []int{}
+	}
+	return picks[:mainCount], picks[mainCount:]
+}
+
+func testProof(seed, randomness []byte, soldTickets []int, mainCount, blessCount int) Proof {
+	soldTicketsHash := HashTickets(soldTickets)
+	mainWinners, blessWinners := DeriveWinners(DrawSeed(seed, randomness, soldTicketsHash), "raffle-test", soldTickets, mainCount, blessCount)
+
+	return Proof{
+		RaffleId:         "raffle-test",
+		Algorithm:        Algorithm,
+		SeedHash:         HashSeed(seed),
+		MainCount:        mainCount,
+		BlessCount:       blessCount,
+		SoldTicketsHash:  soldTicketsHash,
+		Seed:             hex.EncodeToString(seed),
+		BeaconRandomness: hex.EncodeToString(randomness),
+		SoldTickets:      soldTickets,
+		MainWinners:      mainWinners,
+		BlessWinners:     blessWinners,
+	}
+}
+
+func TestDeriveWinnersFollowsTheDescription(t *testing.T) {
+	cases := []struct {
+		name        string
+		soldTickets []int
+		mainCount   int
+		blessCount  int
+	}{
+		{"single ticket", []int{42}, 1, 5},
+		{"a few tickets", []int{3, 7, 11, 19, 23, 31, 47}, 2, 3},
+		{"full raffle", rangeOf(1000, 1999), 1, 5},
+		{"no sales", []int{}, 1, 5},
+	}
+
+	for i, tc := range cases {
+		seed := sha256.Sum256([]byte("seed-" + strconv.Itoa(i)))
+		randomness := sha256.Sum256([]byte("beacon-" + strconv.Itoa(i)))
+
+		proof := testProof(seed[:], randomness[:], tc.soldTickets, tc.mainCount, tc.blessCount)
+		wantMain, wantBless := describedWinners(seed[:], randomness[:], "raffle-test", tc.soldTickets, tc.mainCount, tc.blessCount)
+
+		if !equalTickets(proof.MainWinners, wantMain) || !equalTickets(proof.BlessWinners, wantBless) {
+			t.Errorf("%s: winners = %v %v, the description gives %v %v", tc.name, proof.MainWinners, proof.BlessWinners, wantMain, wantBless)
+		}
+		if err := VerifyProof(proof); err != nil {
+			t.Errorf("%s: VerifyProof: %v", tc.name, err)
+		}
+	}
+}
+
+func TestVerifyProofRejectsTamperedProofs(t *testing.T) {
+	seed := sha256.Sum256([]byte("seed"))
+	randomness := sha256.Sum256([]byte("beacon"))
+	soldTickets := rangeOf(1, 50)
+
+	cases := []struct {
+		name   string
+		tamper func(proof *Proof)
+	}{
+		{"other seed", func(proof *Proof) { proof.Seed = hex.EncodeToString(randomness[:]) }},
+		{"other beacon round", func(proof *Proof) { proof.BeaconRandomness = hex.EncodeToString(seed[:]) }},
+		{"missing beacon", func(proof *Proof) { proof.BeaconRandomness = "" }},
+		{"ticket sold after close", func(proof *Proof) { proof.SoldTickets = append(proof.SoldTickets, 51) }},
+		{"other winner", func(proof *Proof) { proof.MainWinners = []int{proof.BlessWinners[0]} }},
+		{"reordered bless winners", func(proof *Proof) {
+			proof.BlessWinners[0], proof.BlessWinners[1] = proof.BlessWinners[1], proof.BlessWinners[0]
+		}},
+	}
+
+	for _, tc := range cases {
+		proof := testProof(seed[:], randomness[:], append([]int(nil), soldTickets...), 1, 5)
+		tc.tamper(&proof)
+
+		if err := VerifyProof(proof); err == nil {
+			t.Errorf("%s: VerifyProof accepted the tampered proof", tc.name)
+		}
+	}
+}
+
+func TestVerifyProofAcceptsLegacyDraws(t *testing.T) {
+	seed := sha256.Sum256([]byte("legacy"))
+	soldTickets := rangeOf(1, 20)
+	mainWinners, blessWinners := DeriveWinners(seed[:], "raffle-test", soldTickets, 1, 2)
+
+	proof := Proof{
+		RaffleId:     "raffle-test",
+		Algorithm:    legacyAlgorithm,
+		SeedHash:     HashSeed(seed[:]),
+		MainCount:    1,
+		BlessCount:   2,
+		Seed:         hex.EncodeToString(seed[:]),
+		SoldTickets:  soldTickets,
+		MainWinners:  mainWinners,
+		BlessWinners: blessWinners,
+	}
+
+	if err := VerifyProof(proof); err != nil {
+		t.Fatalf("VerifyProof: %v", err)
+	}
+}
+
+func rangeOf(first, last int) []int {
+	tickets := make([]int, 0, last-first+1)
+	for number := first; number <= last; number++ {
+		tickets = append(tickets, number)
+	}
+	return tickets
+}
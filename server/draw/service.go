@@ -0,0 +1,437 @@
+package draw
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"raffle_web_server/config"
+	"raffle_web_server/reservation"
+	"raffle_web_server/store"
+	"sync"
+	"time"
+)
+
+const defaultMainWinners = 1
+const defaultBlessWinners = 5
+const defaultCheckInterval = time.Minute
+
+// Proof es la información pública del sorteo. Antes del sorteo solo incluye el compromiso;
+// al cierre de las ventas, el hash de los vendidos y la ronda del beacon.
+type Proof struct {
+	RaffleId         string `json:"raffleId"`
+	Status           string `json:"status"` // committed, closed, drawn
+	Algorithm        string `json:"algorithm"`
+	Description      string `json:"description"`
+	SeedHash         string `json:"seedHash"`
+	CommittedAt      string `json:"committedAt"`
+	MainCount        int    `json:"mainCount"`
+	BlessCount       int    `json:"blessCount"`
+	ClosedAt         string `json:"closedAt,omitempty"`
+	SoldTicketsHash  string `json:"soldTicketsHash,omitempty"`
+	BeaconUrl        string `json:"beaconUrl,omitempty"`
+	BeaconRound      uint64 `json:"beaconRound,omitempty"`
+	Seed             string `json:"seed,omitempty"`
+	BeaconRandomness string `json:"beaconRandomness,omitempty"`
+	DrawnAt          string `json:"drawnAt,omitempty"`
+	SoldTickets      []int  `json:"soldTickets,omitempty"`
+	MainWinners      []int  `json:"mainWinners,omitempty"`
+	BlessWinners     []int  `json:"blessWinners,omitempty"`
+}
+
+// Service publica el compromiso de cada rifa y ejecuta el sorteo una sola vez
+// cuando la rifa termina, persistiendo los ganadores. La semilla se guarda cifrada
+// hasta el sorteo y se mezcla con la ronda del beacon posterior al cierre de las ventas.
+type Service struct {
+	repository store.RaffleRepository
+	secrets    *store.SecretBox
+	beacon     Beacon
+	mu         sync.Mutex
+}
+
+func NewService(repository store.RaffleRepository, secrets *store.SecretBox, beacon Beacon) *Service {
+	return &Service{repository: repository, secrets: secrets, beacon: beacon}
+}
+
+// winnersCount devuelve la cantidad de ganadores principales y bendecidos.
+// Si la rifa tiene catálogo de premios se usa un ganador por premio; si no, la configuración.
+func (s *Service) winnersCount(raffleId string) (int, int, error) {
+	prizes, err := s.repository.ListPrizes(raffleId)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if len(prizes) > 0 {
+		mainCount, blessCount := 0, 0
+		for _, prize := range prizes {
+			if prize.IsMainTrack() {
+				mainCount++
+			} else {
+				blessCount++
+			}
+		}
+		return mainCount, blessCount, nil
+	}
+
+	mainCount, blessCount := configuredWinnersCount()
+	return mainCount, blessCount, nil
+}
+
+func configuredWinnersCount() (int, int) {
+	drawConfig := config.GetConfig().DrawConfig
+
+	mainCount := drawConfig.MainWinners
+	if mainCount <= 0 {
+		mainCount = defaultMainWinners
+	}
+
+	blessCount := drawConfig.BlessWinners
+	if blessCount < 0 {
+		blessCount = defaultBlessWinners
+	}
+
+	return mainCount, blessCount
+}
+
+func checkInterval() time.Duration {
+	seconds := config.GetConfig().DrawConfig.CheckIntervalSeconds
+	if seconds <= 0 {
+		return defaultCheckInterval
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// Commit genera la semilla secreta de la rifa, la guarda cifrada y publica su hash. Si ya existe
+// un compromiso lo devuelve sin modificarlo.
+func (s *Service) Commit(raffleId string) (*store.Draw, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.commit(raffleId)
+}
+
+func (s *Service) commit(raffleId string) (*store.Draw, error) {
+	existing, err := s.repository.GetDraw(raffleId)
+	if err == nil {
+		return existing, nil
+	}
+	if !errors.Is(err, store.ErrNotFound) {
+		return nil, err
+	}
+
+	seed := make([]byte, 32)
+	if _, err := rand.Read(seed); err != nil {
+		return nil, fmt.Errorf("error generating draw seed: %v", err)
+	}
+
+	mainCount, blessCount, err := s.winnersCount(raffleId)
+	if err != nil {
+		return nil, err
+	}
+
+	sealedSeed, err := s.secrets.Seal(hex.EncodeToString(seed))
+	if err != nil {
+		return nil, err
+	}
+
+	draw := store.Draw{
+		RaffleId:    raffleId,
+		Algorithm:   Algorithm,
+		SeedHash:    HashSeed(seed),
+		SealedSeed:  sealedSeed,
+		CommittedAt: time.Now().UTC(),
+		MainCount:   mainCount,
+		BlessCount:  blessCount,
+	}
+
+	if err := s.repository.SaveDraw(draw); err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("Draw committed for raffle %s with seed hash %s\n", raffleId, draw.SeedHash)
+
+	return &draw, nil
+}
+
+// IsDue indica si la rifa ya terminó y pasó el tiempo de retención de los pagos pendientes.
+// Una reserva hecha justo antes del cierre puede enviar el débito al final de su retención
+// y esperar la confirmación durante la retención de pago.
+func IsDue(raffle store.Raffle, now time.Time) bool {
+	return !now.Before(raffle.EndsAt.Add(reservation.HoldTTL() + reservation.PaymentHoldTTL()))
+}
+
+// Execute realiza el sorteo de la rifa con los tickets vendidos. Si ya se realizó
+// devuelve el resultado persistido sin volver a sortear. La primera llamada fija los
+// vendidos y espera la ronda del beacon; si la ronda aún no está disponible devuelve
+// ErrBeaconPending y el sorteo se completa en una llamada posterior con los mismos vendidos.
+func (s *Service) Execute(ctx context.Context, raffle store.Raffle) (*store.Draw, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	draw, err := s.commit(raffle.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if draw.IsDrawn() {
+		return draw, nil
+	}
+
+	if !draw.IsClosed() {
+		publishedAt, err := s.close(ctx, draw)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := waitUntil(ctx, publishedAt); err != nil {
+			return nil, err
+		}
+	}
+
+	seed, err := s.seed(draw)
+	if err != nil {
+		return nil, err
+	}
+
+	randomness, err := s.beacon.Randomness(ctx, draw.BeaconRound)
+	if err != nil {
+		return nil, fmt.Errorf("error reading beacon round %d for raffle %s: %w", draw.BeaconRound, raffle.ID, err)
+	}
+
+	drawSeed := DrawSeed(seed, randomness, draw.SoldTicketsHash)
+	draw.MainWinners, draw.BlessWinners = DeriveWinners(drawSeed, raffle.ID, draw.SoldTickets, draw.MainCount, draw.BlessCount)
+
+	// Con el sorteo hecho la semilla se revela junto con la aleatoriedad del beacon
+	draw.Seed = hex.EncodeToString(seed)
+	draw.SealedSeed = ""
+	draw.BeaconRandomness = hex.EncodeToString(randomness)
+
+	drawnAt := time.Now().UTC()
+	draw.DrawnAt = &drawnAt
+
+	if err := s.repository.SaveDraw(*draw); err != nil {
+		return nil, err
+	}
+
+	if err := s.markDrawn(raffle.ID, drawnAt); err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("Draw executed for raffle %s: main winners %v, bless winners %v\n",
+		raffle.ID, draw.MainWinners, draw.BlessWinners)
+
+	return draw, nil
+}
+
+// close fija los tickets vendidos que entran en el sorteo y la ronda del beacon que se
+// publica después. Devuelve la hora de publicación de esa ronda.
+func (s *Service) close(ctx context.Context, draw *store.Draw) (time.Time, error) {
+	tickets, err := s.repository.ListTickets(draw.RaffleId)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	soldTickets := make([]int, 0, len(tickets))
+	for _, ticket := range tickets {
+		if ticket.Status == store.TicketSold {
+			soldTickets = append(soldTickets, ticket.Number)
+		}
+	}
+
+	closedAt := time.Now().UTC()
+
+	round, publishedAt, err := s.beacon.NextRound(ctx, closedAt)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	// Los sorteos comprometidos con el algoritmo anterior también se cierran con el beacon
+	draw.Algorithm = Algorithm
+	draw.ClosedAt = &closedAt
+	draw.SoldTickets = soldTickets
+	draw.SoldTicketsHash = HashTickets(soldTickets)
+	draw.BeaconUrl = s.beacon.Source()
+	draw.BeaconRound = round
+
+	if err := s.repository.SaveDraw(*draw); err != nil {
+		return time.Time{}, err
+	}
+
+	fmt.Printf("Draw closed for raffle %s with %d sold tickets (hash %s), beacon round %d\n",
+		draw.RaffleId, len(soldTickets), draw.SoldTicketsHash, round)
+
+	return publishedAt, nil
+}
+
+// seed devuelve la semilla comprometida. Los sorteos anteriores al cifrado la guardaban en Seed.
+func (s *Service) seed(draw *store.Draw) ([]byte, error) {
+	encoded := draw.Seed
+	if draw.SealedSeed != "" {
+		opened, err := s.secrets.Open(draw.SealedSeed)
+		if err != nil {
+			return nil, fmt.Errorf("error opening the seed of raffle %s: %v", draw.RaffleId, err)
+		}
+		encoded = opened
+	}
+
+	seed, err := hex.DecodeString(encoded)
+	if err != nil || len(seed) == 0 {
+		return nil, fmt.Errorf("invalid stored seed for raffle %s", draw.RaffleId)
+	}
+
+	return seed, nil
+}
+
+// waitUntil espera hasta la hora indicada o hasta que se cancele el contexto
+func waitUntil(ctx context.Context, at time.Time) error {
+	wait := time.Until(at)
+	if wait <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// markDrawn deja la rifa en estado sorteada
+func (s *Service) markDrawn(raffleId string, drawnAt time.Time) error {
+	raffle, err := s.repository.GetRaffle(raffleId)
+	if err != nil {
+		return err
+	}
+
+	raffle.Status = store.RaffleDrawn
+	raffle.UpdatedAt = drawnAt
+	return s.repository.SaveRaffle(*raffle)
+}
+
+// GetDraw devuelve el sorteo persistido de una rifa
+func (s *Service) GetDraw(raffleId string) (*store.Draw, error) {
+	return s.repository.GetDraw(raffleId)
+}
+
+// BuildProof construye la información pública del sorteo. El hash de los vendidos y la
+// ronda del beacon se publican al cierre; la semilla, la aleatoriedad del beacon, la
+// lista de vendidos y los ganadores solo cuando el sorteo ya se realizó.
+func BuildProof(draw *store.Draw) Proof {
+	proof := Proof{
+		RaffleId:    draw.RaffleId,
+		Status:      "committed",
+		Algorithm:   draw.Algorithm,
+		Description: Describe(draw.Algorithm),
+		SeedHash:    draw.SeedHash,
+		CommittedAt: draw.CommittedAt.Format(time.RFC3339),
+		MainCount:   draw.MainCount,
+		BlessCount:  draw.BlessCount,
+	}
+
+	if draw.IsClosed() {
+		proof.Status = "closed"
+		proof.ClosedAt = draw.ClosedAt.Format(time.RFC3339)
+		proof.SoldTicketsHash = draw.SoldTicketsHash
+		proof.BeaconUrl = draw.BeaconUrl
+		proof.BeaconRound = draw.BeaconRound
+	}
+
+	if !draw.IsDrawn() {
+		return proof
+	}
+
+	proof.Status = "drawn"
+	proof.Seed = draw.Seed
+	proof.BeaconRandomness = draw.BeaconRandomness
+	proof.DrawnAt = draw.DrawnAt.Format(time.RFC3339)
+	proof.SoldTickets = append([]int{}, draw.SoldTickets...)
+	proof.SoldTicketsHash = HashTickets(draw.SoldTickets)
+	proof.MainWinners = append([]int{}, draw.MainWinners...)
+	proof.BlessWinners = append([]int{}, draw.BlessWinners...)
+
+	return proof
+}
+
+// ResolvePrize devuelve el premio del catálogo que ganó el ticket en el sorteo.
+// El k-ésimo ganador principal recibe el k-ésimo premio main/secondary y el
+// k-ésimo número bendecido el k-ésimo premio bless. Devuelve nil si el ticket no ganó.
+func ResolvePrize(draw *store.Draw, prizes []store.Prize, ticket int) *store.Prize {
+	if draw == nil || !draw.IsDrawn() {
+		return nil
+	}
+
+	mainTrack := make([]store.Prize, 0)
+	blessTrack := make([]store.Prize, 0)
+	for _, prize := range prizes {
+		if prize.IsMainTrack() {
+			mainTrack = append(mainTrack, prize)
+		} else {
+			blessTrack = append(blessTrack, prize)
+		}
+	}
+
+	for i, winner := range draw.MainWinners {
+		if winner == ticket && i < len(mainTrack) {
+			return &mainTrack[i]
+		}
+	}
+
+	for i, winner := range draw.BlessWinners {
+		if winner == ticket && i < len(blessTrack) {
+			return &blessTrack[i]
+		}
+	}
+
+	return nil
+}
+
+// processRaffles publica los compromisos pendientes y sortea las rifas publicadas o cerradas que vencieron
+func (s *Service) processRaffles(ctx context.Context) {
+	raffles, err := s.repository.ListRaffles()
+	if err != nil {
+		fmt.Printf("Error listing raffles for draw: %v\n", err)
+		return
+	}
+
+	now := time.Now().UTC()
+
+	for _, raffle := range raffles {
+		if raffle.Status != store.RafflePublished && raffle.Status != store.RaffleClosed {
+			continue
+		}
+
+		if !IsDue(raffle, now) {
+			if _, err := s.Commit(raffle.ID); err != nil {
+				fmt.Printf("Error committing draw for raffle %s: %v\n", raffle.ID, err)
+			}
+			continue
+		}
+
+		if _, err := s.Execute(ctx, raffle); err != nil {
+			fmt.Printf("Error executing draw for raffle %s: %v\n", raffle.ID, err)
+		}
+	}
+}
+
+// Run revisa periódicamente las rifas hasta que se cancele el contexto
+func (s *Service) Run(ctx context.Context) {
+	s.processRaffles(ctx)
+
+	ticker := time.NewTicker(checkInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.processRaffles(ctx)
+			ticker.Reset(checkInterval())
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package draw
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"raffle_web_server/store"
+	"raffle_web_server/store/storetest"
+	"testing"
+	"time"
+)
+
+// stubBeacon publica la ronda 7 cuando published es true
+type stubBeacon struct {
+	published bool
+}
+
+func (b *stubBeacon) Source() string {
+	return "https://beacon.test"
+}
+
+func (b *stubBeacon) NextRound(ctx context.Context, t time.Time) (uint64, time.Time, error) {
+	return 7, t, nil
+}
+
+func (b *stubBeacon) Randomness(ctx context.Context, round uint64) ([]byte, error) {
+	if !b.published {
+		return nil, fmt.Errorf("round %d: %w", round, ErrBeaconPending)
+	}
+	sum := sha256.Sum256([]byte(fmt.Sprintf("round-%d", round)))
+	return sum[:], nil
+}
+
+func newTestService(t *testing.T, beacon Beacon) (*Service, *store.FileRepository, string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "store.json")
+	repository := storetest.OpenRepository(t, path)
+	secrets := storetest.NewSecretBox(t)
+
+	now := time.Now().UTC()
+	raffle := store.Raffle{
+		ID:            "raffle-test",
+		Status:        store.RaffleClosed,
+		InitialTicket: 1,
+		TicketsTotal:  100,
+		EndsAt:        now.Add(-time.Hour),
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if err := repository.SaveRaffle(raffle); err != nil {
+		t.Fatalf("SaveRaffle: %v", err)
+	}
+
+	return NewService(repository, secrets, beacon), repository, path
+}
+
+func sell(t *testing.T, repository *store.FileRepository, numbers ...int) {
+	t.Helper()
+
+	if _, err := repository.SellTickets("raffle-test", "BK-test", numbers); err != nil {
+		t.Fatalf("SellTickets: %v", err)
+	}
+}
+
+func TestExecutePublishesAVerifiableProof(t *testing.T) {
+	service, repository, path := newTestService(t, &stubBeacon{published: true})
+
+	committed, err := service.Commit("raffle-test")
+	if err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	if committed.Seed != "" || committed.SealedSeed == "" {
+		t.Fatalf("committed draw has seed %q and sealed seed %q, want only the sealed seed", committed.Seed, committed.SealedSeed)
+	}
+	if proof := BuildProof(committed); proof.Seed != "" || proof.Status != "committed" {
+		t.Fatalf("proof before the draw = %s with seed %q, want committed without seed", proof.Status, proof.Seed)
+	}
+
+	stored, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+
+	sell(t, repository, 4, 8, 15, 16, 23, 42)
+
+	drawn, err := service.Execute(context.Background(), store.Raffle{ID: "raffle-test"})
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+
+	// La semilla revelada no estaba en claro en el archivo antes del sorteo
+	if bytes.Contains(stored, []byte(drawn.Seed)) {
+		t.Fatal("the seed was stored in plaintext before the draw")
+	}
+
+	proof := BuildProof(drawn)
+	if proof.Status != "drawn" || proof.BeaconRound != 7 || proof.BeaconUrl != "https://beacon.test" {
+		t.Fatalf("proof = %s with beacon %s round %d, want drawn with the stub beacon round 7", proof.Status, proof.BeaconUrl, proof.BeaconRound)
+	}
+	if err := VerifyProof(proof); err != nil {
+		t.Fatalf("VerifyProof: %v", err)
+	}
+
+	raffle, err := repository.GetRaffle("raffle-test")
+	if err != nil {
+		t.Fatalf("GetRaffle: %v", err)
+	}
+	if raffle.Status != store.RaffleDrawn {
+		t.Fatalf("raffle status = %s, want %s", raffle.Status, store.RaffleDrawn)
+	}
+
+	// Un segundo Execute devuelve el mismo resultado
+	again, err := service.Execute(context.Background(), store.Raffle{ID: "raffle-test"})
+	if err != nil {
+		t.Fatalf("second Execute: %v", err)
+	}
+	if !equalTickets(again.MainWinners, drawn.MainWinners) || !equalTickets(again.BlessWinners, drawn.BlessWinners) {
+		t.Fatalf("second Execute winners = %v %v, want %v %v", again.MainWinners, again.BlessWinners, drawn.MainWinners, drawn.BlessWinners)
+	}
+}
+
+func TestSoldTicketsAreFixedAtClose(t *testing.T) {
+	beacon := &stubBeacon{}
+	service, repository, _ := newTestService(t, beacon)
+
+	sell(t, repository, 1, 2, 3)
+
+	// Al cerrar se fija la lista de vendidos, aunque la ronda del beacon todavía no exista
+	if _, err := service.Execute(context.Background(), store.Raffle{ID: "raffle-test"}); !errors.Is(err, ErrBeaconPending) {
+		t.Fatalf("Execute before the beacon round error = %v, want %v", err, ErrBeaconPending)
+	}
+
+	closed, err := service.GetDraw("raffle-test")
+	if err != nil {
+		t.Fatalf("GetDraw: %v", err)
+	}
+	proof := BuildProof(closed)
+	if proof.Status != "closed" || proof.SoldTicketsHash != HashTickets([]int{1, 2, 3}) || proof.Seed != "" {
+		t.Fatalf("proof = %s with sold hash %s and seed %q, want closed with the hash of [1 2 3] and no seed",
+			proof.Status, proof.SoldTicketsHash, proof.Seed)
+	}
+
+	// Una venta posterior al cierre no entra en el sorteo
+	sell(t, repository, 4)
+	beacon.published = true
+
+	drawn, err := service.Execute(context.Background(), store.Raffle{ID: "raffle-test"})
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if !equalTickets(drawn.SoldTickets, []int{1, 2, 3}) {
+		t.Fatalf("drawn sold tickets = %v, want [1 2 3]", drawn.SoldTickets)
+	}
+	if err := VerifyProof(BuildProof(drawn)); err != nil {
+		t.Fatalf("VerifyProof: %v", err)
+	}
+}
@@ -0,0 +1,86 @@
+package exchange
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"raffle_web_server/config"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// Provider informa la tasa de cambio del día. Se puede reemplazar por un proveedor remoto
+// (por ejemplo la tasa oficial del BCV); el archivo y la configuración sirven como tasa local.
+type Provider interface {
+	// Name identifica al proveedor en el historial de tasas
+	Name() string
+	// Rate devuelve cuántas unidades de to equivalen a una unidad de from
+	Rate(ctx context.Context, from, to string) (decimal.Decimal, error)
+}
+
+// lookupRate busca la tasa con la clave "FROM_TO". Si solo existe la inversa se usa su recíproco.
+func lookupRate(rates map[string]float64, from, to string) (decimal.Decimal, error) {
+	if rate, exists := rates[strings.ToUpper(from+"_"+to)]; exists && rate > 0 {
+		return decimal.NewFromFloat(rate), nil
+	}
+
+	if rate, exists := rates[strings.ToUpper(to+"_"+from)]; exists && rate > 0 {
+		return decimal.NewFromInt(1).Div(decimal.NewFromFloat(rate)), nil
+	}
+
+	return decimal.Zero, fmt.Errorf("%w: %s to %s", ErrNoRate, from, to)
+}
+
+// ConfigProvider toma las tasas de ExchangeRateConfig.Rates
+type ConfigProvider struct{}
+
+func (ConfigProvider) Name() string {
+	return "config"
+}
+
+func (ConfigProvider) Rate(ctx context.Context, from, to string) (decimal.Decimal, error) {
+	return lookupRate(config.GetConfig().ExchangeRateConfig.Rates, from, to)
+}
+
+// FileProvider lee las tasas de un archivo JSON con el formato {"USD_VES": 36.5}.
+// El archivo se lee en cada consulta para que se pueda actualizar sin reiniciar.
+type FileProvider struct {
+	Path string
+}
+
+func (p FileProvider) Name() string {
+	return "file"
+}
+
+func (p FileProvider) Rate(ctx context.Context, from, to string) (decimal.Decimal, error) {
+	content, err := os.ReadFile(p.Path)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("error reading exchange rates file: %v", err)
+	}
+
+	var rates map[string]float64
+	if err := json.Unmarshal(content, &rates); err != nil {
+		return decimal.Zero, fmt.Errorf("error parsing exchange rates file %s: %v", p.Path, err)
+	}
+
+	return lookupRate(rates, from, to)
+}
+
+// ProviderFromConfig devuelve el proveedor indicado en ExchangeRateConfig.Provider
+func ProviderFromConfig() (Provider, error) {
+	rateConfig := config.GetConfig().ExchangeRateConfig
+
+	switch rateConfig.Provider {
+	case "", "config":
+		return ConfigProvider{}, nil
+	case "file":
+		if rateConfig.RatesFile == "" {
+			return nil, fmt.Errorf("exchange rate provider \"file\" requires RatesFile")
+		}
+		return FileProvider{Path: rateConfig.RatesPath()}, nil
+	default:
+		return nil, fmt.Errorf("unknown exchange rate provider %q", rateConfig.Provider)
+	}
+}
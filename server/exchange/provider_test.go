@@ -0,0 +1,36 @@
+package exchange
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestLookupRate(t *testing.T) {
+	rates := map[string]float64{"USD_VES": 40, "EUR_USD": 0, "COP_VES": -1}
+
+	direct, err := lookupRate(rates, "usd", "ves")
+	if err != nil {
+		t.Fatalf("lookupRate(USD, VES): %v", err)
+	}
+	if !direct.Equal(decimal.NewFromInt(40)) {
+		t.Fatalf("USD to VES = %s, want 40", direct)
+	}
+
+	// Solo existe USD_VES: VES a USD usa su recíproco
+	inverse, err := lookupRate(rates, "VES", "USD")
+	if err != nil {
+		t.Fatalf("lookupRate(VES, USD): %v", err)
+	}
+	if !inverse.Equal(decimal.NewFromInt(1).Div(decimal.NewFromInt(40))) {
+		t.Fatalf("VES to USD = %s, want 1/40", inverse)
+	}
+
+	// Las tasas que no son positivas no se usan, tampoco su inversa
+	for _, pair := range [][2]string{{"EUR", "USD"}, {"USD", "EUR"}, {"VES", "COP"}, {"USD", "COP"}} {
+		if _, err := lookupRate(rates, pair[0], pair[1]); !errors.Is(err, ErrNoRate) {
+			t.Errorf("lookupRate(%s, %s) error = %v, want %v", pair[0], pair[1], err, ErrNoRate)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package exchange
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"raffle_web_server/store"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// AdminSource es el origen de las tasas cargadas desde la API de administración
+const AdminSource = "admin"
+
+const providerTimeout = 10 * time.Second
+
+// ErrNoRate se devuelve cuando no hay tasa de cambio para el par de monedas
+var ErrNoRate = errors.New("exchange rate not available")
+
+// ErrInvalidRate se devuelve cuando la tasa cargada no es válida
+var ErrInvalidRate = errors.New("invalid exchange rate")
+
+// venezuelaTime es la zona horaria con la que se fechan las tasas diarias
+var venezuelaTime = time.FixedZone("VET", -4*60*60)
+
+// Today devuelve la fecha (YYYY-MM-DD) de la tasa vigente
+func Today(now time.Time) string {
+	return now.In(venezuelaTime).Format(time.DateOnly)
+}
+
+// Service guarda una tasa por día y par de monedas. La primera consulta del día la pide
+// al proveedor; una tasa cargada por un administrador reemplaza a la del proveedor.
+type Service struct {
+	repository store.RaffleRepository
+	provider   Provider
+	mu         sync.Mutex
+}
+
+func NewService(repository store.RaffleRepository, provider Provider) *Service {
+	return &Service{repository: repository, provider: provider}
+}
+
+func normalizePair(from, to string) (string, string) {
+	return strings.ToUpper(strings.TrimSpace(from)), strings.ToUpper(strings.TrimSpace(to))
+}
+
+// CurrentRate devuelve la tasa del día para convertir de from a to. El proveedor se consulta
+// sin tomar el lock, que solo protege la verificación y el guardado de la tasa.
+func (s *Service) CurrentRate(from, to string) (store.ExchangeRate, error) {
+	from, to = normalizePair(from, to)
+	now := time.Now().UTC()
+	date := Today(now)
+
+	stored, err := s.repository.GetExchangeRate(date, from, to)
+	if err == nil {
+		return *stored, nil
+	}
+	if !errors.Is(err, store.ErrNotFound) {
+		return store.ExchangeRate{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), providerTimeout)
+	defer cancel()
+
+	value, err := s.provider.Rate(ctx, from, to)
+	if err != nil {
+		return store.ExchangeRate{}, fmt.Errorf("%w: %s to %s from %s provider: %v", ErrNoRate, from, to, s.provider.Name(), err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Otra consulta o un administrador pudo guardar la tasa del día mientras se consultaba al proveedor
+	stored, err = s.repository.GetExchangeRate(date, from, to)
+	if err == nil {
+		return *stored, nil
+	}
+	if !errors.Is(err, store.ErrNotFound) {
+		return store.ExchangeRate{}, err
+	}
+
+	rate := store.ExchangeRate{
+		Date:      date,
+		From:      from,
+		To:        to,
+		Rate:      value,
+		Source:    s.provider.Name(),
+		CreatedAt: now,
+	}
+
+	if err := s.repository.SaveExchangeRate(rate); err != nil {
+		return store.ExchangeRate{}, err
+	}
+
+	return rate, nil
+}
+
+// SetRate guarda la tasa cargada por un administrador. Si date está vacío se usa el día actual.
+func (s *Service) SetRate(date, from, to string, value decimal.Decimal) (store.ExchangeRate, error) {
+	from, to = normalizePair(from, to)
+	now := time.Now().UTC()
+
+	if date == "" {
+		date = Today(now)
+	}
+
+	if _, err := time.Parse(time.DateOnly, date); err != nil {
+		return store.ExchangeRate{}, fmt.Errorf("%w: date must be YYYY-MM-DD", ErrInvalidRate)
+	}
+
+	if from == "" || to == "" || from == to {
+		return store.ExchangeRate{}, fmt.Errorf("%w: from and to must be different currencies", ErrInvalidRate)
+	}
+
+	if !value.IsPositive() {
+		return store.ExchangeRate{}, fmt.Errorf("%w: rate must be greater than 0", ErrInvalidRate)
+	}
+
+	rate := store.ExchangeRate{
+		Date:      date,
+		From:      from,
+		To:        to,
+		Rate:      value,
+		Source:    AdminSource,
+		CreatedAt: now,
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.repository.SaveExchangeRate(rate); err != nil {
+		return store.ExchangeRate{}, err
+	}
+
+	return rate, nil
+}
+
+// History devuelve las tasas guardadas del par de monedas, de la más reciente a la más antigua
+func (s *Service) History(from, to string) ([]store.ExchangeRate, error) {
+	from, to = normalizePair(from, to)
+	return s.repository.ListExchangeRates(from, to)
+}
@@ -0,0 +1,127 @@
+package exchange
+
+import (
+	"context"
+	"errors"
+	"raffle_web_server/store/storetest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// stubProvider devuelve una tasa fija y cuenta las consultas. Si release no es nil, cada consulta
+// espera a que se cierre antes de responder.
+type stubProvider struct {
+	rate    decimal.Decimal
+	err     error
+	calls   atomic.Int32
+	started chan struct{}
+	release chan struct{}
+}
+
+func (p *stubProvider) Name() string {
+	return "stub"
+}
+
+func (p *stubProvider) Rate(ctx context.Context, from, to string) (decimal.Decimal, error) {
+	p.calls.Add(1)
+	if p.release != nil {
+		close(p.started)
+		<-p.release
+	}
+	return p.rate, p.err
+}
+
+func TestCurrentRateKeepsTheProviderRateForTheDay(t *testing.T) {
+	provider := &stubProvider{rate: decimal.NewFromInt(40)}
+	service := NewService(storetest.NewRepository(t), provider)
+
+	for i := 0; i < 3; i++ {
+		rate, err := service.CurrentRate("usd", " VES ")
+		if err != nil {
+			t.Fatalf("CurrentRate: %v", err)
+		}
+		if !rate.Rate.Equal(decimal.NewFromInt(40)) || rate.Source != "stub" || rate.From != "USD" || rate.To != "VES" {
+			t.Fatalf("rate = %+v, want 40 USD_VES from stub", rate)
+		}
+		if rate.Date != Today(time.Now()) {
+			t.Fatalf("rate date = %s, want %s", rate.Date, Today(time.Now()))
+		}
+	}
+
+	if calls := provider.calls.Load(); calls != 1 {
+		t.Fatalf("provider calls = %d, want 1", calls)
+	}
+}
+
+func TestCurrentRateUsesTheDailyRateBeforeTheProvider(t *testing.T) {
+	provider := &stubProvider{err: errors.New("provider down")}
+	service := NewService(storetest.NewRepository(t), provider)
+
+	if _, err := service.CurrentRate("USD", "VES"); !errors.Is(err, ErrNoRate) {
+		t.Fatalf("CurrentRate without a rate: err = %v, want %v", err, ErrNoRate)
+	}
+
+	if _, err := service.SetRate("", "USD", "VES", decimal.NewFromFloat(36.5)); err != nil {
+		t.Fatalf("SetRate: %v", err)
+	}
+
+	rate, err := service.CurrentRate("USD", "VES")
+	if err != nil {
+		t.Fatalf("CurrentRate: %v", err)
+	}
+	if !rate.Rate.Equal(decimal.NewFromFloat(36.5)) || rate.Source != AdminSource {
+		t.Fatalf("rate = %s from %s, want 36.5 from %s", rate.Rate, rate.Source, AdminSource)
+	}
+	if calls := provider.calls.Load(); calls != 1 {
+		t.Fatalf("provider calls = %d, want 1", calls)
+	}
+}
+
+func TestCurrentRateDoesNotHoldTheLockWhileFetching(t *testing.T) {
+	provider := &stubProvider{
+		rate:    decimal.NewFromInt(40),
+		started: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+	service := NewService(storetest.NewRepository(t), provider)
+
+	fetched := make(chan error, 1)
+	go func() {
+		_, err := service.CurrentRate("USD", "VES")
+		fetched <- err
+	}()
+	<-provider.started
+
+	// Un administrador carga la tasa mientras el proveedor responde
+	saved := make(chan error, 1)
+	go func() {
+		_, err := service.SetRate("", "USD", "VES", decimal.NewFromFloat(36.5))
+		saved <- err
+	}()
+
+	select {
+	case err := <-saved:
+		if err != nil {
+			t.Fatalf("SetRate: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("SetRate blocked while the provider was being called")
+	}
+
+	close(provider.release)
+	if err := <-fetched; err != nil {
+		t.Fatalf("CurrentRate: %v", err)
+	}
+
+	// La tasa del administrador no se reemplaza con la que llegó después
+	rate, err := service.CurrentRate("USD", "VES")
+	if err != nil {
+		t.Fatalf("CurrentRate: %v", err)
+	}
+	if rate.Source != AdminSource {
+		t.Fatalf("rate source = %s, want %s", rate.Source, AdminSource)
+	}
+}
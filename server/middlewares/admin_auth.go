@@ -0,0 +1,44 @@
+package middlewares
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"raffle_web_server/config"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// RequireAdminKey exige el header "Authorization: Bearer <ApiKey>" con la clave
+// configurada en AdminConfig. Si no hay clave configurada se rechazan todas las peticiones.
+func RequireAdminKey() gin.HandlerFunc {
+
+	return func(c *gin.Context) {
+		apiKey := config.GetConfig().AdminConfig.ApiKey
+
+		if apiKey == "" {
+			log.Warn().Str("path", c.Request.URL.Path).Msg("Gin Rest API/Admin Auth/ Admin API sin clave configurada")
+
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+				"error":   "Admin API disabled",
+				"message": "No admin API key is configured",
+			})
+			return
+		}
+
+		token, found := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+
+		if !found || subtle.ConstantTimeCompare([]byte(token), []byte(apiKey)) != 1 {
+			log.Warn().Str("path", c.Request.URL.Path).Str("ip", c.ClientIP()).Msg("Gin Rest API/Admin Auth/ Credenciales inválidas")
+
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error":   "Unauthorized",
+				"message": "A valid admin API key is required",
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
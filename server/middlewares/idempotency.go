@@ -0,0 +1,184 @@
+package middlewares
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"raffle_web_server/store"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// IdempotencyKeyHeader es el header con el que el cliente identifica una operación
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// IdempotentReplayHeader indica que la respuesta es la repetición de una anterior
+const IdempotentReplayHeader = "Idempotent-Replayed"
+
+const maxIdempotencyKeyLength = 255
+const idempotencyTTL = 24 * time.Hour
+
+// IdempotencyGuard guarda la primera respuesta exitosa de cada Idempotency-Key y evita que
+// dos peticiones con la misma clave se procesen a la vez
+type IdempotencyGuard struct {
+	repository store.RaffleRepository
+	mu         sync.Mutex
+	inflight   map[string]bool
+}
+
+func NewIdempotencyGuard(repository store.RaffleRepository) *IdempotencyGuard {
+	return &IdempotencyGuard{
+		repository: repository,
+		inflight:   make(map[string]bool),
+	}
+}
+
+// begin marca la clave como en proceso; devuelve false si ya lo estaba
+func (g *IdempotencyGuard) begin(key string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.inflight[key] {
+		return false
+	}
+	g.inflight[key] = true
+	return true
+}
+
+func (g *IdempotencyGuard) end(key string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	delete(g.inflight, key)
+}
+
+// recordingWriter copia el body de la respuesta para poder guardarla
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(content []byte) (int, error) {
+	w.body.Write(content)
+	return w.ResponseWriter.Write(content)
+}
+
+func (w *recordingWriter) WriteString(content string) (int, error) {
+	w.body.WriteString(content)
+	return w.ResponseWriter.WriteString(content)
+}
+
+// idempotencyScope devuelve la huella de la ruta, la clave y la reserva indicada en el
+// campo bookingField del body JSON
+func idempotencyScope(c *gin.Context, key string, body []byte, bookingField string) string {
+	bookingId := ""
+	if bookingField != "" {
+		var fields map[string]interface{}
+		if err := json.Unmarshal(body, &fields); err == nil {
+			bookingId, _ = fields[bookingField].(string)
+		}
+	}
+
+	hash := sha256.Sum256([]byte(c.Request.Method + "\x00" + c.FullPath() + "\x00" + bookingId + "\x00" + key))
+	return hex.EncodeToString(hash[:])
+}
+
+// Idempotent atiende el header Idempotency-Key. La primera respuesta exitosa (2xx) para la
+// ruta, la clave y la reserva se guarda y se repite ante las peticiones duplicadas. Las
+// respuestas con error no se guardan para que el cliente pueda corregir y reintentar.
+// Sin el header la petición se procesa normalmente.
+func Idempotent(guard *IdempotencyGuard, bookingField string) gin.HandlerFunc {
+
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		if len(key) > maxIdempotencyKeyLength {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid Idempotency-Key",
+				"message": "The Idempotency-Key header must have at most 255 characters",
+			})
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid request format",
+				"message": "Unable to read the request body",
+			})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		bodyHash := sha256.Sum256(body)
+		requestHash := hex.EncodeToString(bodyHash[:])
+		scope := idempotencyScope(c, key, body, bookingField)
+
+		if !guard.begin(scope) {
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{
+				"error":   "Request in progress",
+				"message": "A request with this Idempotency-Key is still being processed",
+			})
+			return
+		}
+		defer guard.end(scope)
+
+		stored, err := guard.repository.GetIdempotentResponse(scope, time.Now().UTC())
+		if err != nil && !errors.Is(err, store.ErrNotFound) {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"error":   "Internal Server Error",
+				"message": "Unable to read the idempotency record",
+			})
+			return
+		}
+
+		if stored != nil {
+			if stored.RequestHash != requestHash {
+				c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{
+					"error":   "Idempotency-Key reused",
+					"message": "The Idempotency-Key was already used with a different request",
+				})
+				return
+			}
+
+			c.Header(IdempotentReplayHeader, "true")
+			c.Data(stored.StatusCode, stored.ContentType, []byte(stored.Body))
+			c.Abort()
+			return
+		}
+
+		writer := &recordingWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+
+		c.Next()
+
+		if writer.Status() < 200 || writer.Status() >= 300 {
+			return
+		}
+
+		now := time.Now().UTC()
+		err = guard.repository.SaveIdempotentResponse(store.IdempotentResponse{
+			Key:         scope,
+			RequestHash: requestHash,
+			StatusCode:  writer.Status(),
+			ContentType: writer.Header().Get("Content-Type"),
+			Body:        writer.body.String(),
+			CreatedAt:   now,
+			ExpiresAt:   now.Add(idempotencyTTL),
+		})
+		if err != nil {
+			log.Error().Err(err).Str("path", c.Request.URL.Path).Msg("Gin Rest API/Idempotency/ No se pudo guardar la respuesta")
+		}
+	}
+}
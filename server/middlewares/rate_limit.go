@@ -0,0 +1,105 @@
+package middlewares
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// rateWindow cuenta las peticiones de una clave dentro de la ventana actual
+type rateWindow struct {
+	count   int
+	resetAt time.Time
+}
+
+// RateLimiter limita la cantidad de peticiones por clave usando ventanas fijas.
+// El límite se consulta en cada petición para respetar la recarga de la configuración.
+type RateLimiter struct {
+	mu        sync.Mutex
+	windows   map[string]*rateWindow
+	settings  func() (int, time.Duration)
+	lastPrune time.Time
+}
+
+// NewRateLimiter crea un limitador. settings devuelve el máximo de peticiones y la
+// duración de la ventana; un máximo menor o igual a 0 desactiva el límite.
+func NewRateLimiter(settings func() (int, time.Duration)) *RateLimiter {
+	return &RateLimiter{
+		windows:  make(map[string]*rateWindow),
+		settings: settings,
+	}
+}
+
+// Allow registra una petición para la clave e indica si está permitida.
+// Si no lo está devuelve el tiempo restante hasta que se reinicie la ventana.
+func (l *RateLimiter) Allow(key string) (bool, time.Duration) {
+	limit, window := l.settings()
+	if limit <= 0 || window <= 0 {
+		return true, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.prune(now, window)
+
+	current, exists := l.windows[key]
+	if !exists || !now.Before(current.resetAt) {
+		current = &rateWindow{resetAt: now.Add(window)}
+		l.windows[key] = current
+	}
+
+	if current.count >= limit {
+		return false, current.resetAt.Sub(now)
+	}
+
+	current.count++
+	return true, 0
+}
+
+// prune elimina las ventanas vencidas como máximo una vez por ventana
+func (l *RateLimiter) prune(now time.Time, window time.Duration) {
+	if now.Sub(l.lastPrune) < window {
+		return
+	}
+
+	for key, current := range l.windows {
+		if !now.Before(current.resetAt) {
+			delete(l.windows, key)
+		}
+	}
+	l.lastPrune = now
+}
+
+// RateLimit rechaza con 429 las peticiones que superan el límite para la clave
+// devuelta por key. Si key devuelve una cadena vacía la petición no se limita.
+func RateLimit(limiter *RateLimiter, key func(c *gin.Context) string) gin.HandlerFunc {
+
+	return func(c *gin.Context) {
+		limitKey := key(c)
+		if limitKey == "" {
+			c.Next()
+			return
+		}
+
+		allowed, retryAfter := limiter.Allow(limitKey)
+		if !allowed {
+			log.Warn().Str("path", c.Request.URL.Path).Str("ip", c.ClientIP()).Msg("Gin Rest API/Rate Limit/ Límite de peticiones excedido")
+
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"error":   "Too many requests",
+				"message": "Rate limit exceeded, please try again later",
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,101 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func fixedLimit(limit int, window time.Duration) func() (int, time.Duration) {
+	return func() (int, time.Duration) {
+		return limit, window
+	}
+}
+
+func TestRateLimiterCountsEachKey(t *testing.T) {
+	limiter := NewRateLimiter(fixedLimit(2, time.Minute))
+
+	for i := range 2 {
+		if allowed, _ := limiter.Allow("V12345678"); !allowed {
+			t.Fatalf("request %d was rejected within the limit", i+1)
+		}
+	}
+
+	allowed, retryAfter := limiter.Allow("V12345678")
+	if allowed {
+		t.Fatal("the third request was allowed over a limit of 2")
+	}
+	if retryAfter <= 0 || retryAfter > time.Minute {
+		t.Fatalf("retry after = %v, want the rest of the window", retryAfter)
+	}
+
+	// Otra clave tiene su propia ventana
+	if allowed, _ := limiter.Allow("V87654321"); !allowed {
+		t.Fatal("another key was rejected")
+	}
+}
+
+func TestRateLimiterResetsAfterTheWindow(t *testing.T) {
+	limiter := NewRateLimiter(fixedLimit(1, 20*time.Millisecond))
+
+	if allowed, _ := limiter.Allow("key"); !allowed {
+		t.Fatal("the first request was rejected")
+	}
+	if allowed, _ := limiter.Allow("key"); allowed {
+		t.Fatal("the second request was allowed within the window")
+	}
+
+	time.Sleep(30 * time.Millisecond)
+
+	if allowed, _ := limiter.Allow("key"); !allowed {
+		t.Fatal("the request was rejected after the window elapsed")
+	}
+}
+
+func TestRateLimiterWithoutLimit(t *testing.T) {
+	limiter := NewRateLimiter(fixedLimit(0, time.Minute))
+
+	for range 100 {
+		if allowed, _ := limiter.Allow("key"); !allowed {
+			t.Fatal("a request was rejected with the limit disabled")
+		}
+	}
+}
+
+func TestRateLimitRespondsTooManyRequests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	limiter := NewRateLimiter(fixedLimit(1, time.Minute))
+
+	router := gin.New()
+	router.GET("/prize", RateLimit(limiter, func(c *gin.Context) string { return c.Query("documentId") }), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	cases := []struct {
+		name   string
+		target string
+		want   int
+	}{
+		{"first request", "/prize?documentId=V1", http.StatusOK},
+		{"over the limit", "/prize?documentId=V1", http.StatusTooManyRequests},
+		{"another document", "/prize?documentId=V2", http.StatusOK},
+		{"without key", "/prize", http.StatusOK},
+		{"without key again", "/prize", http.StatusOK},
+	}
+
+	for _, tc := range cases {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, tc.target, nil))
+
+		if recorder.Code != tc.want {
+			t.Errorf("%s: status = %d, want %d", tc.name, recorder.Code, tc.want)
+		}
+		if tc.want == http.StatusTooManyRequests && recorder.Header().Get("Retry-After") == "" {
+			t.Errorf("%s: missing Retry-After header", tc.name)
+		}
+	}
+}
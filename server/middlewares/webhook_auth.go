@@ -0,0 +1,90 @@
+package middlewares
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// WebhookSignatureHeader lleva el HMAC-SHA256 del body, en hexadecimal, calculado con el secreto del webhook
+const WebhookSignatureHeader = "X-Webhook-Signature"
+
+// WebhookTokenHeader lleva el secreto del webhook cuando el emisor no firma el body
+const WebhookTokenHeader = "X-Webhook-Token"
+
+// maxWebhookBodyBytes limita el body que se lee para verificar la firma
+const maxWebhookBodyBytes = 1 << 20
+
+// validWebhookSignature compara la firma recibida (con o sin el prefijo "sha256=") con el HMAC del body
+func validWebhookSignature(signature string, body []byte, secret string) bool {
+	received, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(signature), "sha256="))
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	return hmac.Equal(received, mac.Sum(nil))
+}
+
+// RequireWebhookAuth exige el secreto que devuelve secret, consultado en cada request: la firma HMAC
+// del body en el header X-Webhook-Signature o el secreto en el header X-Webhook-Token. El secreto no
+// viaja en la URL para que no quede en los logs de acceso. Si no hay secreto configurado se
+// rechazan todas las notificaciones.
+func RequireWebhookAuth(secret func() string) gin.HandlerFunc {
+
+	return func(c *gin.Context) {
+		secret := secret()
+
+		if secret == "" {
+			log.Warn().Str("path", c.Request.URL.Path).Msg("Gin Rest API/Webhook Auth/ Webhook sin secreto configurado")
+
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+				"error":   "Webhook disabled",
+				"message": "No webhook secret is configured",
+			})
+			return
+		}
+
+		authorized := false
+
+		if signature := c.GetHeader(WebhookSignatureHeader); signature != "" {
+			body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxWebhookBodyBytes))
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{
+					"error":   "Invalid request format",
+					"message": "Unable to read the notification body",
+					"details": err.Error(),
+				})
+				return
+			}
+
+			// El handler vuelve a leer el body
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+			authorized = validWebhookSignature(signature, body, secret)
+		} else if token := c.GetHeader(WebhookTokenHeader); token != "" {
+			authorized = subtle.ConstantTimeCompare([]byte(token), []byte(secret)) == 1
+		}
+
+		if !authorized {
+			log.Warn().Str("path", c.Request.URL.Path).Str("ip", c.ClientIP()).Msg("Gin Rest API/Webhook Auth/ Credenciales inválidas")
+
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error":   "Unauthorized",
+				"message": "A valid webhook signature or token is required",
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
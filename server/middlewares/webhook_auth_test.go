@@ -0,0 +1,62 @@
+package middlewares
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequireWebhookAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	const body = `{"transaction_id":"TX-1"}`
+
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write([]byte(body))
+	signature := hex.EncodeToString(mac.Sum(nil))
+
+	router := gin.New()
+	router.POST("/webhook", RequireWebhookAuth(func() string { return "s3cret" }), func(c *gin.Context) {
+		received, _ := io.ReadAll(c.Request.Body)
+		c.String(http.StatusOK, string(received))
+	})
+
+	cases := []struct {
+		name    string
+		target  string
+		headers map[string]string
+		want    int
+	}{
+		{"signature", "/webhook", map[string]string{WebhookSignatureHeader: signature}, http.StatusOK},
+		{"prefixed signature", "/webhook", map[string]string{WebhookSignatureHeader: "sha256=" + signature}, http.StatusOK},
+		{"token header", "/webhook", map[string]string{WebhookTokenHeader: "s3cret"}, http.StatusOK},
+		{"wrong signature", "/webhook", map[string]string{WebhookSignatureHeader: strings.Repeat("0", 64)}, http.StatusUnauthorized},
+		{"token in query", "/webhook?token=s3cret", nil, http.StatusUnauthorized},
+		{"no credentials", "/webhook", nil, http.StatusUnauthorized},
+	}
+
+	for _, tc := range cases {
+		request := httptest.NewRequest(http.MethodPost, tc.target, strings.NewReader(body))
+		for name, value := range tc.headers {
+			request.Header.Set(name, value)
+		}
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+
+		if recorder.Code != tc.want {
+			t.Errorf("%s: status = %d, want %d", tc.name, recorder.Code, tc.want)
+			continue
+		}
+		if tc.want == http.StatusOK && recorder.Body.String() != body {
+			t.Errorf("%s: handler read %q, want %q", tc.name, recorder.Body.String(), body)
+		}
+	}
+}
@@ -1,41 +1,46 @@
 package mock
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
-	"math/rand"
 	"net/http"
+	"raffle_web_server/booking"
+	"raffle_web_server/config"
+	"raffle_web_server/draw"
+	"raffle_web_server/exchange"
+	"raffle_web_server/middlewares"
+	"raffle_web_server/payment"
+	"raffle_web_server/refund"
+	"raffle_web_server/reservation"
+	"raffle_web_server/store"
+	"raffle_web_server/sypago"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
 )
 
-const clientId = "orlando"
-const apiKey = "06TtzNsTXVSlVdqJ7cLMmcZ9FALIyKUX"
-
-const sypagoApiBaseUrl = "https://pruebas.sypago.net:8086"
-
-const account = "01052510145947929553"
-const bankCode = "0105"
-
 // RaffleId representa el ID de una rifa
 type RaffleId string
 
 type RaffleParticipant struct {
-	ParticipantId RaffleId `json:"participantId"`
-	RaffleId      RaffleId `json:"raffleId"`
-	Name          string   `json:"name"`
-	Email         string   `json:"email"`
-	Phone         string   `json:"phone"`
-	TicketNumber  []int    `json:"ticketNumber"`
+	ParticipantId  RaffleId `json:"participantId"`
+	RaffleId       RaffleId `json:"raffleId"`
+	DocumentId     string   `json:"id"`
+	Name           string   `json:"name"`
+	Email          string   `json:"email"`
+	Phone          string   `json:"phone"`
+	TicketNumber   []int    `json:"ticketNumber"`   // Puede venir vacío para que el servidor elija los números
+	TicketQuantity int      `json:"ticketQuantity"` // Cantidad de tickets a elegir cuando TicketNumber está vacío
 }
 
 type RaffleParticipantResponse struct {
 	ReserveTickets []int  `json:"reserveTickets"`
 	BookingId      string `json:"bookingId"`
+	ExpiresAt      string `json:"expiresAt"`
 }
 
 // RaffleVerifyRequest representa el request para verificar tickets de un participante
@@ -46,9 +51,10 @@ type RaffleVerifyRequest struct {
 
 // RaffleVerifyTicket representa un ticket verificado
 type RaffleVerifyTicket struct {
-	TicketNumber  int   `json:"ticketNumber"`
-	IsMainPrize   *bool `json:"isMainPrize,omitempty"`
-	IsBlessNumber *bool `json:"isBlessNumber,omitempty"`
+	TicketNumber  int    `json:"ticketNumber"`
+	IsMainPrize   *bool  `json:"isMainPrize,omitempty"`
+	IsBlessNumber *bool  `json:"isBlessNumber,omitempty"`
+	PurchasedAt   string `json:"purchasedAt"` // RFC3339
 }
 
 // RaffleVerifyResult representa el resultado de la verificación
@@ -68,34 +74,21 @@ type Prize struct {
 	ImageUrl         string   `json:"imageUrl"`
 	Title            string   `json:"title"`
 	ShortDescription string   `json:"shortDescription"`
-	WinningTicket    int      `json:"winningTicket"`
+	WinningTicket    int      `json:"winningTicket,omitempty"`
 	IsMainPrize      *bool    `json:"isMainPrize,omitempty"`
+	Kind             string   `json:"kind"`
+	Rank             int      `json:"rank"`
 }
 
-type SypagoJwtRequest struct {
-	ClientId string `json:"client_id"`
-	ApiKey   string `json:"secret"`
-}
-
-// Bank representa la estructura completa del banco desde SyPago API
-type Bank struct {
-	Code                      string `json:"Code"`
-	Name                      string `json:"Name"`
-	Active                    bool   `json:"Active"`
-	SypagoClient              bool   `json:"SypagoClient"`
-	EnableTransitionAccount   bool   `json:"EnableTransitionAccount"`
-	VerifyType                int    `json:"VerifyType"`
-	IsSmsOtp                  bool   `json:"IsSmsOtp"`
-	SmsOtpAddress             string `json:"SmsOtpAddress"`
-	SmsOtpText                string `json:"SmsOtpText"`
-	IsDebitOTP                bool   `json:"IsDebitOTP"`
-	DisabledValidationAccount bool   `json:"DisabledValidationAccount"`
-}
-
-// BankResponse representa la respuesta simplificada para el frontend
+// BankResponse representa la respuesta simplificada para el frontend. Incluye cómo
+// recibe el usuario el OTP para que la interfaz se lo indique.
 type BankResponse struct {
-	Code string `json:"code"`
-	Name string `json:"name"`
+	Code          string `json:"code"`
+	Name          string `json:"name"`
+	VerifyType    int    `json:"verifyType"`
+	IsSmsOtp      bool   `json:"isSmsOtp"`
+	SmsOtpAddress string `json:"smsOtpAddress,omitempty"`
+	SmsOtpText    string `json:"smsOtpText,omitempty"`
 }
 
 // RaffleSummary representa el resumen de una rifa
@@ -113,142 +106,149 @@ type RaffleSummary struct {
 	TotalSold        int      `json:"totalSold"`
 }
 
-// getMockRaffles devuelve 4 rifas de ejemplo
-func getMockRaffles() []RaffleSummary {
-	isMain := true
-	return []RaffleSummary{
-		{
-			ID:               "raffle-001",
-			Title:            "iPhone 15 Pro Max",
-			ShortDescription: "Último modelo de iPhone con 256GB de almacenamiento",
-			CoverImageUrl:    "https://images.unsplash.com/photo-1592750475338-74b7b21085ab?w=400",
-			Price:            25.00,
-			Currency:         "USD",
-			InitialTicket:    1,
-			TicketsTotal:     1000,
-			EndsAt:           time.Now().AddDate(0, 0, 15).Format(time.RFC3339),
-			IsMain:           &isMain,
-			TotalSold:        100,
-		},
-		{
-			ID:               "raffle-002",
-			Title:            "PlayStation 5",
-			ShortDescription: "Consola de videojuegos de última generación",
-			CoverImageUrl:    "https://images.unsplash.com/photo-1606813907291-d86efa9b94db?w=400",
-			Price:            15.00,
-			Currency:         "USD",
-			InitialTicket:    1001,
-			TicketsTotal:     800,
-			EndsAt:           time.Now().AddDate(0, 0, 22).Format(time.RFC3339),
-			TotalSold:        100,
-		},
-		{
-			ID:               "raffle-003",
-			Title:            "MacBook Air M2",
-			ShortDescription: "Laptop ultradelgada con chip M2 y 512GB SSD",
-			CoverImageUrl:    "https://images.unsplash.com/photo-1541807084-5c52b6b3adef?w=400",
-			Price:            30.00,
-			Currency:         "USD",
-			InitialTicket:    1801,
-			TicketsTotal:     500,
-			EndsAt:           time.Now().AddDate(0, 1, 5).Format(time.RFC3339),
-			TotalSold:        50,
-		},
-		{
-			ID:               "raffle-004",
-			Title:            "Tesla Model 3",
-			ShortDescription: "Vehículo eléctrico premium con autopilot",
-			CoverImageUrl:    "https://images.unsplash.com/photo-1560958089-b8a1929cea89?w=400",
-			Price:            100.00,
-			Currency:         "USD",
-			InitialTicket:    2301,
-			TicketsTotal:     2000,
-			EndsAt:           time.Now().AddDate(0, 2, 0).Format(time.RFC3339),
-			TotalSold:        5,
-		},
-	}
-}
-
-// simulateRandomError simula errores aleatorios para testing
-func simulateRandomError(c *gin.Context) bool {
-	source := rand.NewSource(time.Now().UnixNano())
-	rng := rand.New(source)
-	randomNum := rng.Intn(3) + 1 // Genera número entre 1 y 3
-
-	if randomNum == 1 {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Internal Server Error",
-			"message": "Something went wrong on the server",
-			"code":    500,
-		})
-		return true // Error generado
+// raffleRepository es el almacén persistente de rifas, tickets, participantes y reservas
+var raffleRepository store.RaffleRepository
+
+// reservationEngine retiene los tickets mientras se completa el pago
+var reservationEngine *reservation.Engine
+
+// drawService ejecuta y guarda los sorteos de las rifas
+var drawService *draw.Service
+
+// bookingService avanza el estado de las reservas según el flujo de pago
+var bookingService *booking.Service
+
+// sypagoDebit cobra las reservas con el débito con OTP de SyPago
+var sypagoDebit *payment.Sypago
+
+// bankCatalog guarda en cache la lista de bancos de SyPago
+var bankCatalog *sypago.BankCatalog
+
+// rejectCatalog describe en español los códigos de rechazo de SyPago
+var rejectCatalog *sypago.RejectCatalog
+
+// toRaffleSummary convierte una rifa persistida al formato que consume el frontend
+func toRaffleSummary(raffle store.Raffle) RaffleSummary {
+	totalSold, err := raffleRepository.CountTickets(raffle.ID, store.TicketSold)
+	if err != nil {
+		fmt.Printf("Error counting sold tickets for raffle %s: %v\n", raffle.ID, err)
+	}
+
+	summary := RaffleSummary{
+		ID:               RaffleId(raffle.ID),
+		Title:            raffle.Title,
+		ShortDescription: raffle.ShortDescription,
+		CoverImageUrl:    raffle.CoverImageUrl,
+		Price:            raffle.Price,
+		Currency:         raffle.Currency,
+		InitialTicket:    raffle.InitialTicket,
+		TicketsTotal:     raffle.TicketsTotal,
+		EndsAt:           raffle.EndsAt.Format(time.RFC3339),
+		TotalSold:        totalSold,
 	}
-	return false // Sin error, continuar normalmente
+
+	if raffle.IsMain {
+		isMain := true
+		summary.IsMain = &isMain
+	}
+
+	return summary
 }
 
 // getRaffles maneja el endpoint GET /raffles
 func getRaffles(c *gin.Context) {
-	// Simular error aleatorio
-	if simulateRandomError(c) {
+	raffles, err := raffleRepository.ListRaffles()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to load raffles",
+			"message": "Unable to retrieve raffles from the store",
+			"details": err.Error(),
+		})
 		return
 	}
 
-	raffles := getMockRaffles()
-	c.JSON(http.StatusOK, raffles)
-}
-
-// getRaffleById busca una rifa por ID
-func getRaffleById(raffleId string) *RaffleSummary {
-	raffles := getMockRaffles()
+	summaries := make([]RaffleSummary, 0, len(raffles))
 	for _, raffle := range raffles {
-		if string(raffle.ID) == raffleId {
-			return &raffle
+		if !raffle.Status.IsPublic() {
+			continue
 		}
+		summaries = append(summaries, toRaffleSummary(raffle))
 	}
-	return nil
+
+	c.JSON(http.StatusOK, summaries)
 }
 
-// generateSoldTickets genera números de tickets vendidos aleatorios dentro del rango de la rifa
-func generateSoldTickets(raffle *RaffleSummary) []int {
-	if raffle.TotalSold <= 0 {
-		return []int{}
+// getRaffleById busca una rifa pública por ID en el almacén.
+// Las rifas en borrador o archivadas no se exponen.
+func getRaffleById(raffleId string) *RaffleSummary {
+	raffle, err := raffleRepository.GetRaffle(raffleId)
+	if err != nil {
+		if !errors.Is(err, store.ErrNotFound) {
+			fmt.Printf("Error loading raffle %s: %v\n", raffleId, err)
+		}
+		return nil
 	}
 
-	// Crear un slice con todos los números posibles en el rango
-	maxTickets := raffle.TicketsTotal
-	if raffle.TotalSold > maxTickets {
-		raffle.TotalSold = maxTickets
+	if !raffle.Status.IsPublic() {
+		return nil
 	}
 
-	// Generar números aleatorios únicos
-	soldTickets := make([]int, 0, raffle.TotalSold)
-	usedNumbers := make(map[int]bool)
+	summary := toRaffleSummary(*raffle)
+	return &summary
+}
+
+// RaffleTicketStatus representa el estado de un ticket para el frontend
+type RaffleTicketStatus string
 
-	// Inicializar generador de números aleatorios
-	source := rand.NewSource(time.Now().UnixNano())
-	rng := rand.New(source)
+const (
+	TicketStatusAvailable RaffleTicketStatus = "available"
+	TicketStatusSold      RaffleTicketStatus = "sold"
+	TicketStatusReserved  RaffleTicketStatus = "reserved"
+)
 
-	for len(soldTickets) < raffle.TotalSold {
-		// Generar número aleatorio en el rango de la rifa
-		ticketNumber := rng.Intn(raffle.TicketsTotal) + raffle.InitialTicket
+// RaffleTicket representa un ticket ocupado de una rifa
+type RaffleTicket struct {
+	RaffleId      RaffleId           `json:"raffleId"`
+	Number        int                `json:"number"`
+	Status        RaffleTicketStatus `json:"status"`
+	IsMainPrize   *bool              `json:"isMainPrize,omitempty"`
+	IsBlessNumber *bool              `json:"isBlessNumber,omitempty"`
+}
 
-		// Verificar que no esté repetido
-		if !usedNumbers[ticketNumber] {
-			usedNumbers[ticketNumber] = true
-			soldTickets = append(soldTickets, ticketNumber)
+// getUnavailableTickets devuelve los tickets vendidos y los retenidos vigentes de una rifa,
+// ordenados por número. Las retenciones vencidas no se incluyen.
+func getUnavailableTickets(raffleId string) ([]RaffleTicket, error) {
+	tickets, err := raffleRepository.ListTickets(raffleId)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	unavailable := make([]RaffleTicket, 0, len(tickets))
+
+	for _, ticket := range tickets {
+		if !ticket.IsHeld(now) {
+			continue
 		}
+
+		status := TicketStatusReserved
+		if ticket.Status == store.TicketSold {
+			status = TicketStatusSold
+		}
+
+		unavailable = append(unavailable, RaffleTicket{
+			RaffleId: RaffleId(raffleId),
+			Number:   ticket.Number,
+			Status:   status,
+		})
 	}
 
-	return soldTickets
+	return unavailable, nil
 }
 
-// getSoldTickets maneja el endpoint GET /api/v1/raffles/:id/tickets/sold
+// getSoldTickets maneja el endpoint GET /api/v1/raffles/:id/tickets/sold.
+// Admite ?format=list|bitset|ranges o el header Accept para elegir la representación.
 func getSoldTickets(c *gin.Context) {
-	// Simular error aleatorio
-	if simulateRandomError(c) {
-		return
-	}
-
 	raffleId := c.Param("id")
 
 	// Buscar la rifa por ID
@@ -261,22 +261,22 @@ func getSoldTickets(c *gin.Context) {
 		return
 	}
 
-	// Generar tickets vendidos
-	soldTickets := generateSoldTickets(raffle)
+	// Tickets vendidos (compras confirmadas) y reservados (retenciones vigentes)
+	tickets, err := getUnavailableTickets(raffleId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to load tickets",
+			"message": "Unable to retrieve sold tickets from the store",
+			"details": err.Error(),
+		})
+		return
+	}
 
-	c.JSON(http.StatusOK, soldTickets)
+	writeSoldTickets(c, raffle, tickets)
 }
 
 // reserveTickets maneja el endpoint POST /api/v1/raffles/reserve
 func reserveTickets(c *gin.Context) {
-	// Simular error aleatorio
-
-	// time.Sleep(10 * time.Second)
-
-	// if simulateRandomError(c) {
-	// 	return
-	// }
-
 	var participant RaffleParticipant
 
 	// Parsear el JSON del request
@@ -289,9 +289,19 @@ func reserveTickets(c *gin.Context) {
 		return
 	}
 
+	// Validar campos requeridos
+	if participant.Name == "" || participant.Email == "" ||
+		(len(participant.TicketNumber) == 0 && participant.TicketQuantity <= 0) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Missing required fields",
+			"message": "Name, email, and at least one ticket number or a ticket quantity are required",
+		})
+		return
+	}
+
 	// Validar que la rifa existe
-	raffle := getRaffleById(string(participant.RaffleId))
-	if raffle == nil {
+	raffle, err := raffleRepository.GetRaffle(string(participant.RaffleId))
+	if err != nil || !raffle.Status.IsPublic() {
 		c.JSON(http.StatusNotFound, gin.H{
 			"error":   "Raffle not found",
 			"message": fmt.Sprintf("No raffle found with ID: %s", participant.RaffleId),
@@ -299,39 +309,19 @@ func reserveTickets(c *gin.Context) {
 		return
 	}
 
-	// Validar que los tickets están en el rango válido de la rifa
-	for _, ticketNum := range participant.TicketNumber {
-		if ticketNum < raffle.InitialTicket || ticketNum >= raffle.InitialTicket+raffle.TicketsTotal {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": "Invalid ticket number",
-				"message": fmt.Sprintf("Ticket number %d is not valid for raffle %s. Valid range: %d-%d",
-					ticketNum, participant.RaffleId, raffle.InitialTicket, raffle.InitialTicket+raffle.TicketsTotal-1),
-			})
-			return
-		}
-	}
-
-	// Validar campos requeridos
-	if participant.Name == "" || participant.Email == "" || len(participant.TicketNumber) == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "Missing required fields",
-			"message": "Name, email, and at least one ticket number are required",
+	participantId, existing, err := resolveParticipant(participant)
+	if errors.Is(err, errParticipantMismatch) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Participant mismatch",
+			"message": "The participant ID belongs to another document",
 		})
 		return
 	}
-
-	// Simular validación de tickets disponibles
-	// En un escenario real, aquí verificarías contra una base de datos
-	reservedTickets := validateAndReserveTickets(participant.TicketNumber)
-
-	// Validar que todos los tickets solicitados fueron reservados
-	if !areTicketListsEqual(participant.TicketNumber, reservedTickets) {
-		c.JSON(http.StatusConflict, gin.H{
-			"error":            "Tickets not available",
-			"message":          "Some of the selected tickets are no longer available. Please select different numbers.",
-			"requestedTickets": participant.TicketNumber,
-			"availableTickets": reservedTickets,
-			"conflictTickets":  findConflictTickets(participant.TicketNumber, reservedTickets),
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to load participant",
+			"message": "Unable to load the participant, please try again",
+			"details": err.Error(),
 		})
 		return
 	}
@@ -339,77 +329,168 @@ func reserveTickets(c *gin.Context) {
 	// Generar booking ID único
 	bookingId := generateBookingId()
 
+	// Retener los tickets de forma atómica para esta reserva.
+	// Sin números seleccionados el servidor elige los tickets al azar (modo "lucky dip").
+	var hold *reservation.Reservation
+	if len(participant.TicketNumber) > 0 {
+		hold, err = reservationEngine.Reserve(raffle, bookingId, participant.TicketNumber)
+	} else {
+		hold, err = reservationEngine.ReserveRandom(raffle, bookingId, participant.TicketQuantity)
+	}
+	if err != nil {
+		respondReservationError(c, participant.TicketNumber, err)
+		return
+	}
+
+	// Persistir participante y reserva
+	if err := saveParticipantBooking(participantId, existing, participant, hold); err != nil {
+		if _, releaseErr := reservationEngine.Release(hold.RaffleId, bookingId); releaseErr != nil {
+			fmt.Printf("Error releasing tickets for booking %s: %v\n", bookingId, releaseErr)
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to save booking",
+			"message": "Unable to store the booking, please try again",
+			"details": err.Error(),
+		})
+		return
+	}
+
 	// Responder con los tickets reservados exitosamente
 	response := RaffleParticipantResponse{
-		ReserveTickets: reservedTickets,
+		ReserveTickets: hold.Tickets,
 		BookingId:      bookingId,
+		ExpiresAt:      hold.HeldUntil.Format(time.RFC3339),
 	}
 
 	c.JSON(http.StatusOK, response)
 }
 
-// validateAndReserveTickets simula la validación y reserva de tickets
-// En un escenario real, esto consultaría una base de datos
-func validateAndReserveTickets(requestedTickets []int) []int {
-	source := rand.NewSource(time.Now().UnixNano())
-	rng := rand.New(source)
+// respondReservationError traduce los errores del motor de reservas a respuestas HTTP
+func respondReservationError(c *gin.Context, requested []int, err error) {
+	var conflictErr *reservation.ConflictError
+
+	switch {
+	case errors.As(err, &conflictErr):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":            "Tickets not available",
+			"message":          "Some of the selected tickets are no longer available. Please select different numbers.",
+			"requestedTickets": requested,
+			"availableTickets": excludeTickets(requested, conflictErr.Tickets),
+			"conflictTickets":  conflictErr.Tickets,
+		})
+	case errors.Is(err, reservation.ErrRaffleClosed):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Raffle closed",
+			"message": "This raffle is no longer accepting reservations.",
+		})
+	case errors.Is(err, store.ErrNotEnoughTickets):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Tickets not available",
+			"message": "There are not enough available tickets left in this raffle.",
+		})
+	case errors.Is(err, reservation.ErrInvalidTickets):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid ticket number",
+			"message": err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to reserve tickets",
+			"message": "Unable to reserve the selected tickets, please try again",
+			"details": err.Error(),
+		})
+	}
+}
 
-	// Simular que algunos tickets pueden no estar disponibles (20% de probabilidad por ticket)
-	var availableTickets []int
+// excludeTickets devuelve los tickets de la lista que no están en excluded
+func excludeTickets(tickets, excluded []int) []int {
+	excludedMap := make(map[int]bool, len(excluded))
+	for _, ticket := range excluded {
+		excludedMap[ticket] = true
+	}
 
-	for _, ticket := range requestedTickets {
-		// 80% de probabilidad de que el ticket esté disponible
-		if rng.Intn(100) < 80 {
-			availableTickets = append(availableTickets, ticket)
+	result := make([]int, 0, len(tickets))
+	for _, ticket := range tickets {
+		if !excludedMap[ticket] {
+			result = append(result, ticket)
 		}
 	}
 
-	return availableTickets
+	return result
 }
 
-// areTicketListsEqual compara si dos listas de tickets son iguales
-func areTicketListsEqual(list1, list2 []int) bool {
-	if len(list1) != len(list2) {
-		return false
-	}
-
-	// Crear mapas para comparación
-	map1 := make(map[int]bool)
-	map2 := make(map[int]bool)
+// errParticipantMismatch se devuelve cuando el participantId enviado pertenece a otro documento
+var errParticipantMismatch = errors.New("participant belongs to another document")
 
-	for _, ticket := range list1 {
-		map1[ticket] = true
+// resolveParticipant devuelve el ID del participante de la reserva. Sin ID enviado se genera uno
+// nuevo; un participante existente solo se reutiliza si es del mismo documento, para que nadie
+// tome las reservas de otro enviando su ID.
+func resolveParticipant(participant RaffleParticipant) (string, *store.Participant, error) {
+	participantId := string(participant.ParticipantId)
+	if participantId == "" {
+		return generateParticipantId(), nil, nil
 	}
 
-	for _, ticket := range list2 {
-		map2[ticket] = true
+	existing, err := raffleRepository.GetParticipant(participantId)
+	if errors.Is(err, store.ErrNotFound) {
+		return participantId, nil, nil
+	}
+	if err != nil {
+		return "", nil, err
 	}
 
-	// Comparar mapas
-	for ticket := range map1 {
-		if !map2[ticket] {
-			return false
-		}
+	if !sameDocument(existing.DocumentId, participant.DocumentId) {
+		return "", nil, errParticipantMismatch
 	}
 
-	return true
+	return participantId, existing, nil
 }
 
-// findConflictTickets encuentra los tickets que no pudieron ser reservados
-func findConflictTickets(requested, available []int) []int {
-	availableMap := make(map[int]bool)
-	for _, ticket := range available {
-		availableMap[ticket] = true
-	}
+// sameDocument compara dos documentos de identidad ignorando mayúsculas y espacios
+func sameDocument(a, b string) bool {
+	return strings.EqualFold(strings.TrimSpace(a), strings.TrimSpace(b))
+}
 
-	var conflicts []int
-	for _, ticket := range requested {
-		if !availableMap[ticket] {
-			conflicts = append(conflicts, ticket)
-		}
+// saveParticipantBooking guarda el participante y su reserva en el almacén
+func saveParticipantBooking(participantId string, existing *store.Participant, participant RaffleParticipant, hold *reservation.Reservation) error {
+	now := time.Now().UTC()
+
+	createdAt := now
+	if existing != nil {
+		createdAt = existing.CreatedAt
 	}
 
-	return conflicts
+	err := raffleRepository.SaveParticipant(store.Participant{
+		ID:         participantId,
+		RaffleId:   string(participant.RaffleId),
+		DocumentId: participant.DocumentId,
+		Name:       participant.Name,
+		Email:      participant.Email,
+		Phone:      participant.Phone,
+		CreatedAt:  createdAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	return raffleRepository.SaveBooking(store.Booking{
+		ID:            hold.BookingId,
+		RaffleId:      hold.RaffleId,
+		ParticipantId: participantId,
+		DocumentId:    strings.TrimSpace(participant.DocumentId),
+		Tickets:       hold.Tickets,
+		Status:        store.BookingReserved,
+		ExpiresAt:     hold.HeldUntil,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	})
+}
+
+// generateParticipantId genera un participant ID único
+func generateParticipantId() string {
+	id := uuid.New()
+	return "PT-" + strings.ToUpper(strings.ReplaceAll(id.String(), "-", ""))
 }
 
 // generateBookingId genera un booking ID único
@@ -418,86 +499,135 @@ func generateBookingId() string {
 	return "BK-" + strings.ToUpper(strings.ReplaceAll(id.String(), "-", ""))
 }
 
-// verifyRaffleTickets simula la verificación de tickets comprados por un documento en una rifa
+// bookingDocument devuelve el documento de quien hizo la reserva. Las reservas anteriores a que
+// se guardara el documento en la reserva usan el del participante.
+func bookingDocument(booking store.Booking) (string, error) {
+	if booking.DocumentId != "" {
+		return booking.DocumentId, nil
+	}
+
+	participant, err := raffleRepository.GetParticipant(booking.ParticipantId)
+	if errors.Is(err, store.ErrNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return participant.DocumentId, nil
+}
+
+// findDocumentBookings devuelve las reservas de la rifa hechas con el documento indicado
+func findDocumentBookings(raffleId, documentId string) (map[string]store.Booking, error) {
+	bookings, err := raffleRepository.ListBookings(raffleId)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]store.Booking)
+
+	for _, booking := range bookings {
+		bookingDocumentId, err := bookingDocument(booking)
+		if err != nil {
+			return nil, err
+		}
+
+		if bookingDocumentId != "" && sameDocument(bookingDocumentId, documentId) {
+			result[booking.ID] = booking
+		}
+	}
+
+	return result, nil
+}
+
+// verifyRaffleTickets busca los tickets vendidos a un documento en una rifa y los marca con el resultado del sorteo.
+// Devuelve nil si el documento no tiene compras confirmadas.
 // Nota: La validación de que la rifa existe se hace en el endpoint antes de llamar esta función
-func verifyRaffleTickets(request RaffleVerifyRequest) *RaffleVerifyResult {
-	// Obtener la rifa (ya validada en el endpoint)
-	raffle := getRaffleById(string(request.RaffleId))
-	if raffle == nil {
-		return nil
+func verifyRaffleTickets(request RaffleVerifyRequest) (*RaffleVerifyResult, error) {
+	raffleId := string(request.RaffleId)
+
+	bookings, err := findDocumentBookings(raffleId, request.DocumentId)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(bookings) == 0 {
+		return nil, nil
 	}
 
-	// Simular búsqueda de tickets comprados por este documento
-	// En un escenario real, esto consultaría una base de datos
-	source := rand.NewSource(time.Now().UnixNano())
-	rng := rand.New(source)
+	tickets, err := raffleRepository.ListTickets(raffleId)
+	if err != nil {
+		return nil, err
+	}
 
-	// 20% de probabilidad de que no existan tickets para este documento
-	if rng.Intn(100) < 20 {
-		return nil // Retornar nil para indicar que no hay tickets
+	result, err := getDrawResult(raffleId)
+	if err != nil {
+		return nil, err
+	}
+
+	mainWinners := make(map[int]bool)
+	blessWinners := make(map[int]bool)
+	if result != nil {
+		for _, winner := range result.MainWinners {
+			mainWinners[winner] = true
+		}
+		for _, winner := range result.BlessWinners {
+			blessWinners[winner] = true
+		}
 	}
 
-	// Generar un número aleatorio de tickets comprados (entre 1 y 10)
-	numTickets := rng.Intn(10) + 1
-	boughtTickets := make([]RaffleVerifyTicket, 0, numTickets)
+	boughtTickets := make([]RaffleVerifyTicket, 0)
 
-	// Generar tickets aleatorios dentro del rango de la rifa
-	usedNumbers := make(map[int]bool)
-	for i := 0; i < numTickets; i++ {
-		var ticketNumber int
-		for {
-			ticketNumber = rng.Intn(raffle.TicketsTotal) + raffle.InitialTicket
-			if !usedNumbers[ticketNumber] {
-				usedNumbers[ticketNumber] = true
-				break
-			}
+	for _, ticket := range tickets {
+		if ticket.Status != store.TicketSold {
+			continue
 		}
 
-		ticket := RaffleVerifyTicket{
-			TicketNumber: ticketNumber,
+		booking, exists := bookings[ticket.BookingId]
+		if !exists {
+			continue
 		}
 
-		// 5% probabilidad de ser número de la suerte
-		if rng.Intn(100) < 5 {
-			isBlessNumber := true
-			ticket.IsBlessNumber = &isBlessNumber
+		purchasedAt := ticket.UpdatedAt
+		if booking.PaidAt != nil {
+			purchasedAt = *booking.PaidAt
 		}
 
-		boughtTickets = append(boughtTickets, ticket)
-	}
+		verified := RaffleVerifyTicket{
+			TicketNumber: ticket.Number,
+			PurchasedAt:  purchasedAt.UTC().Format(time.RFC3339),
+		}
 
-	// Solo puede haber un premio principal - seleccionar aleatoriamente uno si hay tickets
-	// Este ticket puede ser también número de la suerte (bless)
-	if len(boughtTickets) > 0 {
-		// 30% probabilidad de que uno de los tickets sea premio principal
-		if rng.Intn(100) < 30 {
-			// Seleccionar un ticket aleatorio para ser el premio principal
-			// Este ticket puede tener también isBlessNumber si ya lo tiene
-			mainPrizeIndex := rng.Intn(len(boughtTickets))
+		// Un ticket puede ser a la vez ganador principal y número bendecido
+		if mainWinners[ticket.Number] {
 			isMainPrize := true
-			boughtTickets[mainPrizeIndex].IsMainPrize = &isMainPrize
+			verified.IsMainPrize = &isMainPrize
 		}
+		if blessWinners[ticket.Number] {
+			isBlessNumber := true
+			verified.IsBlessNumber = &isBlessNumber
+		}
+
+		boughtTickets = append(boughtTickets, verified)
+	}
+
+	if len(boughtTickets) == 0 {
+		return nil, nil
 	}
 
 	return &RaffleVerifyResult{
 		RaffleId:      request.RaffleId,
 		DocumentId:    request.DocumentId,
 		BoughtTickets: boughtTickets,
-	}
+	}, nil
 }
 
 // verifyRaffleEndpoint maneja el endpoint POST /api/v1/raffles/verify
 func verifyRaffleEndpoint(c *gin.Context) {
-	// Simular error aleatorio
-	if simulateRandomError(c) {
-		time.Sleep(5 * time.Second)
-		return
-	}
-
 	var request RaffleVerifyRequest
 
-	// Parsear el JSON del request
-	if err := c.ShouldBindJSON(&request); err != nil {
+	// Parsear el JSON del request; verifyDocumentIdKey ya lo leyó para limitar las consultas
+	if err := c.ShouldBindBodyWith(&request, binding.JSON); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "Invalid request format",
 			"message": "Please check your request data",
@@ -534,7 +664,16 @@ func verifyRaffleEndpoint(c *gin.Context) {
 	}
 
 	// Verificar tickets
-	result := verifyRaffleTickets(request)
+	result, err := verifyRaffleTickets(request)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to verify tickets",
+			"message": "Unable to retrieve the purchases for this document",
+			"details": err.Error(),
+		})
+		return
+	}
+
 	if result == nil {
 		// La rifa existe pero no hay tickets para este documento
 		c.JSON(http.StatusNotFound, gin.H{
@@ -548,119 +687,100 @@ func verifyRaffleEndpoint(c *gin.Context) {
 	c.JSON(http.StatusOK, result)
 }
 
-// generateMainWinnerTickets genera números de tickets ganadores principales para una rifa
-func generateMainWinnerTickets(raffle *RaffleSummary) []int {
-	if raffle == nil {
-		return []int{}
+// getDrawResult devuelve el sorteo ya realizado de una rifa o nil si aún no se ha sorteado
+func getDrawResult(raffleId string) (*store.Draw, error) {
+	result, err := drawService.GetDraw(raffleId)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
 	}
 
-	source := rand.NewSource(time.Now().UnixNano())
-	rng := rand.New(source)
-
-	// Generar entre 1 y 3 tickets ganadores principales
-	numWinners := rng.Intn(3) + 1
-	winners := make([]int, 0, numWinners)
-	usedNumbers := make(map[int]bool)
-
-	for len(winners) < numWinners {
-		ticketNumber := rng.Intn(raffle.TicketsTotal) + raffle.InitialTicket
-		if !usedNumbers[ticketNumber] {
-			usedNumbers[ticketNumber] = true
-			winners = append(winners, ticketNumber)
-		}
+	if !result.IsDrawn() {
+		return nil, nil
 	}
 
-	return winners
+	return result, nil
 }
 
-// generateBlessNumberWinnerTickets genera números de tickets de la suerte para una rifa
-func generateBlessNumberWinnerTickets(raffle *RaffleSummary) []int {
-	if raffle == nil {
-		return []int{}
+// toPrize convierte un premio del catálogo al formato que consume el frontend
+func toPrize(prize store.Prize, winningTicket int) Prize {
+	result := Prize{
+		ID:               PrizeId(prize.ID),
+		RaffleId:         RaffleId(prize.RaffleId),
+		ImageUrl:         prize.ImageUrl,
+		Title:            prize.Title,
+		ShortDescription: prize.ShortDescription,
+		WinningTicket:    winningTicket,
+		Kind:             string(prize.Kind),
+		Rank:             prize.Rank,
 	}
 
-	source := rand.NewSource(time.Now().UnixNano())
-	rng := rand.New(source)
-
-	// Generar entre 5 y 10 tickets de números de la suerte
-	numBlessNumbers := rng.Intn(6) + 5
-	blessNumbers := make([]int, 0, numBlessNumbers)
-	usedNumbers := make(map[int]bool)
-
-	for len(blessNumbers) < numBlessNumbers {
-		ticketNumber := rng.Intn(raffle.TicketsTotal) + raffle.InitialTicket
-		if !usedNumbers[ticketNumber] {
-			usedNumbers[ticketNumber] = true
-			blessNumbers = append(blessNumbers, ticketNumber)
-		}
+	if prize.Kind == store.PrizeMain {
+		isMain := true
+		result.IsMainPrize = &isMain
 	}
 
-	return blessNumbers
+	return result
 }
 
-// getPrizeByRaffleIdAndTicketId obtiene información de un premio específico
-func getPrizeByRaffleIdAndTicketId(raffleId string, ticketId int) *Prize {
-	raffle := getRaffleById(raffleId)
-	if raffle == nil {
-		return nil
+// getPrizeByRaffleIdAndTicketId obtiene el premio que ganó un ticket en el sorteo persistido.
+// Devuelve nil si la rifa no se ha sorteado o si el ticket no resultó ganador.
+func getPrizeByRaffleIdAndTicketId(raffleId string, ticketId int) (*Prize, error) {
+	result, err := getDrawResult(raffleId)
+	if err != nil || result == nil {
+		return nil, err
 	}
 
-	// Validar que el ticket esté en el rango de la rifa
-	if ticketId < raffle.InitialTicket || ticketId >= raffle.InitialTicket+raffle.TicketsTotal {
-		return nil
+	prizes, err := raffleRepository.ListPrizes(raffleId)
+	if err != nil {
+		return nil, err
 	}
 
-	// Generar premios mock basados en el ticket
-	source := rand.NewSource(time.Now().UnixNano())
-	rng := rand.New(source)
+	won := draw.ResolvePrize(result, prizes, ticketId)
+	if won == nil {
+		return nil, nil
+	}
 
-	// 10% probabilidad de ser premio principal
-	isMainPrize := rng.Intn(100) < 10
+	prize := toPrize(*won, ticketId)
+	return &prize, nil
+}
 
-	// Lista de imágenes variadas para premios
-	prizeImages := []string{
-		"https://images.unsplash.com/photo-1556740758-90de374c12ad?w=400",    // Trofeo
-		"https://images.unsplash.com/photo-1579621970563-ebec7560ff3e?w=400", // Medalla
-		"https://images.unsplash.com/photo-1606107557195-0e29a4b5b4aa?w=400", // Regalo
-		"https://images.unsplash.com/photo-1513475382585-d06e58bcb0e0?w=400", // Caja regalo
-		"https://images.unsplash.com/photo-1607082349566-187342175e2f?w=400", // Estrella
-		"https://images.unsplash.com/photo-1558618666-fcd25c85cd64?w=400",    // Cohete
-		"https://images.unsplash.com/photo-1557804506-669a67965ba0?w=400",    // Diamante
-		"https://images.unsplash.com/photo-1607083206869-4c7672e72a8a?w=400", // Corona
-	}
+// getRafflePrizesEndpoint maneja el endpoint GET /api/v1/raffles/:id/prizes
+func getRafflePrizesEndpoint(c *gin.Context) {
+	raffleId := c.Param("id")
 
-	// Seleccionar imagen basada en el ticketId para que sea determinístico pero variado
-	imageIndex := ticketId % len(prizeImages)
-	prizeImageUrl := prizeImages[imageIndex]
+	// Buscar la rifa por ID
+	raffle := getRaffleById(raffleId)
+	if raffle == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Raffle not found",
+			"message": fmt.Sprintf("No raffle found with ID: %s", raffleId),
+		})
+		return
+	}
 
-	prize := &Prize{
-		ID:               PrizeId("prize-" + generateUUID()[:8]),
-		RaffleId:         RaffleId(raffleId),
-		ImageUrl:         prizeImageUrl,
-		Title:            raffle.Title + " - Premio",
-		ShortDescription: "Premio ganador de la rifa " + raffle.Title,
-		WinningTicket:    ticketId,
+	prizes, err := raffleRepository.ListPrizes(raffleId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to load prizes",
+			"message": "Unable to retrieve the prize catalog",
+			"details": err.Error(),
+		})
+		return
 	}
 
-	if isMainPrize {
-		isMain := true
-		prize.IsMainPrize = &isMain
-		prize.Title = raffle.Title + " - Premio Principal"
-		prize.ShortDescription = "Premio principal ganador de la rifa " + raffle.Title
-		// Para premios principales, usar imagen especial de trofeo/corona
-		prize.ImageUrl = "https://images.unsplash.com/photo-1607083206869-4c7672e72a8a?w=400" // Corona para premio principal
+	catalog := make([]Prize, 0, len(prizes))
+	for _, prize := range prizes {
+		catalog = append(catalog, toPrize(prize, 0))
 	}
 
-	return prize
+	c.JSON(http.StatusOK, catalog)
 }
 
 // getMainWinnerTicketsEndpoint maneja el endpoint GET /api/v1/raffles/:id/winners/main
 func getMainWinnerTicketsEndpoint(c *gin.Context) {
-	// Simular error aleatorio
-	if simulateRandomError(c) {
-		return
-	}
-
 	raffleId := c.Param("id")
 
 	// Buscar la rifa por ID
@@ -673,19 +793,60 @@ func getMainWinnerTicketsEndpoint(c *gin.Context) {
 		return
 	}
 
-	// Generar tickets ganadores principales
-	winnerTickets := generateMainWinnerTickets(raffle)
+	// Ganadores persistidos del sorteo; lista vacía si aún no se ha sorteado
+	result, err := getDrawResult(raffleId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to load draw",
+			"message": "Unable to retrieve the draw results",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	winnerTickets := []int{}
+	if result != nil {
+		winnerTickets = append(winnerTickets, result.MainWinners...)
+	}
 
 	c.JSON(http.StatusOK, winnerTickets)
 }
 
 // getBlessNumberWinnerTicketsEndpoint maneja el endpoint GET /api/v1/raffles/:id/winners/bless
 func getBlessNumberWinnerTicketsEndpoint(c *gin.Context) {
-	// Simular error aleatorio
-	if simulateRandomError(c) {
+	raffleId := c.Param("id")
+
+	// Buscar la rifa por ID
+	raffle := getRaffleById(raffleId)
+	if raffle == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Raffle not found",
+			"message": fmt.Sprintf("No raffle found with ID: %s", raffleId),
+		})
 		return
 	}
 
+	// Números bendecidos persistidos del sorteo; lista vacía si aún no se ha sorteado
+	result, err := getDrawResult(raffleId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to load draw",
+			"message": "Unable to retrieve the draw results",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	blessTickets := []int{}
+	if result != nil {
+		blessTickets = append(blessTickets, result.BlessWinners...)
+	}
+
+	c.JSON(http.StatusOK, blessTickets)
+}
+
+// getDrawProofEndpoint maneja el endpoint GET /api/v1/raffles/:id/draw/proof
+func getDrawProofEndpoint(c *gin.Context) {
 	raffleId := c.Param("id")
 
 	// Buscar la rifa por ID
@@ -698,19 +859,95 @@ func getBlessNumberWinnerTicketsEndpoint(c *gin.Context) {
 		return
 	}
 
-	// Generar tickets de números de la suerte
-	blessTickets := generateBlessNumberWinnerTickets(raffle)
+	result, err := drawService.GetDraw(raffleId)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Draw not committed",
+				"message": fmt.Sprintf("The draw for raffle %s has not been committed yet", raffleId),
+			})
+			return
+		}
 
-	c.JSON(http.StatusOK, blessTickets)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to load draw",
+			"message": "Unable to retrieve the draw proof",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, draw.BuildProof(result))
 }
 
-// getPrizeByRaffleIdAndTicketIdEndpoint maneja el endpoint GET /api/v1/raffles/:id/prizes/:ticketId
-func getPrizeByRaffleIdAndTicketIdEndpoint(c *gin.Context) {
-	// Simular error aleatorio
-	if simulateRandomError(c) {
-		return
+// isTicketOwnedByDocument indica si el ticket está vendido a una reserva hecha con el documento indicado
+func isTicketOwnedByDocument(raffleId string, ticketId int, documentId string) (bool, error) {
+	ticket, err := raffleRepository.GetTicket(raffleId, ticketId)
+	if errors.Is(err, store.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if ticket.Status != store.TicketSold {
+		return false, nil
+	}
+
+	booking, err := raffleRepository.GetBooking(ticket.BookingId)
+	if errors.Is(err, store.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	bookingDocumentId, err := bookingDocument(*booking)
+	if err != nil {
+		return false, err
+	}
+
+	return bookingDocumentId != "" && sameDocument(bookingDocumentId, documentId), nil
+}
+
+// prizeLookupSettings devuelve el límite de consultas de premios por clave y la ventana configurada
+func prizeLookupSettings(perKey func(config.RateLimitConfig) int) func() (int, time.Duration) {
+	return func() (int, time.Duration) {
+		rateLimitConfig := config.GetConfig().RateLimitConfig
+		return perKey(rateLimitConfig), time.Duration(rateLimitConfig.WindowSeconds) * time.Second
+	}
+}
+
+// prizeLookupByIp y prizeLookupByDocument limitan la consulta de premios y la verificación de compras
+// para evitar la enumeración de ganadores y de compradores
+var prizeLookupByIp = middlewares.NewRateLimiter(prizeLookupSettings(func(c config.RateLimitConfig) int {
+	return c.PrizeLookupPerIp
+}))
+
+var prizeLookupByDocument = middlewares.NewRateLimiter(prizeLookupSettings(func(c config.RateLimitConfig) int {
+	return c.PrizeLookupPerDocument
+}))
+
+func clientIpKey(c *gin.Context) string {
+	return c.ClientIP()
+}
+
+func documentIdKey(c *gin.Context) string {
+	return strings.ToUpper(strings.TrimSpace(c.Query("documentId")))
+}
+
+// verifyDocumentIdKey toma el documento del body de la verificación de compras, que lo envía
+// en el JSON y no en la URL. Un body inválido no se limita aquí y el handler lo rechaza.
+func verifyDocumentIdKey(c *gin.Context) string {
+	var request RaffleVerifyRequest
+	if err := c.ShouldBindBodyWith(&request, binding.JSON); err != nil {
+		return ""
 	}
+	return strings.ToUpper(strings.TrimSpace(request.DocumentId))
+}
 
+// getPrizeByRaffleIdAndTicketIdEndpoint maneja el endpoint GET /api/v1/raffles/:id/prizes/:ticketId
+func getPrizeByRaffleIdAndTicketIdEndpoint(c *gin.Context) {
 	raffleId := c.Param("id")
 	ticketIdStr := c.Param("ticketId")
 
@@ -734,87 +971,60 @@ func getPrizeByRaffleIdAndTicketIdEndpoint(c *gin.Context) {
 		return
 	}
 
-	// Obtener premio
-	prize := getPrizeByRaffleIdAndTicketId(raffleId, ticketId)
-	if prize == nil {
+	// Verificar que la rifa existe
+	if getRaffleById(raffleId) == nil {
 		c.JSON(http.StatusNotFound, gin.H{
-			"error":   "Prize not found",
-			"message": fmt.Sprintf("No prize found for raffle %s and ticket %d", raffleId, ticketId),
+			"error":   "Raffle not found",
+			"message": fmt.Sprintf("No raffle found with ID: %s", raffleId),
 		})
 		return
 	}
 
-	c.JSON(http.StatusOK, prize)
-}
-
-// fetchBanksFromSypago consume la API de SyPago para obtener la lista de bancos
-func fetchBanksFromSypago() ([]Bank, error) {
-	url := sypagoApiBaseUrl + "/api/v1/banks"
-
-	// Obtener token de autenticación
-	authHeader, err := GetSypagoAuthHeader()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get SyPago auth token: %v", err)
-	}
-
-	// Crear cliente HTTP con timeout
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
-
-	// Crear la petición GET
-	req, err := http.NewRequest("GET", url, nil)
+	// Verificar que el ticket pertenezca a una compra confirmada del documento
+	owned, err := isTicketOwnedByDocument(raffleId, ticketId, documentId)
 	if err != nil {
-		return nil, fmt.Errorf("error creating request: %v", err)
-	}
-
-	// Configurar headers con autenticación
-	req.Header.Set("Authorization", authHeader)
-	req.Header.Set("Accept", "application/json")
-
-	// Ejecutar la petición
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("error making request to SyPago API: %v", err)
-	}
-	defer resp.Body.Close()
-
-	// Verificar status code
-	if resp.StatusCode == http.StatusUnauthorized {
-		// Token expirado, invalidar cache y reintentar una vez
-		InvalidateToken()
-		return nil, fmt.Errorf("SyPago API returned 401 Unauthorized - token may be expired")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to verify ticket",
+			"message": "Unable to verify the ticket ownership",
+			"details": err.Error(),
+		})
+		return
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("SyPago API returned status code %d: %s", resp.StatusCode, string(body))
+	if !owned {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Ticket not owned",
+			"message": "The ticket does not belong to a confirmed purchase for this document",
+		})
+		return
 	}
 
-	// Leer el body de la respuesta
-	body, err := io.ReadAll(resp.Body)
+	// Obtener el premio ganado por el ticket en el sorteo
+	prize, err := getPrizeByRaffleIdAndTicketId(raffleId, ticketId)
 	if err != nil {
-		return nil, fmt.Errorf("error reading response body: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to load prize",
+			"message": "Unable to resolve the prize for this ticket",
+			"details": err.Error(),
+		})
+		return
 	}
 
-	// Parsear JSON
-	var banks []Bank
-	if err := json.Unmarshal(body, &banks); err != nil {
-		return nil, fmt.Errorf("error parsing JSON response: %v", err)
+	if prize == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Prize not found",
+			"message": fmt.Sprintf("No prize found for raffle %s and ticket %d", raffleId, ticketId),
+		})
+		return
 	}
 
-	return banks, nil
+	c.JSON(http.StatusOK, prize)
 }
 
 // getSypagoBanks maneja el endpoint GET /sypago/banks
 func getSypagoBanks(c *gin.Context) {
-	// Simular error aleatorio
-	if simulateRandomError(c) {
-		return
-	}
-
-	// Obtener bancos desde SyPago API
-	banks, err := fetchBanksFromSypago()
+	// Obtener bancos desde la cache, que se renueva desde SyPago API
+	banks, err := bankCatalog.Banks(c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to fetch banks",
@@ -829,8 +1039,12 @@ func getSypagoBanks(c *gin.Context) {
 	for _, bank := range banks {
 		if bank.IsDebitOTP {
 			filteredBanks = append(filteredBanks, BankResponse{
-				Code: bank.Code,
-				Name: bank.Name,
+				Code:          bank.Code,
+				Name:          bank.Name,
+				VerifyType:    bank.VerifyType,
+				IsSmsOtp:      bank.IsSmsOtp,
+				SmsOtpAddress: bank.SmsOtpAddress,
+				SmsOtpText:    bank.SmsOtpText,
 			})
 		}
 	}
@@ -838,14 +1052,53 @@ func getSypagoBanks(c *gin.Context) {
 	c.JSON(http.StatusOK, filteredBanks)
 }
 
-// requestOtpEndpoint maneja el endpoint POST /api/v1/sypago/request-otp
-func requestOtpEndpoint(c *gin.Context) {
-	// Simular error aleatorio
-	if simulateRandomError(c) {
+// BookingQuoteResponse representa el monto a pagar por una reserva en la moneda solicitada
+type BookingQuoteResponse struct {
+	BookingId string          `json:"bookingId"`
+	Amount    decimal.Decimal `json:"amount"`
+	Currency  string          `json:"currency"`
+	Rate      decimal.Decimal `json:"rate"`
+	RateDate  string          `json:"rateDate,omitempty"`
+	ExpiresAt *time.Time      `json:"expiresAt,omitempty"` // vacío si no hay conversión de moneda
+}
+
+// getBookingQuoteEndpoint maneja el endpoint GET /api/v1/bookings/:id/quote?currency=VES
+func getBookingQuoteEndpoint(c *gin.Context) {
+	currency := strings.ToUpper(c.Query("currency"))
+
+	validCurrencies := map[string]bool{"VES": true, "USD": true}
+	if !validCurrencies[currency] {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"message": fmt.Sprintf("invalid currency: %s (valid: VES, USD)", currency),
+		})
 		return
 	}
 
-	var data DebitRequestOtpData
+	current, err := bookingService.Quote(c.Param("id"), currency)
+	if err != nil {
+		respondBookingError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, BookingQuoteResponse{
+		BookingId: current.ID,
+		Amount:    current.Amount,
+		Currency:  current.Currency,
+		Rate:      current.Rate,
+		RateDate:  current.RateDate,
+		ExpiresAt: current.QuoteExpiresAt,
+	})
+}
+
+// getSypagoRejectCodes maneja el endpoint GET /sypago/reject_codes
+func getSypagoRejectCodes(c *gin.Context) {
+	c.JSON(http.StatusOK, rejectCatalog.Codes())
+}
+
+// requestOtpEndpoint maneja el endpoint POST /api/v1/sypago/request-otp
+func requestOtpEndpoint(c *gin.Context) {
+	var data payment.DebitRequestOtpData
 
 	// Parsear el JSON del request
 	if err := c.ShouldBindJSON(&data); err != nil {
@@ -858,7 +1111,7 @@ func requestOtpEndpoint(c *gin.Context) {
 	}
 
 	// Validar los datos
-	if err := ValidateDebitRequestData(data); err != nil {
+	if err := payment.ValidateDebitRequestData(data); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "Invalid request data",
 			"message": err.Error(),
@@ -866,29 +1119,85 @@ func requestOtpEndpoint(c *gin.Context) {
 		return
 	}
 
-	// Llamar al servicio de SyPago
-	otpResponse, err := RequestOtp(data)
+	// La rifa de la reserva debe aceptar el débito de SyPago
+	if _, ok := selectPaymentProvider(c, data.BookingId, payment.SypagoDebit); !ok {
+		return
+	}
+
+	// Verificar el monto contra la reserva, registrar la solicitud del OTP y llamar al servicio de SyPago
+	_, err := sypagoDebit.RequestOtp(c.Request.Context(), data)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to request OTP",
-			"message": "Unable to process OTP request with SyPago",
-			"details": err.Error(),
-		})
+		if errors.Is(err, payment.ErrProviderFailed) {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to request OTP",
+				"message": "Unable to process OTP request with SyPago",
+				"details": err.Error(),
+			})
+			return
+		}
+		respondBookingError(c, err)
 		return
 	}
 
 	// Responder con la respuesta de SyPago
-	c.JSON(http.StatusOK, otpResponse)
+	c.JSON(http.StatusOK, RequestOtpResponse{
+		Success: true,
+		Message: "OTP request processed successfully",
+		Code:    200,
+	})
 }
 
-// transactionOtpEndpoint maneja el endpoint POST /api/v1/sypago/transaction-otp
-func transactionOtpEndpoint(c *gin.Context) {
-	// Simular error aleatorio
-	if simulateRandomError(c) {
-		return
+// respondBookingError traduce los errores del ciclo de vida de la reserva a respuestas HTTP
+func respondBookingError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Booking not found",
+			"message": "No booking found with the given ID",
+		})
+	case errors.Is(err, booking.ErrMismatch):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"message": err.Error(),
+		})
+	case errors.Is(err, booking.ErrExpired):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Booking expired",
+			"message": "The ticket reservation expired, please reserve again",
+		})
+	case errors.Is(err, booking.ErrReferenceUsed):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Payment reference already used",
+			"message": err.Error(),
+		})
+	case errors.Is(err, booking.ErrTicketsLost):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Tickets no longer available",
+			"message": "Some of the reserved tickets were taken, please reserve again",
+			"details": err.Error(),
+		})
+	case errors.Is(err, exchange.ErrNoRate):
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Exchange rate unavailable",
+			"message": err.Error(),
+		})
+	case errors.Is(err, booking.ErrInvalidTransition):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Invalid booking status",
+			"message": err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal Server Error",
+			"message": "Unable to process the booking",
+			"details": err.Error(),
+		})
 	}
+}
 
-	var data TransactionOtpData
+// transactionOtpEndpoint maneja el endpoint POST /api/v1/sypago/transaction-otp
+func transactionOtpEndpoint(c *gin.Context) {
+	var data payment.TransactionOtpData
 
 	// Parsear el JSON del request
 	if err := c.ShouldBindJSON(&data); err != nil {
@@ -901,7 +1210,7 @@ func transactionOtpEndpoint(c *gin.Context) {
 	}
 
 	// Validar los datos
-	if err := ValidateTransactionOtpData(data); err != nil {
+	if err := payment.ValidateTransactionOtpData(data); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "Invalid request data",
 			"message": err.Error(),
@@ -909,31 +1218,48 @@ func transactionOtpEndpoint(c *gin.Context) {
 		return
 	}
 
-	// Guardar mapeo de bookingId -> raffleId para poder generar números bendecidos después
-	SaveBookingRaffleMapping(data.BookingId, data.RaffleId)
+	// La rifa de la reserva debe aceptar el débito de SyPago
+	if _, ok := selectPaymentProvider(c, data.BookingId, payment.SypagoDebit); !ok {
+		return
+	}
 
-	// Llamar al servicio de SyPago
-	transactionResponse, err := TransactionOtp(data)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to process transaction",
-			"message": "Unable to process transaction with SyPago",
-			"details": err.Error(),
+	// Verificar que la reserva pueda pagarse y corresponda a los datos recibidos, llamar al
+	// servicio de SyPago y registrar la transacción en la reserva
+	_, transactionResponse, err := sypagoDebit.SubmitDebit(c.Request.Context(), data)
+	if errors.Is(err, payment.ErrPaymentPending) {
+		// SyPago aceptó el débito: el comprador no debe reintentarlo, solo esperar el resultado
+		c.JSON(http.StatusAccepted, TransactionOtpResponse{
+			Success:       true,
+			Message:       "Transaction submitted, confirmation pending",
+			Code:          http.StatusAccepted,
+			TransactionId: transactionResponse.TransactionId,
 		})
 		return
 	}
+	if err != nil {
+		if errors.Is(err, payment.ErrProviderFailed) {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to process transaction",
+				"message": "Unable to process transaction with SyPago",
+				"details": err.Error(),
+			})
+			return
+		}
+		respondBookingError(c, err)
+		return
+	}
 
-	// Responder con la respuesta de SyPago
-	c.JSON(http.StatusOK, transactionResponse)
+	// Responder con la respuesta de SyPago; el operation_secret no sale del servidor
+	c.JSON(http.StatusOK, TransactionOtpResponse{
+		Success:       true,
+		Message:       "Transaction processed successfully",
+		Code:          200,
+		TransactionId: transactionResponse.TransactionId,
+	})
 }
 
 // transactionStatusEndpoint maneja el endpoint GET /api/v1/sypago/debit/transaction/status
 func transactionStatusEndpoint(c *gin.Context) {
-	// Simular error aleatorio
-	if simulateRandomError(c) {
-		return
-	}
-
 	// Obtener parámetros de query
 	transactionId := c.Query("transaction_id")
 	bookingId := c.Query("booking_id")
@@ -955,12 +1281,15 @@ func transactionStatusEndpoint(c *gin.Context) {
 		return
 	}
 
-	// Para compatibilidad con SyPago, usamos el transactionId como operation_secret
-	// En un escenario real, esto vendría del frontend o se almacenaría
-	operationSecret := transactionId // Simplificación para el mock
-
-	// Llamar al servicio de consulta de estado
-	statusResponse, err := GetTransactionStatus(transactionId, operationSecret, bookingId)
+	// Llamar al servicio de consulta de estado; el operation_secret se toma de la reserva
+	statusResponse, err := GetTransactionStatus(c.Request.Context(), transactionId, bookingId)
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Transaction not found",
+			"message": fmt.Sprintf("No transaction %s found for booking %s", transactionId, bookingId),
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to get transaction status",
@@ -976,22 +1305,69 @@ func transactionStatusEndpoint(c *gin.Context) {
 
 // setupCORS configura CORS para permitir conexiones desde múltiples orígenes
 
-func ActivateRoutesForMock(r *gin.Engine) {
+// Services agrupa las dependencias que usan los handlers del mock
+type Services struct {
+	Repository   store.RaffleRepository
+	Reservations *reservation.Engine
+	Draws        *draw.Service
+	Bookings     *booking.Service
+	SypagoDebit  *payment.Sypago
+	Banks        *sypago.BankCatalog
+	RejectCodes  *sypago.RejectCatalog
+	Payments     *payment.Registry
+	Receipts     *payment.ReceiptStore
+	Refunds      *refund.Service
+}
+
+func ActivateRoutesForMock(r *gin.Engine, services Services) {
+
+	raffleRepository = services.Repository
+	reservationEngine = services.Reservations
+	drawService = services.Draws
+	bookingService = services.Bookings
+	sypagoDebit = services.SypagoDebit
+
+	// Las operaciones que reservan tickets o mueven dinero aceptan Idempotency-Key
+	idempotency := middlewares.NewIdempotencyGuard(services.Repository)
+	bankCatalog = services.Banks
+	rejectCatalog = services.RejectCodes
+	paymentRegistry = services.Payments
+	paymentReceipts = services.Receipts
+	refundService = services.Refunds
 
 	r.GET("api/v1/raffles", getRaffles)
 
 	r.GET("api/v1/raffles/:id/tickets/sold", getSoldTickets)
-	r.POST("api/v1/raffles/participant", reserveTickets)
+	r.POST("api/v1/raffles/participant", middlewares.Idempotent(idempotency, ""), reserveTickets)
 
-	r.POST("api/v1/raffles/verify", verifyRaffleEndpoint)
+	r.POST("api/v1/raffles/verify",
+		middlewares.RateLimit(prizeLookupByIp, clientIpKey),
+		middlewares.RateLimit(prizeLookupByDocument, verifyDocumentIdKey),
+		verifyRaffleEndpoint)
 	r.GET("api/v1/raffles/:id/winners/main", getMainWinnerTicketsEndpoint)
 	r.GET("api/v1/raffles/:id/winners/bless", getBlessNumberWinnerTicketsEndpoint)
-	r.GET("api/v1/raffles/:id/prizes/:ticketId", getPrizeByRaffleIdAndTicketIdEndpoint)
+	r.GET("api/v1/raffles/:id/draw/proof", getDrawProofEndpoint)
+	r.GET("api/v1/raffles/:id/prizes", getRafflePrizesEndpoint)
+	r.GET("api/v1/raffles/:id/prizes/:ticketId",
+		middlewares.RateLimit(prizeLookupByIp, clientIpKey),
+		middlewares.RateLimit(prizeLookupByDocument, documentIdKey),
+		getPrizeByRaffleIdAndTicketIdEndpoint)
+
+	r.GET("api/v1/bookings/:id/quote", getBookingQuoteEndpoint)
+	r.POST("api/v1/bookings/:id/payment-proof", submitPaymentProofEndpoint)
+
+	r.GET("api/v1/payments/providers", getPaymentProvidersEndpoint)
+	r.POST("api/v1/payments/:provider/initiate", middlewares.Idempotent(idempotency, "booking_id"), initiatePaymentEndpoint)
+	r.POST("api/v1/payments/:provider/confirm", middlewares.Idempotent(idempotency, "booking_id"), confirmPaymentEndpoint)
+	r.GET("api/v1/payments/:provider/status", paymentStatusEndpoint)
 
 	r.GET("api/v1/sypago/banks", getSypagoBanks)
+	r.GET("api/v1/sypago/reject_codes", getSypagoRejectCodes)
 
-	r.POST("api/v1/sypago/debit/request-otp", requestOtpEndpoint)
-	r.POST("api/v1/sypago/debit/transaction-otp", transactionOtpEndpoint)
+	r.POST("api/v1/sypago/debit/request-otp", middlewares.Idempotent(idempotency, "booking_id"), requestOtpEndpoint)
+	r.POST("api/v1/sypago/debit/transaction-otp", middlewares.Idempotent(idempotency, "booking_id"), transactionOtpEndpoint)
 	r.GET("api/v1/sypago/debit/transaction/status", transactionStatusEndpoint)
 
+	r.POST("api/v1/sypago/webhook", middlewares.RequireWebhookAuth(webhookSecret), sypagoWebhookEndpoint)
+
 }
@@ -0,0 +1,394 @@
+package mock
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"raffle_web_server/config"
+	"raffle_web_server/payment"
+	"raffle_web_server/store"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+)
+
+// paymentRegistry agrupa los proveedores de pago disponibles
+var paymentRegistry *payment.Registry
+
+// paymentReceipts guarda las imágenes de los comprobantes de pagos manuales
+var paymentReceipts *payment.ReceiptStore
+
+// PaymentProvidersResponse representa los proveedores de pago que acepta una rifa
+type PaymentProvidersResponse struct {
+	RaffleId  string   `json:"raffleId"`
+	Providers []string `json:"providers"`
+}
+
+// paymentRequest contiene los campos comunes a todos los proveedores; el resto del body lo
+// interpreta el proveedor
+type paymentRequest struct {
+	BookingId string `json:"booking_id"`
+}
+
+// respondPaymentError traduce los errores de los proveedores de pago a respuestas HTTP
+func respondPaymentError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, payment.ErrUnknownProvider):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Payment provider not found",
+			"message": err.Error(),
+		})
+	case errors.Is(err, payment.ErrProviderDisabled):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Payment provider not enabled",
+			"message": err.Error(),
+		})
+	case errors.Is(err, payment.ErrInvalidPayload):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"message": err.Error(),
+		})
+	case errors.Is(err, payment.ErrNotSupported):
+		c.JSON(http.StatusNotImplemented, gin.H{
+			"error":   "Operation not supported",
+			"message": err.Error(),
+		})
+	case errors.Is(err, payment.ErrReferenceNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Payment reference not found",
+			"message": "The reference was not found in the received payments, check it or try again in a few minutes",
+			"details": err.Error(),
+		})
+	case errors.Is(err, payment.ErrPaymentPending):
+		c.JSON(http.StatusAccepted, gin.H{
+			"status":  "pending",
+			"message": "The payment was submitted and is waiting for confirmation",
+			"details": err.Error(),
+		})
+	case errors.Is(err, payment.ErrProviderFailed):
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Payment provider error",
+			"message": "Unable to process the payment with the provider",
+			"details": err.Error(),
+		})
+	default:
+		respondBookingError(c, err)
+	}
+}
+
+// allowedProvider obtiene la reserva y verifica que su rifa acepte el proveedor indicado
+func allowedProvider(bookingId, name string) (payment.Provider, *store.Booking, error) {
+	provider, err := paymentRegistry.Get(name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	current, err := bookingService.Get(bookingId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	raffle, err := raffleRepository.GetRaffle(current.RaffleId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := paymentRegistry.Allows(*raffle, name); err != nil {
+		return nil, nil, err
+	}
+
+	return provider, current, nil
+}
+
+// selectPaymentProvider verifica que la rifa de la reserva acepte el proveedor y lo registra en
+// la reserva. Si no es posible responde el error y devuelve false.
+func selectPaymentProvider(c *gin.Context, bookingId, name string) (payment.Provider, bool) {
+	provider, _, err := allowedProvider(bookingId, name)
+	if err == nil {
+		_, err = bookingService.SelectProvider(bookingId, name)
+	}
+
+	if err != nil {
+		respondPaymentError(c, err)
+		return nil, false
+	}
+
+	return provider, true
+}
+
+// getPaymentProvidersEndpoint maneja el endpoint GET /api/v1/payments/providers?raffle_id=X
+func getPaymentProvidersEndpoint(c *gin.Context) {
+	raffleId := c.Query("raffle_id")
+
+	if raffleId == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Missing required parameter",
+			"message": "raffle_id is required",
+		})
+		return
+	}
+
+	raffle, err := raffleRepository.GetRaffle(raffleId)
+	if err != nil || !raffle.Status.IsPublic() {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Raffle not found",
+			"message": "No raffle found with the given ID",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, PaymentProvidersResponse{
+		RaffleId:  raffle.ID,
+		Providers: paymentRegistry.Enabled(*raffle),
+	})
+}
+
+// runPaymentStep procesa los pasos de pago que reciben datos del proveedor en el body
+func runPaymentStep(c *gin.Context, step func(provider payment.Provider, current *store.Booking, payload json.RawMessage) (*payment.Outcome, error)) {
+	payload, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"message": "Please check your request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	var request paymentRequest
+	if err := json.Unmarshal(payload, &request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"message": "Please check your request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if request.BookingId == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"message": "booking ID is required",
+		})
+		return
+	}
+
+	provider, ok := selectPaymentProvider(c, request.BookingId, c.Param("provider"))
+	if !ok {
+		return
+	}
+
+	current, err := bookingService.Get(request.BookingId)
+	if err != nil {
+		respondBookingError(c, err)
+		return
+	}
+
+	outcome, err := step(provider, current, payload)
+	if err != nil {
+		respondPaymentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, outcome)
+}
+
+// initiatePaymentEndpoint maneja el endpoint POST /api/v1/payments/:provider/initiate
+func initiatePaymentEndpoint(c *gin.Context) {
+	runPaymentStep(c, func(provider payment.Provider, current *store.Booking, payload json.RawMessage) (*payment.Outcome, error) {
+		return provider.Initiate(c.Request.Context(), current, payload)
+	})
+}
+
+// confirmPaymentEndpoint maneja el endpoint POST /api/v1/payments/:provider/confirm
+func confirmPaymentEndpoint(c *gin.Context) {
+	runPaymentStep(c, func(provider payment.Provider, current *store.Booking, payload json.RawMessage) (*payment.Outcome, error) {
+		return provider.Confirm(c.Request.Context(), current, payload)
+	})
+}
+
+// paymentStatusEndpoint maneja el endpoint GET /api/v1/payments/:provider/status?booking_id=X.
+// Mientras el pago está enviado o en revisión se consulta al proveedor y se avanza la reserva; en
+// los demás estados se responde con lo registrado en la reserva.
+func paymentStatusEndpoint(c *gin.Context) {
+	bookingId := c.Query("booking_id")
+	name := c.Param("provider")
+
+	if bookingId == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Missing required parameter",
+			"message": "booking_id is required",
+		})
+		return
+	}
+
+	provider, current, err := allowedProvider(bookingId, name)
+	if err != nil {
+		respondPaymentError(c, err)
+		return
+	}
+
+	if payment.ProviderOf(*current) != name {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Invalid booking status",
+			"message": "The booking is not being paid with " + name,
+		})
+		return
+	}
+
+	outcome := payment.Outcome{Provider: name}
+
+	if current.Status == store.BookingDebitSubmitted || current.Status == store.BookingPendingReview {
+		result, err := provider.Status(c.Request.Context(), current)
+		if err != nil {
+			respondPaymentError(c, err)
+			return
+		}
+
+		current, err = bookingService.ApplyPaymentResult(current.ID, result)
+		if err != nil {
+			respondBookingError(c, err)
+			return
+		}
+
+		outcome.PaymentStatus = result.Status
+		outcome.Message = result.Reason
+	}
+
+	outcome.BookingId = current.ID
+	outcome.BookingStatus = current.Status
+	outcome.TransactionId = current.TransactionId
+	outcome.Reference = current.RefIbp
+	outcome.RejectCode = current.RejectedCode
+	if outcome.Message == "" {
+		outcome.Message = string(current.Status)
+	}
+
+	if current.Status == store.BookingPaid {
+		blessNumbers, err := getBookingBlessNumbers(current)
+		if err != nil {
+			respondBookingError(c, err)
+			return
+		}
+		outcome.BlessNumbers = blessNumbers
+	}
+
+	c.JSON(http.StatusOK, outcome)
+}
+
+// validatePaymentProof valida los campos del comprobante de un pago manual
+func validatePaymentProof(reference, bank, currency, amount string) (decimal.Decimal, error) {
+	if reference == "" {
+		return decimal.Zero, fmt.Errorf("payment reference is required")
+	}
+
+	if bank == "" {
+		return decimal.Zero, fmt.Errorf("bank is required")
+	}
+
+	validCurrencies := map[string]bool{"VES": true, "USD": true}
+	if !validCurrencies[currency] {
+		return decimal.Zero, fmt.Errorf("invalid currency: %s (valid: VES, USD)", currency)
+	}
+
+	value, err := decimal.NewFromString(amount)
+	if err != nil || !value.IsPositive() {
+		return decimal.Zero, fmt.Errorf("amount must be greater than 0")
+	}
+
+	return value, nil
+}
+
+// submitPaymentProofEndpoint maneja el endpoint POST /api/v1/bookings/:id/payment-proof.
+// Recibe un formulario multipart con reference, amount, currency, bank y la imagen receipt.
+func submitPaymentProofEndpoint(c *gin.Context) {
+	bookingId := c.Param("id")
+	maxBytes := config.GetConfig().PaymentConfig.MaxReceiptSize()
+
+	// Se admite algo más que la imagen para los demás campos del formulario
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes+64<<10)
+
+	reference := strings.TrimSpace(c.PostForm("reference"))
+	bank := strings.TrimSpace(c.PostForm("bank"))
+	currency := strings.ToUpper(strings.TrimSpace(c.DefaultPostForm("currency", "VES")))
+
+	amount, err := validatePaymentProof(reference, bank, currency, strings.TrimSpace(c.PostForm("amount")))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	file, err := c.FormFile("receipt")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"message": "receipt image is required",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// La rifa de la reserva debe aceptar pagos manuales
+	if _, ok := selectPaymentProvider(c, bookingId, payment.ManualTransfer); !ok {
+		return
+	}
+
+	content, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"message": "Unable to read the receipt image",
+			"details": err.Error(),
+		})
+		return
+	}
+	defer content.Close()
+
+	receiptFile, receiptType, err := paymentReceipts.Save(bookingId, content, maxBytes)
+	if errors.Is(err, payment.ErrInvalidReceipt) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid receipt",
+			"message": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to save receipt",
+			"message": "Unable to store the receipt image",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	current, err := bookingService.SubmitProof(bookingId, store.PaymentProof{
+		Reference:   reference,
+		Amount:      amount,
+		Currency:    currency,
+		Bank:        bank,
+		ReceiptFile: receiptFile,
+		ReceiptType: receiptType,
+	})
+	if err != nil {
+		// El comprobante no quedó asociado a la reserva
+		if path, pathErr := paymentReceipts.Path(receiptFile); pathErr == nil {
+			os.Remove(path)
+		}
+		respondBookingError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, payment.Outcome{
+		Provider:      payment.ManualTransfer,
+		BookingId:     current.ID,
+		BookingStatus: current.Status,
+		Reference:     current.Proof.Reference,
+		Message:       "Payment proof received, pending review",
+	})
+}
@@ -0,0 +1,189 @@
+package mock
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Formatos soportados por GET /api/v1/raffles/:id/tickets/sold
+const (
+	soldTicketsFormatList   = "list"
+	soldTicketsFormatBitset = "bitset"
+	soldTicketsFormatRanges = "ranges"
+)
+
+// Media types que se aceptan en el header Accept para negociar el formato compacto
+const (
+	mediaTypeTicketsBitset = "application/vnd.raffle.tickets.bitset+json"
+	mediaTypeTicketsRanges = "application/vnd.raffle.tickets.ranges+json"
+)
+
+// SoldTicketsBitset representa los tickets ocupados como bitsets en base64.
+// El bit i (byte i/8, bit menos significativo primero) corresponde al ticket InitialTicket+i.
+type SoldTicketsBitset struct {
+	RaffleId      RaffleId `json:"raffleId"`
+	Format        string   `json:"format"`
+	InitialTicket int      `json:"initialTicket"`
+	TicketsTotal  int      `json:"ticketsTotal"`
+	Sold          string   `json:"sold"`
+	Reserved      string   `json:"reserved"`
+}
+
+// SoldTicketsRanges representa los tickets ocupados como rangos [offset, longitud]
+// relativos a InitialTicket.
+type SoldTicketsRanges struct {
+	RaffleId      RaffleId `json:"raffleId"`
+	Format        string   `json:"format"`
+	InitialTicket int      `json:"initialTicket"`
+	TicketsTotal  int      `json:"ticketsTotal"`
+	Sold          [][2]int `json:"sold"`
+	Reserved      [][2]int `json:"reserved"`
+}
+
+// negotiateSoldTicketsFormat elige el formato por ?format= o, en su defecto, por el header Accept
+func negotiateSoldTicketsFormat(c *gin.Context) string {
+	switch strings.ToLower(c.Query("format")) {
+	case soldTicketsFormatBitset:
+		return soldTicketsFormatBitset
+	case soldTicketsFormatRanges:
+		return soldTicketsFormatRanges
+	case soldTicketsFormatList:
+		return soldTicketsFormatList
+	}
+
+	accept := c.GetHeader("Accept")
+	switch {
+	case strings.Contains(accept, mediaTypeTicketsBitset):
+		return soldTicketsFormatBitset
+	case strings.Contains(accept, mediaTypeTicketsRanges):
+		return soldTicketsFormatRanges
+	}
+
+	return soldTicketsFormatList
+}
+
+// soldTicketsETag calcula un ETag a partir del formato y del estado de los tickets ocupados
+func soldTicketsETag(format string, raffle *RaffleSummary, tickets []RaffleTicket) string {
+	hash := sha256.New()
+	hash.Write([]byte(format))
+	hash.Write([]byte(raffle.ID))
+
+	buf := make([]byte, 8)
+	for _, value := range []int{raffle.InitialTicket, raffle.TicketsTotal} {
+		binary.BigEndian.PutUint64(buf, uint64(value))
+		hash.Write(buf)
+	}
+
+	for _, ticket := range tickets {
+		binary.BigEndian.PutUint64(buf, uint64(ticket.Number))
+		hash.Write(buf)
+		hash.Write([]byte(ticket.Status))
+	}
+
+	return `"` + hex.EncodeToString(hash.Sum(nil)[:16]) + `"`
+}
+
+// encodeTicketsBitset construye el bitset de los tickets con el estado indicado
+func encodeTicketsBitset(raffle *RaffleSummary, tickets []RaffleTicket, status RaffleTicketStatus) string {
+	bits := make([]byte, (raffle.TicketsTotal+7)/8)
+
+	for _, ticket := range tickets {
+		if ticket.Status != status {
+			continue
+		}
+		offset := ticket.Number - raffle.InitialTicket
+		if offset < 0 || offset >= raffle.TicketsTotal {
+			continue
+		}
+		bits[offset/8] |= 1 << (offset % 8)
+	}
+
+	return base64.StdEncoding.EncodeToString(bits)
+}
+
+// encodeTicketsRanges agrupa los tickets con el estado indicado en rangos consecutivos.
+// Los tickets deben venir ordenados por número.
+func encodeTicketsRanges(raffle *RaffleSummary, tickets []RaffleTicket, status RaffleTicketStatus) [][2]int {
+	ranges := make([][2]int, 0)
+
+	for _, ticket := range tickets {
+		if ticket.Status != status {
+			continue
+		}
+		offset := ticket.Number - raffle.InitialTicket
+		if offset < 0 || offset >= raffle.TicketsTotal {
+			continue
+		}
+
+		last := len(ranges) - 1
+		if last >= 0 && ranges[last][0]+ranges[last][1] == offset {
+			ranges[last][1]++
+			continue
+		}
+		ranges = append(ranges, [2]int{offset, 1})
+	}
+
+	return ranges
+}
+
+// etagMatches indica si el header If-None-Match coincide con el ETag. Acepta "*" y listas
+// separadas por comas, y compara de forma débil (ignorando el prefijo W/) como pide RFC 9110.
+func etagMatches(ifNoneMatch, etag string) bool {
+	if strings.TrimSpace(ifNoneMatch) == "*" {
+		return true
+	}
+
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// writeSoldTickets responde con los tickets ocupados en el formato negociado,
+// incluyendo ETag para que el cliente pueda consultar con If-None-Match
+func writeSoldTickets(c *gin.Context, raffle *RaffleSummary, tickets []RaffleTicket) {
+	format := negotiateSoldTicketsFormat(c)
+	etag := soldTicketsETag(format, raffle, tickets)
+
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Vary", "Accept")
+
+	if match := c.GetHeader("If-None-Match"); match != "" && etagMatches(match, etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	switch format {
+	case soldTicketsFormatBitset:
+		c.JSON(http.StatusOK, SoldTicketsBitset{
+			RaffleId:      raffle.ID,
+			Format:        soldTicketsFormatBitset,
+			InitialTicket: raffle.InitialTicket,
+			TicketsTotal:  raffle.TicketsTotal,
+			Sold:          encodeTicketsBitset(raffle, tickets, TicketStatusSold),
+			Reserved:      encodeTicketsBitset(raffle, tickets, TicketStatusReserved),
+		})
+	case soldTicketsFormatRanges:
+		c.JSON(http.StatusOK, SoldTicketsRanges{
+			RaffleId:      raffle.ID,
+			Format:        soldTicketsFormatRanges,
+			InitialTicket: raffle.InitialTicket,
+			TicketsTotal:  raffle.TicketsTotal,
+			Sold:          encodeTicketsRanges(raffle, tickets, TicketStatusSold),
+			Reserved:      encodeTicketsRanges(raffle, tickets, TicketStatusReserved),
+		})
+	default:
+		c.JSON(http.StatusOK, tickets)
+	}
+}
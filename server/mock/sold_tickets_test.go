@@ -0,0 +1,52 @@
+package mock
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEncodeTicketsRangesSkipsOutOfRange(t *testing.T) {
+	raffle := &RaffleSummary{ID: "raffle-test", InitialTicket: 10, TicketsTotal: 10}
+	tickets := []RaffleTicket{
+		{Number: 5, Status: TicketStatusSold},
+		{Number: 10, Status: TicketStatusSold},
+		{Number: 11, Status: TicketStatusSold},
+		{Number: 12, Status: TicketStatusReserved},
+		{Number: 13, Status: TicketStatusSold},
+		{Number: 19, Status: TicketStatusSold},
+		{Number: 20, Status: TicketStatusSold},
+	}
+
+	got := encodeTicketsRanges(raffle, tickets, TicketStatusSold)
+	want := [][2]int{{0, 2}, {3, 1}, {9, 1}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("sold ranges = %v, want %v", got, want)
+	}
+
+	// El bitset y los rangos deben describir los mismos tickets
+	if bitset := encodeTicketsBitset(raffle, tickets, TicketStatusSold); bitset != "CwI=" {
+		t.Fatalf("sold bitset = %s, want CwI=", bitset)
+	}
+}
+
+func TestEtagMatches(t *testing.T) {
+	const etag = `"abc"`
+
+	cases := []struct {
+		header string
+		want   bool
+	}{
+		{`"abc"`, true},
+		{`W/"abc"`, true},
+		{`"xyz", W/"abc"`, true},
+		{`*`, true},
+		{`"xyz"`, false},
+		{`"abc-gzip"`, false},
+	}
+
+	for _, tc := range cases {
+		if got := etagMatches(tc.header, etag); got != tc.want {
+			t.Errorf("etagMatches(%q) = %v, want %v", tc.header, got, tc.want)
+		}
+	}
+}
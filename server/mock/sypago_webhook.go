@@ -0,0 +1,145 @@
+package mock
+
+import (
+	"errors"
+	"net/http"
+	"raffle_web_server/config"
+	"raffle_web_server/refund"
+	"raffle_web_server/store"
+	"raffle_web_server/sypago"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SypagoWebhookResponse representa la respuesta enviada a SyPago al recibir una notificación
+type SypagoWebhookResponse struct {
+	Received  bool   `json:"received"`
+	BookingId string `json:"booking_id"`
+	Status    string `json:"status"` // estado de la reserva, o de la devolución si RefundId no está vacío
+	RefundId  string `json:"refund_id,omitempty"`
+}
+
+// refundService finaliza las devoluciones cuyos créditos notifica SyPago
+var refundService *refund.Service
+
+// WebhookEndpoint devuelve la URL de notificación registrada en SyPago. Las notificaciones se
+// autentican con headers (ver middlewares.RequireWebhookAuth), nunca con el secreto en la URL.
+func WebhookEndpoint() string {
+	return config.GetConfig().SypagoConfig.WebhookUrl
+}
+
+// webhookSecret devuelve el secreto con el que se autentican las notificaciones de SyPago
+func webhookSecret() string {
+	return config.GetConfig().SypagoConfig.WebhookSecret
+}
+
+// sypagoWebhookEndpoint maneja el endpoint POST /api/v1/sypago/webhook. La notificación solo
+// indica qué transacción cambió: el estado se consulta a SyPago con el operation_secret guardado,
+// de modo que un callback falso o alterado no puede finalizar un pago.
+func sypagoWebhookEndpoint(c *gin.Context) {
+	var notification sypago.TransactionStatus
+
+	// Parsear el JSON de la notificación
+	if err := c.ShouldBindJSON(&notification); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"message": "Please check your request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if notification.InternalId == "" && notification.TransactionId == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Missing required field",
+			"message": "internal_id or transaction_id is required",
+		})
+		return
+	}
+
+	// Buscar la reserva de la transacción notificada
+	current, err := bookingService.FindByPayment(notification.InternalId, notification.TransactionId)
+	if errors.Is(err, store.ErrNotFound) {
+		// Los créditos de las devoluciones se notifican al mismo webhook
+		if applyRefundNotification(c, notification) {
+			return
+		}
+
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Booking not found",
+			"message": "No booking found for the notified transaction",
+		})
+		return
+	}
+	if err != nil {
+		respondBookingError(c, err)
+		return
+	}
+
+	// La notificación llegó antes de registrar el débito: se pide a SyPago que la reintente
+	if current.TransactionId == "" || current.OperationSecret == "" {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Booking not ready",
+			"message": "The debit for this booking is still being registered, please retry",
+		})
+		return
+	}
+
+	result, err := sypagoDebit.Status(c.Request.Context(), current)
+	if err != nil {
+		respondPaymentError(c, err)
+		return
+	}
+
+	// Avanzar la reserva; las notificaciones repetidas no la modifican
+	current, err = bookingService.ApplyPaymentResult(current.ID, result)
+	if err != nil {
+		respondBookingError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, SypagoWebhookResponse{
+		Received:  true,
+		BookingId: current.ID,
+		Status:    string(current.Status),
+	})
+}
+
+// applyRefundNotification consulta a SyPago el crédito notificado y finaliza la devolución.
+// Devuelve false si la transacción no corresponde a ninguna devolución.
+func applyRefundNotification(c *gin.Context, notification sypago.TransactionStatus) bool {
+	current, err := refundService.FindByPayment(notification.InternalId, notification.TransactionId)
+	if errors.Is(err, store.ErrNotFound) {
+		return false
+	}
+
+	// El crédito todavía se está enviando: se pide a SyPago que reintente la notificación
+	if err == nil && (current.Status == store.RefundRequested || current.Status == store.RefundSending) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Refund not ready",
+			"message": "The credit for this refund is still being registered, please retry",
+		})
+		return true
+	}
+
+	if err == nil {
+		current, err = refundService.Refresh(c.Request.Context(), current.ID)
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal Server Error",
+			"message": "Unable to process the refund notification",
+			"details": err.Error(),
+		})
+		return true
+	}
+
+	c.JSON(http.StatusOK, SypagoWebhookResponse{
+		Received:  true,
+		BookingId: current.BookingId,
+		Status:    string(current.Status),
+		RefundId:  current.ID,
+	})
+	return true
+}
@@ -0,0 +1,51 @@
+package mock
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"raffle_web_server/middlewares"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+func TestVerifyIsLimitedPerDocumentInTheBody(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	limiter := middlewares.NewRateLimiter(func() (int, time.Duration) { return 2, time.Minute })
+
+	router := gin.New()
+	router.POST("/verify", middlewares.RateLimit(limiter, verifyDocumentIdKey), func(c *gin.Context) {
+		// El handler vuelve a leer el body que ya leyó la clave del límite
+		var request RaffleVerifyRequest
+		if err := c.ShouldBindBodyWith(&request, binding.JSON); err != nil {
+			c.Status(http.StatusBadRequest)
+			return
+		}
+		c.String(http.StatusOK, request.DocumentId)
+	})
+
+	cases := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"first lookup", `{"raffleId":"r1","documentId":"V12345678"}`, http.StatusOK},
+		{"same document in another raffle", `{"raffleId":"r2","documentId":"v12345678 "}`, http.StatusOK},
+		{"over the limit", `{"raffleId":"r3","documentId":"V12345678"}`, http.StatusTooManyRequests},
+		{"another document", `{"raffleId":"r1","documentId":"V87654321"}`, http.StatusOK},
+		{"invalid body", `{`, http.StatusBadRequest},
+	}
+
+	for _, tc := range cases {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/verify", strings.NewReader(tc.body)))
+
+		if recorder.Code != tc.want {
+			t.Errorf("%s: status = %d, want %d", tc.name, recorder.Code, tc.want)
+		}
+	}
+}
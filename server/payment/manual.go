@@ -0,0 +1,105 @@
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"raffle_web_server/booking"
+	"raffle_web_server/config"
+	"raffle_web_server/refund"
+	"raffle_web_server/store"
+)
+
+// ManualTransfer es el nombre del proveedor de transferencias y Pago Móvil verificados a mano
+const ManualTransfer = "manual_transfer"
+
+// manualInitiateRequest es el body de Initiate para los pagos manuales
+type manualInitiateRequest struct {
+	Currency string `json:"currency"`
+}
+
+// Manual cobra las reservas con transferencias o Pago Móvil que el comprador hace por su
+// cuenta: Initiate cotiza el monto y devuelve la cuenta de destino, y el comprobante se envía a
+// /api/v1/bookings/:id/payment-proof para que un administrador lo revise. Las devoluciones
+// también se hacen a mano.
+type Manual struct {
+	bookings *booking.Service
+	refunds  *refund.Service
+}
+
+func NewManual(bookings *booking.Service, refunds *refund.Service) *Manual {
+	return &Manual{bookings: bookings, refunds: refunds}
+}
+
+func (m *Manual) Name() string {
+	return ManualTransfer
+}
+
+// Initiate cotiza la reserva en la moneda indicada (VES por defecto) y devuelve los datos de la
+// cuenta a la que se debe transferir
+func (m *Manual) Initiate(ctx context.Context, current *store.Booking, payload json.RawMessage) (*Outcome, error) {
+	var request manualInitiateRequest
+	if err := json.Unmarshal(payload, &request); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPayload, err)
+	}
+
+	if request.Currency == "" {
+		request.Currency = "VES"
+	}
+
+	quoted, err := m.bookings.Quote(current.ID, request.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	account := config.GetConfig().PaymentConfig.ManualTransfer
+
+	return &Outcome{
+		Provider:      m.Name(),
+		BookingId:     quoted.ID,
+		BookingStatus: quoted.Status,
+		Message:       "Transfer the amount and submit the payment proof",
+		Details: map[string]string{
+			"amount":        quoted.Amount.StringFixed(2),
+			"currency":      quoted.Currency,
+			"bank":          account.Bank,
+			"accountNumber": account.AccountNumber,
+			"accountHolder": account.AccountHolder,
+			"documentId":    account.DocumentId,
+			"phone":         account.Phone,
+		},
+	}, nil
+}
+
+// Confirm no aplica: el comprobante incluye una imagen y se envía como multipart al endpoint
+// de comprobantes de la reserva
+func (m *Manual) Confirm(ctx context.Context, current *store.Booking, payload json.RawMessage) (*Outcome, error) {
+	return nil, fmt.Errorf("%w: submit the payment proof to /api/v1/bookings/%s/payment-proof", ErrNotSupported, current.ID)
+}
+
+// Status informa el estado de la revisión registrado en la reserva
+func (m *Manual) Status(ctx context.Context, current *store.Booking) (booking.PaymentResult, error) {
+	return reviewStatus(current)
+}
+
+// reviewStatus informa el estado de un pago verificado por el servidor o por un administrador
+func reviewStatus(current *store.Booking) (booking.PaymentResult, error) {
+	switch current.Status {
+	case store.BookingPaid:
+		return booking.PaymentResult{Status: booking.PaymentAccepted, RefIbp: current.RefIbp, Reason: "Pago aprobado"}, nil
+	case store.BookingRejected:
+		return booking.PaymentResult{Status: booking.PaymentRejected, Reason: "Pago rechazado"}, nil
+	case store.BookingPendingReview:
+		return booking.PaymentResult{Reason: "Pago en revisión"}, nil
+	}
+	return booking.PaymentResult{}, fmt.Errorf("%w: booking %s has no payment proof", booking.ErrInvalidTransition, current.ID)
+}
+
+// Refund registra la devolución como un pago manual; el back office la completa con la referencia
+// de la transferencia
+func (m *Manual) Refund(ctx context.Context, current *store.Booking, request refund.Request) (*store.Refund, error) {
+	request.BookingId = current.ID
+	request.Method = store.RefundManualPayout
+
+	return m.refunds.Request(request)
+}
@@ -0,0 +1,236 @@
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"raffle_web_server/booking"
+	"raffle_web_server/config"
+	"raffle_web_server/refund"
+	"raffle_web_server/store"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// PagoMovilReference es el nombre del proveedor de Pago Móvil verificado por referencia
+const PagoMovilReference = "pago_movil"
+
+// movementClockSkew es la diferencia tolerada entre el reloj del banco y el del servidor
+const movementClockSkew = 5 * time.Minute
+
+// Movement es un Pago Móvil recibido en la cuenta del comercio
+type Movement struct {
+	Reference string          `json:"reference"`
+	BankCode  string          `json:"bankCode"` // banco del pagador
+	Phone     string          `json:"phone"`    // teléfono del pagador
+	Amount    decimal.Decimal `json:"amount"`   // en VES
+	Date      time.Time       `json:"date"`
+}
+
+// MovementSource consulta los Pago Móvil recibidos en la cuenta del comercio. Se puede
+// reemplazar por la API del banco; el archivo exportado del banco sirve como fuente local.
+type MovementSource interface {
+	// Find devuelve el movimiento con la referencia indicada o ErrReferenceNotFound
+	Find(ctx context.Context, reference string) (*Movement, error)
+}
+
+// FileMovements lee los movimientos de un archivo JSON con una lista de Movement.
+// El archivo se lee en cada consulta para que se pueda actualizar sin reiniciar.
+type FileMovements struct {
+	Path string
+}
+
+func (m FileMovements) Find(ctx context.Context, reference string) (*Movement, error) {
+	content, err := os.ReadFile(m.Path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: error reading Pago Móvil movements: %v", ErrProviderFailed, err)
+	}
+
+	var movements []Movement
+	if err := json.Unmarshal(content, &movements); err != nil {
+		return nil, fmt.Errorf("%w: error parsing Pago Móvil movements file %s: %v", ErrProviderFailed, m.Path, err)
+	}
+
+	for _, movement := range movements {
+		if movement.Reference == reference {
+			return &movement, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrReferenceNotFound, reference)
+}
+
+// MovementsFromConfig devuelve la fuente indicada en PaymentConfig.PagoMovil, o nil si no hay
+func MovementsFromConfig() MovementSource {
+	path := config.GetConfig().PaymentConfig.PagoMovil.MovementsPath()
+	if path == "" {
+		return nil
+	}
+	return FileMovements{Path: path}
+}
+
+// PagoMovilConfirmData es el body de Confirm: los datos del Pago Móvil que hizo el comprador
+type PagoMovilConfirmData struct {
+	Reference      string `json:"reference"`
+	DocumentType   string `json:"document_letter"`
+	DocumentNumber string `json:"document"`
+	BankCode       string `json:"bank_code"`
+	Phone          string `json:"phone"`
+}
+
+// ValidatePagoMovilConfirmData valida los datos del Pago Móvil
+func ValidatePagoMovilConfirmData(data PagoMovilConfirmData) error {
+	if data.Reference == "" {
+		return fmt.Errorf("reference is required")
+	}
+
+	validDocTypes := map[string]bool{"V": true, "E": true, "J": true, "G": true}
+	if !validDocTypes[data.DocumentType] {
+		return fmt.Errorf("invalid document type: %s (valid: V, E, J, G)", data.DocumentType)
+	}
+
+	if data.DocumentNumber == "" {
+		return fmt.Errorf("document number is required")
+	}
+
+	if data.BankCode == "" {
+		return fmt.Errorf("bank code is required")
+	}
+
+	if normalizePhone(data.Phone) == "" {
+		return fmt.Errorf("phone is required")
+	}
+
+	return nil
+}
+
+// normalizePhone deja solo los dígitos del teléfono, con el prefijo local 0 en lugar del 58
+func normalizePhone(phone string) string {
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, phone)
+
+	if strings.HasPrefix(digits, "58") && len(digits) == 12 {
+		return "0" + digits[2:]
+	}
+	return digits
+}
+
+// matchMovement verifica que el movimiento sea el Pago Móvil que el comprador dice haber hecho
+// para la reserva: del mismo banco y teléfono, y no anterior a la reserva
+func matchMovement(movement *Movement, data PagoMovilConfirmData, current *store.Booking) error {
+	if movement.BankCode != data.BankCode || normalizePhone(movement.Phone) != normalizePhone(data.Phone) {
+		return fmt.Errorf("%w: reference %s was paid from another bank or phone", booking.ErrMismatch, data.Reference)
+	}
+
+	if movement.Date.Before(current.CreatedAt.Add(-movementClockSkew)) {
+		return fmt.Errorf("%w: reference %s is older than booking %s", booking.ErrMismatch, data.Reference, current.ID)
+	}
+
+	return nil
+}
+
+// PagoMovil cobra las reservas con Pago Móvil: Initiate cotiza el monto en VES y devuelve los datos
+// del comercio, y Confirm busca la referencia en los movimientos de la cuenta y aprueba el pago
+// sin revisión manual. Las devoluciones se envían con un crédito de SyPago al teléfono del pagador.
+type PagoMovil struct {
+	bookings  *booking.Service
+	refunds   *refund.Service
+	movements MovementSource
+}
+
+func NewPagoMovil(bookings *booking.Service, refunds *refund.Service, movements MovementSource) *PagoMovil {
+	return &PagoMovil{bookings: bookings, refunds: refunds, movements: movements}
+}
+
+func (p *PagoMovil) Name() string {
+	return PagoMovilReference
+}
+
+// Initiate cotiza la reserva en VES y devuelve los datos del Pago Móvil del comercio
+func (p *PagoMovil) Initiate(ctx context.Context, current *store.Booking, payload json.RawMessage) (*Outcome, error) {
+	quoted, err := p.bookings.Quote(current.ID, "VES")
+	if err != nil {
+		return nil, err
+	}
+
+	account := config.GetConfig().PaymentConfig.ManualTransfer
+
+	return &Outcome{
+		Provider:      p.Name(),
+		BookingId:     quoted.ID,
+		BookingStatus: quoted.Status,
+		Message:       "Pay with Pago Móvil and confirm the reference",
+		Details: map[string]string{
+			"amount":     quoted.Amount.StringFixed(2),
+			"currency":   quoted.Currency,
+			"bank":       account.Bank,
+			"documentId": account.DocumentId,
+			"phone":      account.Phone,
+		},
+	}, nil
+}
+
+// Confirm verifica la referencia contra los movimientos de la cuenta y, si corresponde al monto
+// cotizado, vende los tickets; el body tiene el formato de PagoMovilConfirmData
+func (p *PagoMovil) Confirm(ctx context.Context, current *store.Booking, payload json.RawMessage) (*Outcome, error) {
+	var data PagoMovilConfirmData
+	if err := json.Unmarshal(payload, &data); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPayload, err)
+	}
+
+	data.Reference = strings.TrimSpace(data.Reference)
+	if err := ValidatePagoMovilConfirmData(data); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPayload, err)
+	}
+
+	movement, err := p.movements.Find(ctx, data.Reference)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := matchMovement(movement, data, current); err != nil {
+		return nil, err
+	}
+
+	paid, err := p.bookings.ConfirmTransfer(current.ID, store.PaymentProof{
+		Reference: movement.Reference,
+		Amount:    movement.Amount,
+		Currency:  "VES",
+		Bank:      movement.BankCode,
+	}, store.PaymentAccount{
+		DocumentType:   data.DocumentType,
+		DocumentNumber: data.DocumentNumber,
+		BankCode:       data.BankCode,
+		AccountType:    "CELE",
+		AccountNumber:  normalizePhone(data.Phone),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Outcome{
+		Provider:      p.Name(),
+		BookingId:     paid.ID,
+		BookingStatus: paid.Status,
+		PaymentStatus: booking.PaymentAccepted,
+		Reference:     paid.RefIbp,
+		Message:       "Pago Móvil verified",
+	}, nil
+}
+
+// Status informa el estado registrado en la reserva: el pago se verifica al confirmarlo
+func (p *PagoMovil) Status(ctx context.Context, current *store.Booking) (booking.PaymentResult, error) {
+	return reviewStatus(current)
+}
+
+// Refund devuelve el pago con un crédito de SyPago al teléfono desde el que se pagó
+func (p *PagoMovil) Refund(ctx context.Context, current *store.Booking, request refund.Request) (*store.Refund, error) {
+	return creditRefund(ctx, p.refunds, current, request)
+}
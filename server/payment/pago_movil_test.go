@@ -0,0 +1,221 @@
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"raffle_web_server/booking"
+	"raffle_web_server/refund"
+	"raffle_web_server/store"
+	"raffle_web_server/store/storetest"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// newTestBookings devuelve el servicio de reservas sobre un almacén con la rifa "raffle-test",
+// de 10 VES por ticket
+func newTestBookings(t *testing.T) (*booking.Service, *store.FileRepository) {
+	t.Helper()
+
+	repository := storetest.NewRepository(t)
+
+	now := time.Now().UTC()
+	err := repository.SaveRaffle(store.Raffle{
+		ID:            "raffle-test",
+		Title:         "Rifa de prueba",
+		Price:         10,
+		Currency:      "VES",
+		InitialTicket: 1,
+		TicketsTotal:  100,
+		EndsAt:        now.Add(24 * time.Hour),
+		Status:        store.RafflePublished,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	})
+	if err != nil {
+		t.Fatalf("SaveRaffle: %v", err)
+	}
+
+	rates := func(from, to string) (store.ExchangeRate, error) {
+		return store.ExchangeRate{}, store.ErrNotFound
+	}
+
+	return booking.NewService(repository, storetest.NewSecretBox(t), rates), repository
+}
+
+// reserveBooking retiene los tickets y guarda la reserva con el proveedor elegido
+func reserveBooking(t *testing.T, repository *store.FileRepository, bookingId, provider string, tickets []int) *store.Booking {
+	t.Helper()
+
+	now := time.Now().UTC()
+	heldUntil := now.Add(10 * time.Minute)
+
+	conflicts, err := repository.HoldTickets("raffle-test", bookingId, tickets, heldUntil)
+	if err != nil || len(conflicts) > 0 {
+		t.Fatalf("HoldTickets(%s): conflicts %v, err %v", bookingId, conflicts, err)
+	}
+
+	current := store.Booking{
+		ID:            bookingId,
+		RaffleId:      "raffle-test",
+		ParticipantId: "P-1",
+		Tickets:       tickets,
+		Provider:      provider,
+		Status:        store.BookingReserved,
+		ExpiresAt:     heldUntil,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if err := repository.SaveBooking(current); err != nil {
+		t.Fatalf("SaveBooking(%s): %v", bookingId, err)
+	}
+
+	return &current
+}
+
+// writeMovements guarda los movimientos de Pago Móvil en un archivo temporal
+func writeMovements(t *testing.T, movements ...Movement) FileMovements {
+	t.Helper()
+
+	content, err := json.Marshal(movements)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "movements.json")
+	if err := os.WriteFile(path, content, 0600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	return FileMovements{Path: path}
+}
+
+func confirmPagoMovil(provider *PagoMovil, current *store.Booking, data PagoMovilConfirmData) (*Outcome, error) {
+	payload, _ := json.Marshal(data)
+	return provider.Confirm(context.Background(), current, payload)
+}
+
+func TestPagoMovilConfirmVerifiesTheReference(t *testing.T) {
+	bookings, repository := newTestBookings(t)
+	current := reserveBooking(t, repository, "BK-A", PagoMovilReference, []int{1, 2})
+
+	movements := writeMovements(t,
+		Movement{Reference: "000111", BankCode: "0102", Phone: "04140000000", Amount: decimal.NewFromInt(5), Date: time.Now().UTC()},
+		Movement{Reference: "000222", BankCode: "0102", Phone: "+58 414-1234567", Amount: decimal.NewFromInt(20), Date: time.Now().UTC()},
+	)
+	provider := NewPagoMovil(bookings, nil, movements)
+
+	outcome, err := confirmPagoMovil(provider, current, PagoMovilConfirmData{
+		Reference:      "000222",
+		DocumentType:   "V",
+		DocumentNumber: "12345678",
+		BankCode:       "0102",
+		Phone:          "04141234567",
+	})
+	if err != nil {
+		t.Fatalf("Confirm: %v", err)
+	}
+	if outcome.BookingStatus != store.BookingPaid || outcome.Reference != "000222" {
+		t.Fatalf("outcome = %s with reference %q, want %s with 000222", outcome.BookingStatus, outcome.Reference, store.BookingPaid)
+	}
+
+	paid, err := repository.GetBooking("BK-A")
+	if err != nil {
+		t.Fatalf("GetBooking: %v", err)
+	}
+	if paid.Payer == nil || paid.Payer.AccountNumber != "04141234567" || paid.Payer.AccountType != "CELE" {
+		t.Fatalf("payer = %+v, want the Pago Móvil phone", paid.Payer)
+	}
+	if paid.Proof == nil || paid.Proof.ReviewedAt == nil || !paid.Proof.Amount.Equal(decimal.NewFromInt(20)) {
+		t.Fatalf("proof = %+v, want a reviewed proof of 20 VES", paid.Proof)
+	}
+
+	ticket, err := repository.GetTicket("raffle-test", 2)
+	if err != nil {
+		t.Fatalf("GetTicket: %v", err)
+	}
+	if ticket.Status != store.TicketSold || ticket.BookingId != "BK-A" {
+		t.Fatalf("ticket 2 = %s by %s, want sold to BK-A", ticket.Status, ticket.BookingId)
+	}
+}
+
+func TestPagoMovilConfirmRejectsUnverifiedReferences(t *testing.T) {
+	bookings, repository := newTestBookings(t)
+	now := time.Now().UTC()
+
+	movements := writeMovements(t,
+		Movement{Reference: "000222", BankCode: "0102", Phone: "04141234567", Amount: decimal.NewFromInt(20), Date: now},
+		Movement{Reference: "000333", BankCode: "0102", Phone: "04141234567", Amount: decimal.NewFromInt(15), Date: now},
+		Movement{Reference: "000444", BankCode: "0102", Phone: "04141234567", Amount: decimal.NewFromInt(20), Date: now.Add(-24 * time.Hour)},
+	)
+	provider := NewPagoMovil(bookings, nil, movements)
+
+	first := reserveBooking(t, repository, "BK-A", PagoMovilReference, []int{1, 2})
+	second := reserveBooking(t, repository, "BK-B", PagoMovilReference, []int{3, 4})
+
+	data := PagoMovilConfirmData{Reference: "000222", DocumentType: "V", DocumentNumber: "12345678", BankCode: "0102", Phone: "04141234567"}
+	if _, err := confirmPagoMovil(provider, first, data); err != nil {
+		t.Fatalf("Confirm: %v", err)
+	}
+
+	cases := []struct {
+		name   string
+		change func(data *PagoMovilConfirmData)
+		want   error
+	}{
+		{"reference already used", func(data *PagoMovilConfirmData) {}, booking.ErrReferenceUsed},
+		{"unknown reference", func(data *PagoMovilConfirmData) { data.Reference = "999999" }, ErrReferenceNotFound},
+		{"another phone", func(data *PagoMovilConfirmData) { data.Phone = "04240000000" }, booking.ErrMismatch},
+		{"another bank", func(data *PagoMovilConfirmData) { data.BankCode = "0105" }, booking.ErrMismatch},
+		{"another amount", func(data *PagoMovilConfirmData) { data.Reference = "000333" }, booking.ErrMismatch},
+		{"older than the booking", func(data *PagoMovilConfirmData) { data.Reference = "000444" }, booking.ErrMismatch},
+		{"missing document", func(data *PagoMovilConfirmData) { data.DocumentNumber = "" }, ErrInvalidPayload},
+	}
+
+	for _, c := range cases {
+		attempt := data
+		c.change(&attempt)
+
+		if _, err := confirmPagoMovil(provider, second, attempt); !errors.Is(err, c.want) {
+			t.Errorf("%s: error = %v, want %v", c.name, err, c.want)
+		}
+	}
+
+	unpaid, err := repository.GetBooking("BK-B")
+	if err != nil {
+		t.Fatalf("GetBooking: %v", err)
+	}
+	if unpaid.Status != store.BookingReserved {
+		t.Fatalf("BK-B status = %s, want %s", unpaid.Status, store.BookingReserved)
+	}
+}
+
+func TestManualRefundIsAManualPayout(t *testing.T) {
+	bookings, repository := newTestBookings(t)
+	refunds := refund.NewService(repository, storetest.NewSecretBox(t), nil, func() string { return "" })
+
+	current := reserveBooking(t, repository, "BK-A", ManualTransfer, []int{1})
+	current.Status = store.BookingPaid
+	current.Amount = decimal.NewFromInt(10)
+	current.Currency = "VES"
+	if err := repository.SaveBooking(*current); err != nil {
+		t.Fatalf("SaveBooking: %v", err)
+	}
+
+	registry := NewRegistry(NewManual(bookings, refunds))
+
+	requested, err := registry.Refund(context.Background(), current, refund.Request{Reason: "raffle cancelled"})
+	if err != nil {
+		t.Fatalf("Refund: %v", err)
+	}
+	if requested.Method != store.RefundManualPayout || requested.Status != store.RefundRequested {
+		t.Fatalf("refund = %s %s, want %s %s", requested.Method, requested.Status, store.RefundManualPayout, store.RefundRequested)
+	}
+	if !requested.Amount.Equal(decimal.NewFromInt(10)) || requested.BookingId != "BK-A" {
+		t.Fatalf("refund = %s of %s, want 10 of BK-A", requested.Amount, requested.BookingId)
+	}
+}
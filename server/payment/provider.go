@@ -0,0 +1,197 @@
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"raffle_web_server/booking"
+	"raffle_web_server/config"
+	"raffle_web_server/refund"
+	"raffle_web_server/store"
+	"sort"
+)
+
+// SypagoDebit es el nombre del proveedor de débito inmediato con OTP de SyPago
+const SypagoDebit = "sypago_debit"
+
+// ErrUnknownProvider se devuelve cuando no hay un proveedor registrado con el nombre indicado
+var ErrUnknownProvider = errors.New("unknown payment provider")
+
+// ErrProviderDisabled se devuelve cuando la rifa de la reserva no acepta el proveedor
+var ErrProviderDisabled = errors.New("payment provider not enabled for raffle")
+
+// ErrInvalidPayload se devuelve cuando los datos enviados al proveedor no son válidos
+var ErrInvalidPayload = errors.New("invalid payment data")
+
+// ErrProviderFailed se devuelve cuando el servicio externo del proveedor no pudo procesar la operación
+var ErrProviderFailed = errors.New("payment provider failed")
+
+// ErrPaymentPending se devuelve cuando el proveedor aceptó el pago pero no se pudo registrar en la
+// reserva; el cobro existe y su resultado llega por el webhook o la revisión manual
+var ErrPaymentPending = errors.New("payment submitted, confirmation pending")
+
+// ErrReferenceNotFound se devuelve cuando la referencia del pago no aparece en los movimientos de la cuenta
+var ErrReferenceNotFound = errors.New("payment reference not found")
+
+// ErrNotSupported se devuelve cuando el proveedor no implementa la operación
+var ErrNotSupported = errors.New("operation not supported by payment provider")
+
+// Outcome es el resultado de una operación de pago que se devuelve al frontend
+type Outcome struct {
+	Provider      string              `json:"provider"`
+	BookingId     string              `json:"bookingId"`
+	BookingStatus store.BookingStatus `json:"bookingStatus"`
+	PaymentStatus string              `json:"paymentStatus,omitempty"` // estado informado por el proveedor
+	TransactionId string              `json:"transactionId,omitempty"`
+	Reference     string              `json:"reference,omitempty"` // referencia del pago confirmado
+	RejectCode    string              `json:"rejectCode,omitempty"`
+	Message       string              `json:"message"`
+	BlessNumbers  []int               `json:"blessNumbers,omitempty"`
+	Details       map[string]string   `json:"details,omitempty"` // datos propios del proveedor, ej. la cuenta de destino
+}
+
+// Provider es un medio de pago. Initiate y Confirm reciben el body de la petición tal cual
+// llegó y lo interpretan según el proveedor; Status consulta el estado del pago sin modificar
+// la reserva, y el resultado se aplica con booking.Service.ApplyPaymentResult. Refund devuelve
+// el pago de la reserva por el medio que corresponde al proveedor y delega en refund.Service,
+// que lleva el registro de las devoluciones.
+type Provider interface {
+	Name() string
+	Initiate(ctx context.Context, current *store.Booking, payload json.RawMessage) (*Outcome, error)
+	Confirm(ctx context.Context, current *store.Booking, payload json.RawMessage) (*Outcome, error)
+	Status(ctx context.Context, current *store.Booking) (booking.PaymentResult, error)
+	Refund(ctx context.Context, current *store.Booking, request refund.Request) (*store.Refund, error)
+}
+
+// Registry agrupa los proveedores de pago disponibles en el servidor
+type Registry struct {
+	providers map[string]Provider
+}
+
+func NewRegistry(providers ...Provider) *Registry {
+	registry := &Registry{providers: make(map[string]Provider, len(providers))}
+	for _, provider := range providers {
+		registry.providers[provider.Name()] = provider
+	}
+	return registry
+}
+
+// Get devuelve el proveedor registrado con el nombre indicado
+func (r *Registry) Get(name string) (Provider, error) {
+	provider, exists := r.providers[name]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownProvider, name)
+	}
+	return provider, nil
+}
+
+// Names devuelve los nombres de los proveedores registrados, ordenados
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.providers))
+	for name := range r.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Enabled devuelve los proveedores que acepta la rifa. Si la rifa no define ninguno se usan los
+// de PaymentConfig.DefaultProviders y, si tampoco hay, el débito de SyPago. Las rifas
+// canceladas no aceptan pagos.
+func (r *Registry) Enabled(raffle store.Raffle) []string {
+	if raffle.Status == store.RaffleCancelled {
+		return []string{}
+	}
+
+	names := raffle.PaymentProviders
+	if len(names) == 0 {
+		names = config.GetConfig().PaymentConfig.DefaultProviders
+	}
+	if len(names) == 0 {
+		names = []string{SypagoDebit}
+	}
+
+	enabled := make([]string, 0, len(names))
+	for _, name := range names {
+		if _, exists := r.providers[name]; exists {
+			enabled = append(enabled, name)
+		}
+	}
+	return enabled
+}
+
+// Allows verifica que la rifa acepte el proveedor indicado
+func (r *Registry) Allows(raffle store.Raffle, name string) error {
+	if _, err := r.Get(name); err != nil {
+		return err
+	}
+
+	for _, enabled := range r.Enabled(raffle) {
+		if enabled == name {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %s does not accept %s", ErrProviderDisabled, raffle.ID, name)
+}
+
+// Validate verifica que todos los nombres correspondan a proveedores registrados
+func (r *Registry) Validate(names []string) error {
+	for _, name := range names {
+		if _, err := r.Get(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ProviderOf devuelve el proveedor con el que se está pagando la reserva. Las reservas
+// anteriores a los proveedores de pago solo podían pagarse con el débito de SyPago.
+func ProviderOf(current store.Booking) string {
+	if current.Provider == "" {
+		return SypagoDebit
+	}
+	return current.Provider
+}
+
+// FetchPaymentResult consulta el estado del pago de una reserva con su proveedor.
+// Lo usa el reconciliador de reservas pendientes.
+func (r *Registry) FetchPaymentResult(ctx context.Context, pending store.Booking) (booking.PaymentResult, error) {
+	provider, err := r.Get(ProviderOf(pending))
+	if err != nil {
+		return booking.PaymentResult{}, err
+	}
+
+	return provider.Status(ctx, &pending)
+}
+
+// Refund devuelve el pago de una reserva con el proveedor con el que se cobró
+func (r *Registry) Refund(ctx context.Context, current *store.Booking, request refund.Request) (*store.Refund, error) {
+	provider, err := r.Get(ProviderOf(*current))
+	if err != nil {
+		return nil, err
+	}
+
+	return provider.Refund(ctx, current, request)
+}
+
+// creditRefund devuelve el pago con un crédito de SyPago a la cuenta desde la que se pagó, o a
+// la indicada en la devolución, y lo envía en el acto. Si el envío falla la devolución queda
+// registrada y se puede reintentar.
+func creditRefund(ctx context.Context, refunds *refund.Service, current *store.Booking, request refund.Request) (*store.Refund, error) {
+	request.BookingId = current.ID
+	request.Method = store.RefundSypagoCredit
+
+	requested, err := refunds.Request(request)
+	if err != nil {
+		return nil, err
+	}
+
+	executed, err := refunds.Execute(ctx, requested.ID)
+	if err != nil {
+		return nil, fmt.Errorf("refund %s was registered but not sent: %w", requested.ID, err)
+	}
+
+	return executed, nil
+}
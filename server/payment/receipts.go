@@ -0,0 +1,88 @@
+package payment
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrInvalidReceipt se devuelve cuando la imagen del comprobante no es válida
+var ErrInvalidReceipt = errors.New("invalid receipt")
+
+// receiptTypes son los formatos de imagen aceptados y la extensión con la que se guardan
+var receiptTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+}
+
+// ReceiptStore guarda las imágenes de los comprobantes de pagos manuales en un directorio
+type ReceiptStore struct {
+	dir string
+}
+
+func NewReceiptStore(dir string) (*ReceiptStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create receipts directory: %v", err)
+	}
+	return &ReceiptStore{dir: dir}, nil
+}
+
+// Save guarda la imagen del comprobante de la reserva y devuelve el nombre del archivo y su
+// content type. El formato se detecta a partir del contenido, no del nombre enviado.
+func (r *ReceiptStore) Save(bookingId string, content io.Reader, maxBytes int64) (string, string, error) {
+	data, err := io.ReadAll(io.LimitReader(content, maxBytes+1))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read receipt: %v", err)
+	}
+
+	if len(data) == 0 {
+		return "", "", fmt.Errorf("%w: receipt is empty", ErrInvalidReceipt)
+	}
+
+	if int64(len(data)) > maxBytes {
+		return "", "", fmt.Errorf("%w: receipt exceeds %d bytes", ErrInvalidReceipt, maxBytes)
+	}
+
+	contentType := http.DetectContentType(data)
+	extension, allowed := receiptTypes[contentType]
+	if !allowed {
+		return "", "", fmt.Errorf("%w: unsupported receipt type %s (valid: JPEG, PNG, WEBP)", ErrInvalidReceipt, contentType)
+	}
+
+	name := fmt.Sprintf("%s-%d%s", bookingId, time.Now().UnixNano(), extension)
+
+	tmp, err := os.CreateTemp(r.dir, name+".tmp")
+	if err != nil {
+		return "", "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return "", "", err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return "", "", err
+	}
+
+	if err := os.Rename(tmp.Name(), filepath.Join(r.dir, name)); err != nil {
+		return "", "", err
+	}
+
+	return name, contentType, nil
+}
+
+// Path devuelve la ruta del archivo de un comprobante guardado. El nombre no puede salir del
+// directorio de comprobantes.
+func (r *ReceiptStore) Path(name string) (string, error) {
+	if name == "" || name == "." || name == ".." || filepath.Base(name) != name {
+		return "", fmt.Errorf("%w: invalid receipt name %q", ErrInvalidReceipt, name)
+	}
+	return filepath.Join(r.dir, name), nil
+}
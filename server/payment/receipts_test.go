@@ -0,0 +1,110 @@
+package payment
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Cabeceras mínimas con las que http.DetectContentType reconoce cada formato
+var (
+	pngReceipt  = append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0}, 32)...)
+	jpegReceipt = append([]byte("\xff\xd8\xff\xe0"), bytes.Repeat([]byte{0}, 32)...)
+	webpReceipt = append([]byte("RIFF\x00\x00\x00\x00WEBPVP8 "), bytes.Repeat([]byte{0}, 32)...)
+)
+
+func TestReceiptStoreDetectsTheTypeFromTheContent(t *testing.T) {
+	dir := t.TempDir()
+	receipts, err := NewReceiptStore(dir)
+	if err != nil {
+		t.Fatalf("NewReceiptStore: %v", err)
+	}
+
+	cases := []struct {
+		content   []byte
+		wantType  string
+		extension string
+	}{
+		{pngReceipt, "image/png", ".png"},
+		{jpegReceipt, "image/jpeg", ".jpg"},
+		{webpReceipt, "image/webp", ".webp"},
+	}
+
+	for _, c := range cases {
+		name, contentType, err := receipts.Save("BK-A", bytes.NewReader(c.content), 1024)
+		if err != nil {
+			t.Fatalf("Save(%s): %v", c.wantType, err)
+		}
+		if contentType != c.wantType || !strings.HasPrefix(name, "BK-A-") || filepath.Ext(name) != c.extension {
+			t.Errorf("Save(%s) = %s as %s, want a BK-A %s file", c.wantType, name, contentType, c.extension)
+		}
+
+		path, err := receipts.Path(name)
+		if err != nil {
+			t.Fatalf("Path(%s): %v", name, err)
+		}
+		saved, err := os.ReadFile(path)
+		if err != nil || !bytes.Equal(saved, c.content) {
+			t.Errorf("saved %s = %d bytes, err %v, want the uploaded content", name, len(saved), err)
+		}
+	}
+}
+
+func TestReceiptStoreRejectsInvalidReceipts(t *testing.T) {
+	dir := t.TempDir()
+	receipts, err := NewReceiptStore(dir)
+	if err != nil {
+		t.Fatalf("NewReceiptStore: %v", err)
+	}
+
+	cases := []struct {
+		name    string
+		content []byte
+	}{
+		{"empty", nil},
+		{"over the limit", append(append([]byte(nil), pngReceipt...), bytes.Repeat([]byte{0}, 64)...)},
+		{"html named as an image", []byte("<html><script>alert(1)</script></html>")},
+		{"pdf", []byte("%PDF-1.7\n")},
+		{"gif", []byte("GIF89a\x01\x00\x01\x00")},
+	}
+
+	for _, c := range cases {
+		if _, _, err := receipts.Save("BK-A", bytes.NewReader(c.content), 64); !errors.Is(err, ErrInvalidReceipt) {
+			t.Errorf("%s: error = %v, want %v", c.name, err, ErrInvalidReceipt)
+		}
+	}
+
+	// Los comprobantes rechazados no dejan archivos en el directorio
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("receipts directory has %d files, want none", len(entries))
+	}
+}
+
+func TestReceiptPathStaysInTheDirectory(t *testing.T) {
+	dir := t.TempDir()
+	receipts, err := NewReceiptStore(dir)
+	if err != nil {
+		t.Fatalf("NewReceiptStore: %v", err)
+	}
+
+	for _, name := range []string{"", ".", "..", "../store.json", "nested/BK-A.png", "/etc/passwd", "BK-A/../../store.json"} {
+		if _, err := receipts.Path(name); !errors.Is(err, ErrInvalidReceipt) {
+			t.Errorf("Path(%q) error = %v, want %v", name, err, ErrInvalidReceipt)
+		}
+	}
+
+	path, err := receipts.Path("BK-A-1.png")
+	if err != nil {
+		t.Fatalf("Path: %v", err)
+	}
+	if path != filepath.Join(dir, "BK-A-1.png") {
+		t.Fatalf("Path = %s, want it inside %s", path, dir)
+	}
+}
@@ -0,0 +1,466 @@
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"raffle_web_server/booking"
+	"raffle_web_server/config"
+	"raffle_web_server/refund"
+	"raffle_web_server/store"
+	"raffle_web_server/sypago"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DebitRequestOtpData estructura plana con los datos necesarios para el débito
+type DebitRequestOtpData struct {
+	// Reserva que se va a pagar
+	BookingId string `json:"booking_id"`
+
+	// Datos del deudor (documento)
+
+	DebitorDocumentType   string `json:"document_letter"` // "V", "E", "J", etc.
+	DebitorDocumentNumber string `json:"document"`        // "26951697"
+
+	// Datos de la cuenta del deudor
+	DebitorBankCode      string `json:"bank_code"`      // "0105"
+	DebitorAccountNumber string `json:"account_number"` // "04242186302"
+
+	// Datos del monto
+	Amount   float64 `json:"amount"`   // 5.0
+	Currency string  `json:"currency"` // "VES", "USD"
+}
+
+// TransactionOtpData estructura plana para datos de transacción OTP
+type TransactionOtpData struct {
+	// Datos de la rifa y participante
+	BookingId     string `json:"booking_id"`
+	ParticipantId string `json:"participant_id"`
+	RaffleId      string `json:"raffle_id"`
+	Tickets       []int  `json:"tickets"`
+
+	// Datos del usuario receptor
+	ReceiverName           string `json:"receiver_name"`
+	ReceiverOtp            string `json:"receiver_otp"`
+	ReceiverDocumentType   string `json:"receiver_document_type"`
+	ReceiverDocumentNumber string `json:"receiver_document_number"`
+	ReceiverBankCode       string `json:"receiver_bank_code"`
+	ReceiverAccountNumber  string `json:"receiver_account_number"`
+
+	// Datos del monto
+	Amount   float64 `json:"amount"`
+	Currency string  `json:"currency"`
+
+	// InternalId es el identificador de la operación enviado a SyPago; lo asigna el servidor
+	InternalId string `json:"-"`
+}
+
+// ValidateDebitRequestData valida los datos de la petición de débito
+func ValidateDebitRequestData(data DebitRequestOtpData) error {
+	if data.BookingId == "" {
+		return fmt.Errorf("booking ID is required")
+	}
+
+	if data.DebitorDocumentType == "" {
+		return fmt.Errorf("debitor document type is required")
+	}
+
+	if data.DebitorDocumentNumber == "" {
+		return fmt.Errorf("debitor document number is required")
+	}
+
+	if data.DebitorBankCode == "" {
+		return fmt.Errorf("debitor bank code is required")
+	}
+
+	if data.DebitorAccountNumber == "" {
+		return fmt.Errorf("debitor account number is required")
+	}
+
+	if data.Amount <= 0 {
+		return fmt.Errorf("amount must be greater than 0")
+	}
+
+	if data.Currency == "" {
+		return fmt.Errorf("currency is required")
+	}
+
+	// Validar tipos de documento válidos
+	validDocTypes := map[string]bool{"V": true, "E": true, "J": true, "G": true}
+	if !validDocTypes[data.DebitorDocumentType] {
+		return fmt.Errorf("invalid document type: %s (valid: V, E, J, G)", data.DebitorDocumentType)
+	}
+
+	// Validar monedas válidas
+	validCurrencies := map[string]bool{"VES": true, "USD": true}
+	if !validCurrencies[data.Currency] {
+		return fmt.Errorf("invalid currency: %s (valid: VES, USD)", data.Currency)
+	}
+
+	return nil
+}
+
+// ValidateTransactionOtpData valida los datos de la transacción OTP
+func ValidateTransactionOtpData(data TransactionOtpData) error {
+	// Validar datos de rifa y participante
+	if data.BookingId == "" {
+		return fmt.Errorf("booking ID is required")
+	}
+
+	if data.ParticipantId == "" {
+		return fmt.Errorf("participant ID is required")
+	}
+
+	if data.RaffleId == "" {
+		return fmt.Errorf("raffle ID is required")
+	}
+
+	if len(data.Tickets) == 0 {
+		return fmt.Errorf("at least one ticket is required")
+	}
+
+	// Validar datos del receptor
+	if data.ReceiverName == "" {
+		return fmt.Errorf("receiver name is required")
+	}
+
+	if data.ReceiverOtp == "" {
+		return fmt.Errorf("receiver OTP is required")
+	}
+
+	if data.ReceiverDocumentType == "" {
+		return fmt.Errorf("receiver document type is required")
+	}
+
+	if data.ReceiverDocumentNumber == "" {
+		return fmt.Errorf("receiver document number is required")
+	}
+
+	if data.ReceiverBankCode == "" {
+		return fmt.Errorf("receiver bank code is required")
+	}
+
+	if data.ReceiverAccountNumber == "" {
+		return fmt.Errorf("receiver account number is required")
+	}
+
+	if data.Amount <= 0 {
+		return fmt.Errorf("amount must be greater than 0")
+	}
+
+	if data.Currency == "" {
+		return fmt.Errorf("currency is required")
+	}
+
+	// Validar tipos de documento válidos
+	validDocTypes := map[string]bool{"V": true, "E": true, "J": true, "G": true}
+	if !validDocTypes[data.ReceiverDocumentType] {
+		return fmt.Errorf("invalid document type: %s (valid: V, E, J, G)", data.ReceiverDocumentType)
+	}
+
+	// Validar monedas válidas
+	validCurrencies := map[string]bool{"VES": true, "USD": true}
+	if !validCurrencies[data.Currency] {
+		return fmt.Errorf("invalid currency: %s (valid: VES, USD)", data.Currency)
+	}
+
+	// Validar que los tickets sean números positivos
+	for _, ticket := range data.Tickets {
+		if ticket <= 0 {
+			return fmt.Errorf("ticket numbers must be positive: %d", ticket)
+		}
+	}
+
+	return nil
+}
+
+// recordDebitAttempts es cuántas veces se intenta registrar en la reserva un débito que SyPago ya aceptó
+const recordDebitAttempts = 3
+const recordDebitRetryDelay = 200 * time.Millisecond
+
+// generateUUID genera un UUID sin guiones y en mayúsculas
+func generateUUID() string {
+	id := uuid.New()
+	return strings.ToUpper(strings.ReplaceAll(id.String(), "-", ""))
+}
+
+// buildRequestOtpPayload construye el payload para la petición de OTP
+func buildRequestOtpPayload(data DebitRequestOtpData) sypago.RequestOtpRequest {
+	sypagoConfig := config.GetConfig().SypagoConfig
+
+	return sypago.RequestOtpRequest{
+		CreditorAccount: sypago.Account{
+			BankCode: sypagoConfig.CreditorBankCode,
+			Type:     "CNTA", // Siempre CNTA para creditor
+			Number:   sypagoConfig.CreditorAccount,
+		},
+		DebitorDocumentInfo: sypago.DocumentInfo{
+			Type:   data.DebitorDocumentType,
+			Number: data.DebitorDocumentNumber,
+		},
+		DebitorAccount: sypago.Account{
+			BankCode: data.DebitorBankCode,
+			Type:     "CELE",
+			Number:   data.DebitorAccountNumber,
+		},
+		Amount: sypago.Amount{
+			Amt:      data.Amount,
+			Currency: data.Currency,
+		},
+	}
+}
+
+// buildTransactionOtpPayload construye el payload para la transacción OTP (solo formato SyPago)
+func buildTransactionOtpPayload(data TransactionOtpData, webhookEndpoint string) sypago.TransactionOtpRequest {
+	sypagoConfig := config.GetConfig().SypagoConfig
+
+	return sypago.TransactionOtpRequest{
+		InternalId: data.InternalId,
+		GroupId:    generateUUID(),
+		Account: sypago.Account{
+			BankCode: sypagoConfig.CreditorBankCode,
+			Type:     "CNTA", // Siempre CNTA para account
+			Number:   sypagoConfig.CreditorAccount,
+		},
+		Amount: sypago.AmountWithRate{
+			Amt:        data.Amount,
+			Currency:   data.Currency,
+			UseDayRate: false, // el monto ya viene convertido con la tasa registrada en la reserva
+		},
+		Concept: "Concept",
+		NotificationUrls: sypago.NotificationUrls{
+			WebHookEndpoint: webhookEndpoint,
+		},
+		ReceivingUser: sypago.ReceivingUser{
+			Name: data.ReceiverName,
+			Otp:  data.ReceiverOtp,
+			DocumentInfo: sypago.DocumentInfo{
+				Type:   data.ReceiverDocumentType,
+				Number: data.ReceiverDocumentNumber,
+			},
+			Account: sypago.Account{
+				BankCode: data.ReceiverBankCode,
+				Type:     "CELE", // Siempre CELE para receiving user
+				Number:   data.ReceiverAccountNumber,
+			},
+		},
+	}
+}
+
+// Sypago cobra las reservas con el débito inmediato de SyPago: Initiate solicita el OTP al banco
+// del pagador, Confirm envía el débito con el OTP recibido y Refund devuelve el pago con un crédito
+type Sypago struct {
+	bookings    *booking.Service
+	refunds     *refund.Service
+	client      sypago.Client
+	rejectCodes *sypago.RejectCatalog
+	webhook     func() string // URL de notificación que se registra en cada débito
+}
+
+func NewSypago(bookings *booking.Service, refunds *refund.Service, client sypago.Client, rejectCodes *sypago.RejectCatalog, webhook func() string) *Sypago {
+	return &Sypago{bookings: bookings, refunds: refunds, client: client, rejectCodes: rejectCodes, webhook: webhook}
+}
+
+func (p *Sypago) Name() string {
+	return SypagoDebit
+}
+
+// RequestOtp verifica el monto contra la reserva, registra la solicitud del OTP y lo pide a SyPago
+func (p *Sypago) RequestOtp(ctx context.Context, data DebitRequestOtpData) (*store.Booking, error) {
+	current, err := p.bookings.MarkOtpRequested(data.BookingId, data.Amount, data.Currency, store.PaymentAccount{
+		DocumentType:   data.DebitorDocumentType,
+		DocumentNumber: data.DebitorDocumentNumber,
+		BankCode:       data.DebitorBankCode,
+		AccountType:    "CELE",
+		AccountNumber:  data.DebitorAccountNumber,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Se cobra el monto calculado por el servidor
+	data.Amount = current.Amount.InexactFloat64()
+	data.Currency = current.Currency
+
+	if err := p.client.RequestOtp(ctx, buildRequestOtpPayload(data)); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrProviderFailed, err)
+	}
+
+	return current, nil
+}
+
+// SubmitDebit verifica que la reserva corresponda a los datos recibidos, envía el débito a SyPago
+// y registra la transacción en la reserva. El operation_secret de la respuesta solo se guarda
+// cifrado en la reserva y no debe enviarse al frontend. Si SyPago aceptó el débito pero no se pudo
+// registrar, devuelve la respuesta junto con ErrPaymentPending.
+func (p *Sypago) SubmitDebit(ctx context.Context, data TransactionOtpData) (*store.Booking, *sypago.TransactionOtpResponse, error) {
+	data.InternalId = booking.DebitInternalId(data.BookingId)
+	current, err := p.bookings.PrepareDebit(data.BookingId, data.RaffleId, data.ParticipantId, data.Tickets, data.Amount, data.Currency, data.InternalId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Se cobra el monto calculado por el servidor
+	data.Amount = current.Amount.InexactFloat64()
+	data.Currency = current.Currency
+
+	response, err := p.client.TransactionOtp(ctx, buildTransactionOtpPayload(data, p.webhook()))
+	if err != nil {
+		p.bookings.ReleaseDebit(data.BookingId)
+		return nil, nil, fmt.Errorf("%w: %v", ErrProviderFailed, err)
+	}
+
+	current, err = p.recordDebit(data.BookingId, response)
+	if err != nil {
+		p.bookings.ReleaseDebit(data.BookingId)
+		fmt.Printf("Debit %s of booking %s was submitted but could not be recorded: %v\n", response.TransactionId, data.BookingId, err)
+		return nil, response, fmt.Errorf("%w: transaction %s of booking %s could not be recorded: %v", ErrPaymentPending, response.TransactionId, data.BookingId, err)
+	}
+
+	return current, response, nil
+}
+
+// recordDebit registra en la reserva la transacción que SyPago ya aceptó. Se reintenta porque sin
+// el transaction_id y el operation_secret no se puede conciliar el cobro.
+func (p *Sypago) recordDebit(bookingId string, response *sypago.TransactionOtpResponse) (*store.Booking, error) {
+	var err error
+	for i := 1; i <= recordDebitAttempts; i++ {
+		var current *store.Booking
+		current, err = p.bookings.MarkDebitSubmitted(bookingId, response.TransactionId, response.OperationSecret)
+		if err == nil {
+			return current, nil
+		}
+
+		// Un error de la reserva no cambia al reintentar
+		if errors.Is(err, booking.ErrInvalidTransition) || errors.Is(err, store.ErrNotFound) {
+			break
+		}
+
+		if i < recordDebitAttempts {
+			time.Sleep(time.Duration(i) * recordDebitRetryDelay)
+		}
+	}
+	return nil, err
+}
+
+// Initiate solicita el OTP; el body tiene el formato de DebitRequestOtpData
+func (p *Sypago) Initiate(ctx context.Context, current *store.Booking, payload json.RawMessage) (*Outcome, error) {
+	var data DebitRequestOtpData
+	if err := json.Unmarshal(payload, &data); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPayload, err)
+	}
+
+	data.BookingId = current.ID
+	if err := ValidateDebitRequestData(data); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPayload, err)
+	}
+
+	updated, err := p.RequestOtp(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Outcome{
+		Provider:      p.Name(),
+		BookingId:     updated.ID,
+		BookingStatus: updated.Status,
+		Message:       "OTP request processed successfully",
+	}, nil
+}
+
+// Confirm envía el débito; el body tiene el formato de TransactionOtpData. La rifa, el
+// participante y los tickets se toman de la reserva cuando no se envían.
+func (p *Sypago) Confirm(ctx context.Context, current *store.Booking, payload json.RawMessage) (*Outcome, error) {
+	var data TransactionOtpData
+	if err := json.Unmarshal(payload, &data); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPayload, err)
+	}
+
+	data.BookingId = current.ID
+	if data.RaffleId == "" {
+		data.RaffleId = current.RaffleId
+	}
+	if data.ParticipantId == "" {
+		data.ParticipantId = current.ParticipantId
+	}
+	if len(data.Tickets) == 0 {
+		data.Tickets = current.Tickets
+	}
+
+	if err := ValidateTransactionOtpData(data); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPayload, err)
+	}
+
+	updated, response, err := p.SubmitDebit(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Outcome{
+		Provider:      p.Name(),
+		BookingId:     updated.ID,
+		BookingStatus: updated.Status,
+		PaymentStatus: sypago.StatusPending,
+		TransactionId: response.TransactionId,
+		Message:       "Transaction processed successfully",
+	}, nil
+}
+
+// Status consulta en SyPago el estado del débito de la reserva sin modificarla
+func (p *Sypago) Status(ctx context.Context, current *store.Booking) (booking.PaymentResult, error) {
+	if current.TransactionId == "" {
+		return booking.PaymentResult{}, fmt.Errorf("%w: booking %s has no SyPago transaction", booking.ErrInvalidTransition, current.ID)
+	}
+
+	operationSecret, err := p.bookings.OperationSecret(current)
+	if err != nil {
+		return booking.PaymentResult{}, fmt.Errorf("failed to read operation secret: %v", err)
+	}
+
+	response, err := p.client.TransactionStatus(ctx, current.TransactionId, operationSecret)
+	if err != nil {
+		return booking.PaymentResult{}, fmt.Errorf("%w: failed to fetch transaction status from SyPago: %v", ErrProviderFailed, err)
+	}
+
+	return booking.PaymentResult{
+		Status:       response.Status,
+		RefIbp:       response.RefIbp,
+		RejectedCode: response.RejectedCode,
+		Reason:       p.statusReason(response.Status, response.RejectedCode),
+	}, nil
+}
+
+// Refund devuelve el pago con un crédito de SyPago a la cuenta desde la que se debitó.
+// SyPago no permite revertir el débito.
+func (p *Sypago) Refund(ctx context.Context, current *store.Booking, request refund.Request) (*store.Refund, error) {
+	return creditRefund(ctx, p.refunds, current, request)
+}
+
+// statusReason mapea el status de SyPago a una descripción en español. Los rechazos llevan la
+// descripción del código de rechazo cuando se conoce.
+func (p *Sypago) statusReason(status, rejectedCode string) string {
+	switch status {
+	case sypago.StatusPending:
+		return "Transacción pendiente"
+	case sypago.StatusProcessing:
+		return "Transacción en procesamiento"
+	case sypago.StatusInProcess:
+		return "Transacción en proceso"
+	case sypago.StatusAccepted:
+		return "Transacción aceptada y procesada exitosamente"
+	case sypago.StatusRejected:
+		if rejectedCode == "" {
+			return "Transacción rechazada"
+		}
+		if description, exists := p.rejectCodes.Describe(rejectedCode); exists {
+			return fmt.Sprintf("Transacción rechazada (código: %s). %s", rejectedCode, description)
+		}
+		return fmt.Sprintf("Transacción rechazada (código: %s)", rejectedCode)
+	default:
+		return fmt.Sprintf("Estado desconocido: %s", status)
+	}
+}
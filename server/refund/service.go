@@ -0,0 +1,746 @@
+package refund
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"raffle_web_server/backoff"
+	"raffle_web_server/config"
+	"raffle_web_server/store"
+	"raffle_web_server/sypago"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// amountDecimals son los decimales con los que se devuelve un pago
+const amountDecimals = 2
+
+// ErrInvalidRefund se devuelve cuando los datos de la devolución no son válidos
+var ErrInvalidRefund = errors.New("invalid refund")
+
+// ErrNotRefundable se devuelve cuando la reserva o la rifa no admiten devoluciones
+var ErrNotRefundable = errors.New("not refundable")
+
+// ErrInvalidTransition se devuelve cuando la devolución no admite la operación en su estado actual
+var ErrInvalidTransition = errors.New("invalid refund transition")
+
+// ErrProviderFailed se devuelve cuando SyPago no pudo procesar el crédito; la devolución se puede reintentar
+var ErrProviderFailed = errors.New("refund provider failed")
+
+// Request representa una devolución solicitada por un administrador
+type Request struct {
+	BookingId   string
+	Amount      decimal.Decimal // cero devuelve el monto por defecto (ver defaultAmount)
+	Reason      string
+	Method      store.RefundMethod
+	Beneficiary *store.PaymentAccount // vacía usa la cuenta desde la que se pagó
+}
+
+// Skipped es una reserva que no se incluyó en una devolución masiva
+type Skipped struct {
+	BookingId string `json:"bookingId"`
+	Reason    string `json:"reason"`
+}
+
+// BulkResult es el resultado de devolver los pagos de una rifa cancelada
+type BulkResult struct {
+	RaffleId string         `json:"raffleId"`
+	Refunds  []store.Refund `json:"refunds"`
+	Skipped  []Skipped      `json:"skipped"`
+}
+
+// Service administra las devoluciones de las reservas pagadas:
+// REQUESTED → SENDING → PROCESSING → COMPLETED / FAILED con crédito de SyPago, o
+// REQUESTED → COMPLETED con pago manual. Una devolución solo se puede cancelar mientras ningún
+// crédito suyo pudo haber llegado a SyPago.
+type Service struct {
+	repository store.RaffleRepository
+	secrets    *store.SecretBox
+	client     sypago.Client
+	webhook    func() string
+	sending    map[string]bool   // devoluciones cuyo crédito se está enviando en este momento
+	schedule   *backoff.Schedule // próximo intento de Run para cada crédito pendiente
+	mu         sync.Mutex
+}
+
+// NewService crea el servicio; webhook devuelve la URL a la que SyPago notifica los créditos
+func NewService(repository store.RaffleRepository, secrets *store.SecretBox, client sypago.Client, webhook func() string) *Service {
+	return &Service{
+		repository: repository,
+		secrets:    secrets,
+		client:     client,
+		webhook:    webhook,
+		sending:    make(map[string]bool),
+		schedule:   backoff.NewSchedule(),
+	}
+}
+
+// generateRefundId genera un refund ID único
+func generateRefundId() string {
+	id := uuid.New()
+	return "RF-" + strings.ToUpper(strings.ReplaceAll(id.String(), "-", ""))
+}
+
+// CreditInternalId deriva el internal_id de SyPago a partir de la devolución, de modo que un
+// crédito reenviado lleve el mismo identificador y SyPago no lo pague dos veces
+func CreditInternalId(refundId string) string {
+	hash := sha256.Sum256([]byte("refund\x00" + refundId))
+	return strings.ToUpper(hex.EncodeToString(hash[:16]))
+}
+
+// Get devuelve la devolución persistida
+func (s *Service) Get(refundId string) (*store.Refund, error) {
+	return s.repository.GetRefund(refundId)
+}
+
+// List devuelve las devoluciones que cumplen los filtros indicados; los filtros vacíos no se aplican
+func (s *Service) List(bookingId, raffleId string, status store.RefundStatus) ([]store.Refund, error) {
+	refunds, err := s.repository.ListRefunds()
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := make([]store.Refund, 0, len(refunds))
+	for _, refund := range refunds {
+		if (bookingId != "" && refund.BookingId != bookingId) ||
+			(raffleId != "" && refund.RaffleId != raffleId) ||
+			(status != "" && refund.Status != status) {
+			continue
+		}
+		filtered = append(filtered, refund)
+	}
+
+	return filtered, nil
+}
+
+// refundable devuelve cuánto del pago de la reserva queda por devolver
+func (s *Service) refundable(booking *store.Booking) (decimal.Decimal, error) {
+	refunds, err := s.List(booking.ID, "", "")
+	if err != nil {
+		return decimal.Zero, err
+	}
+
+	remaining := booking.Amount
+	for _, refund := range refunds {
+		if refund.Status.IsActive() {
+			remaining = remaining.Sub(refund.Amount)
+		}
+	}
+
+	return remaining, nil
+}
+
+// defaultAmount devuelve el monto a devolver cuando no se indica: lo que queda por devolver si la
+// rifa se canceló, o la parte de los tickets perdidos si el pago se aceptó pero otra reserva los tomó
+func (s *Service) defaultAmount(booking *store.Booking, raffle *store.Raffle, remaining decimal.Decimal) decimal.Decimal {
+	if raffle.Status == store.RaffleCancelled || len(booking.LostTickets) == 0 || len(booking.Tickets) == 0 {
+		return remaining
+	}
+
+	perTicket := booking.Amount.Div(decimal.NewFromInt(int64(len(booking.Tickets))))
+	amount := perTicket.Mul(decimal.NewFromInt(int64(len(booking.LostTickets)))).Round(amountDecimals)
+
+	return decimal.Min(amount, remaining)
+}
+
+// validateBeneficiary valida la cuenta a la que se envía un crédito de SyPago
+func validateBeneficiary(account *store.PaymentAccount) error {
+	validDocTypes := map[string]bool{"V": true, "E": true, "J": true, "G": true}
+	if !validDocTypes[account.DocumentType] {
+		return fmt.Errorf("%w: invalid beneficiary document type: %s (valid: V, E, J, G)", ErrInvalidRefund, account.DocumentType)
+	}
+
+	if account.DocumentNumber == "" || account.BankCode == "" || account.AccountNumber == "" {
+		return fmt.Errorf("%w: beneficiary document, bank code and account number are required", ErrInvalidRefund)
+	}
+
+	if account.AccountType == "" {
+		account.AccountType = "CELE"
+	}
+
+	if account.AccountType != "CELE" && account.AccountType != "CNTA" {
+		return fmt.Errorf("%w: invalid beneficiary account type: %s (valid: CELE, CNTA)", ErrInvalidRefund, account.AccountType)
+	}
+
+	return nil
+}
+
+// Request registra la devolución de una reserva pagada. El monto no puede superar lo que queda
+// por devolver; los créditos de SyPago se envían después con Execute.
+func (s *Service) Request(request Request) (*store.Refund, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	booking, err := s.repository.GetBooking(request.BookingId)
+	if err != nil {
+		return nil, err
+	}
+
+	if booking.Status != store.BookingPaid {
+		return nil, fmt.Errorf("%w: booking %s is %s", ErrNotRefundable, booking.ID, booking.Status)
+	}
+
+	if booking.Amount.IsZero() {
+		return nil, fmt.Errorf("%w: booking %s has no recorded payment amount", ErrNotRefundable, booking.ID)
+	}
+
+	if !request.Method.IsValid() {
+		return nil, fmt.Errorf("%w: invalid method: %s (valid: %s, %s)", ErrInvalidRefund, request.Method, store.RefundSypagoCredit, store.RefundManualPayout)
+	}
+
+	raffle, err := s.repository.GetRaffle(booking.RaffleId)
+	if err != nil {
+		return nil, err
+	}
+
+	remaining, err := s.refundable(booking)
+	if err != nil {
+		return nil, err
+	}
+
+	amount := request.Amount.Round(amountDecimals)
+	if amount.IsZero() {
+		amount = s.defaultAmount(booking, raffle, remaining)
+	}
+
+	if !amount.IsPositive() || amount.GreaterThan(remaining) {
+		return nil, fmt.Errorf("%w: amount %s %s must be greater than 0 and at most the %s %s left to refund",
+			ErrInvalidRefund, amount.StringFixed(amountDecimals), booking.Currency, remaining.StringFixed(amountDecimals), booking.Currency)
+	}
+
+	var beneficiary *store.PaymentAccount
+	if request.Beneficiary != nil {
+		copied := *request.Beneficiary
+		beneficiary = &copied
+	} else if booking.Payer != nil {
+		copied := *booking.Payer
+		beneficiary = &copied
+	}
+
+	if request.Method == store.RefundSypagoCredit {
+		if beneficiary == nil {
+			return nil, fmt.Errorf("%w: booking %s has no payer account, a beneficiary is required", ErrInvalidRefund, booking.ID)
+		}
+		if err := validateBeneficiary(beneficiary); err != nil {
+			return nil, err
+		}
+	}
+
+	if beneficiary != nil && beneficiary.Name == "" {
+		if participant, err := s.repository.GetParticipant(booking.ParticipantId); err == nil {
+			beneficiary.Name = participant.Name
+		}
+	}
+
+	now := time.Now().UTC()
+
+	refund := store.Refund{
+		ID:          generateRefundId(),
+		BookingId:   booking.ID,
+		RaffleId:    booking.RaffleId,
+		Amount:      amount,
+		Currency:    booking.Currency,
+		Reason:      strings.TrimSpace(request.Reason),
+		Method:      request.Method,
+		Status:      store.RefundRequested,
+		Beneficiary: beneficiary,
+		RequestedAt: now,
+		UpdatedAt:   now,
+	}
+
+	if err := s.repository.SaveRefund(refund); err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("Refund %s of %s %s requested for booking %s\n", refund.ID, refund.Amount.StringFixed(amountDecimals), refund.Currency, booking.ID)
+
+	return &refund, nil
+}
+
+// buildCreditPayload construye el crédito de SyPago desde la cuenta del comercio al beneficiario
+func buildCreditPayload(refund *store.Refund, webhook string) sypago.TransactionCreditRequest {
+	sypagoConfig := config.GetConfig().SypagoConfig
+
+	return sypago.TransactionCreditRequest{
+		InternalId: refund.InternalId,
+		GroupId:    strings.ToUpper(strings.ReplaceAll(uuid.New().String(), "-", "")),
+		Account: sypago.Account{
+			BankCode: sypagoConfig.CreditorBankCode,
+			Type:     "CNTA",
+			Number:   sypagoConfig.CreditorAccount,
+		},
+		Amount: sypago.AmountWithRate{
+			Amt:        refund.Amount.InexactFloat64(),
+			Currency:   refund.Currency,
+			UseDayRate: false, // se devuelve el mismo monto cobrado
+		},
+		Concept: "Reembolso " + refund.BookingId,
+		NotificationUrls: sypago.NotificationUrls{
+			WebHookEndpoint: webhook,
+		},
+		ReceivingUser: sypago.ReceivingUser{
+			Name: refund.Beneficiary.Name,
+			DocumentInfo: sypago.DocumentInfo{
+				Type:   refund.Beneficiary.DocumentType,
+				Number: refund.Beneficiary.DocumentNumber,
+			},
+			Account: sypago.Account{
+				BankCode: refund.Beneficiary.BankCode,
+				Type:     refund.Beneficiary.AccountType,
+				Number:   refund.Beneficiary.AccountNumber,
+			},
+		},
+	}
+}
+
+// Execute envía a SyPago el crédito de una devolución. La devolución pasa a SENDING antes del envío
+// y el mutex se libera mientras dura la llamada. Si SyPago rechaza la primera petición sin
+// procesarla, la devolución vuelve a REQUESTED con el error registrado; ante cualquier otro error
+// (timeout, error del servidor, internal_id duplicado) el crédito pudo haberse registrado, así que
+// queda en SENDING: no se puede cancelar y se reenvía con el mismo internal_id, que SyPago no
+// paga dos veces. Si SyPago responde que el internal_id está duplicado, la devolución pasa a
+// revisión manual y no se reenvía más.
+func (s *Service) Execute(ctx context.Context, refundId string) (*store.Refund, error) {
+	refund, firstAttempt, err := s.claimCredit(refundId)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := s.client.TransactionCredit(ctx, buildCreditPayload(refund, s.webhook()))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.sending, refund.ID)
+
+	if err != nil {
+		return nil, s.recordCreditError(refund, firstAttempt, err)
+	}
+
+	sealedSecret, err := s.secrets.Seal(response.OperationSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	refund.Status = store.RefundProcessing
+	refund.TransactionId = response.TransactionId
+	refund.OperationSecret = sealedSecret
+	refund.LastError = ""
+	refund.UpdatedAt = time.Now().UTC()
+
+	if err := s.repository.SaveRefund(*refund); err != nil {
+		return nil, err
+	}
+
+	return refund, nil
+}
+
+// claimCredit pasa la devolución a SENDING antes de enviar su crédito, de modo que un envío
+// simultáneo de la misma devolución se rechaza. Indica además si es el primer envío: una
+// devolución con internal_id ya tuvo un envío cuyo resultado se desconoce.
+func (s *Service) claimCredit(refundId string) (*store.Refund, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	refund, err := s.repository.GetRefund(refundId)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if refund.Method != store.RefundSypagoCredit {
+		return nil, false, fmt.Errorf("%w: refund %s is a %s, complete it with the payout reference", ErrInvalidTransition, refund.ID, refund.Method)
+	}
+
+	if refund.Status != store.RefundRequested && refund.Status != store.RefundSending {
+		return nil, false, fmt.Errorf("%w: refund %s is %s", ErrInvalidTransition, refund.ID, refund.Status)
+	}
+
+	if s.sending[refund.ID] {
+		return nil, false, fmt.Errorf("%w: the credit of refund %s is already being sent", ErrInvalidTransition, refund.ID)
+	}
+
+	if refund.ManualReview.IsPending() {
+		return nil, false, fmt.Errorf("%w: refund %s is waiting for manual review, complete it with the reference verified in SyPago", ErrInvalidTransition, refund.ID)
+	}
+
+	firstAttempt := refund.InternalId == ""
+
+	refund.Status = store.RefundSending
+	refund.InternalId = CreditInternalId(refund.ID)
+	refund.UpdatedAt = time.Now().UTC()
+
+	if err := s.repository.SaveRefund(*refund); err != nil {
+		return nil, false, err
+	}
+
+	s.sending[refund.ID] = true
+
+	return refund, firstAttempt, nil
+}
+
+// recordCreditError registra el error del envío del crédito. Se llama con el mutex tomado.
+func (s *Service) recordCreditError(refund *store.Refund, firstAttempt bool, sendErr error) error {
+	refused := errors.Is(sendErr, sypago.ErrRefused) || errors.Is(sendErr, sypago.ErrUnauthorized) || errors.Is(sendErr, sypago.ErrNotFound)
+
+	refund.LastError = sendErr.Error()
+	refund.UpdatedAt = time.Now().UTC()
+
+	switch {
+	case errors.Is(sendErr, sypago.ErrDuplicate):
+		// Reenviarlo solo devolvería el mismo error: un administrador lo verifica en SyPago
+		refund.LastError = "SyPago already registered this credit, verify it in SyPago and complete the refund with its reference: " + sendErr.Error()
+		refund.ManualReview = &store.ManualReview{Reason: "SyPago reported the credit as a duplicate", RequestedAt: refund.UpdatedAt}
+	case refused && firstAttempt:
+		// SyPago no procesó la petición: la devolución se puede corregir, reintentar o cancelar
+		refund.Status = store.RefundRequested
+		refund.InternalId = ""
+	}
+
+	if err := s.repository.SaveRefund(*refund); err != nil {
+		return err
+	}
+
+	return fmt.Errorf("%w: %v", ErrProviderFailed, sendErr)
+}
+
+// Complete registra la referencia de una devolución pagada a mano por el back office. También
+// cierra un crédito de SyPago cuyo envío quedó en SENDING, con la referencia verificada en SyPago,
+// y registra la revisión manual si la tenía pendiente.
+func (s *Service) Complete(refundId, reference, note string) (*store.Refund, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	refund, err := s.repository.GetRefund(refundId)
+	if err != nil {
+		return nil, err
+	}
+
+	settling := refund.Method == store.RefundSypagoCredit && refund.Status == store.RefundSending && !s.sending[refund.ID]
+
+	if refund.Method != store.RefundManualPayout && !settling {
+		return nil, fmt.Errorf("%w: refund %s is a %s in %s, it is completed by SyPago", ErrInvalidTransition, refund.ID, refund.Method, refund.Status)
+	}
+
+	if refund.Status != store.RefundRequested && !settling {
+		return nil, fmt.Errorf("%w: refund %s is %s", ErrInvalidTransition, refund.ID, refund.Status)
+	}
+
+	if reference == "" {
+		return nil, fmt.Errorf("%w: payout reference is required", ErrInvalidRefund)
+	}
+
+	now := time.Now().UTC()
+
+	refund.Status = store.RefundCompleted
+	refund.Reference = reference
+	refund.Note = note
+	refund.CompletedAt = &now
+	refund.UpdatedAt = now
+
+	if refund.ManualReview.IsPending() {
+		review := *refund.ManualReview
+		review.ReviewedAt = &now
+		review.Note = note
+		refund.ManualReview = &review
+	}
+
+	if err := s.repository.SaveRefund(*refund); err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("Refund %s completed with reference %s\n", refund.ID, reference)
+
+	return refund, nil
+}
+
+// Cancel descarta una devolución que todavía no se ejecutó. Una devolución con un crédito enviado
+// no se cancela aunque el envío haya fallado: el crédito pudo haberse registrado en SyPago y una
+// nueva devolución, con otro internal_id, pagaría dos veces.
+func (s *Service) Cancel(refundId, note string) (*store.Refund, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	refund, err := s.repository.GetRefund(refundId)
+	if err != nil {
+		return nil, err
+	}
+
+	if refund.Status != store.RefundRequested {
+		return nil, fmt.Errorf("%w: refund %s is %s", ErrInvalidTransition, refund.ID, refund.Status)
+	}
+
+	if refund.InternalId != "" {
+		return nil, fmt.Errorf("%w: the credit of refund %s may have reached SyPago, execute it again to settle it", ErrInvalidTransition, refund.ID)
+	}
+
+	refund.Status = store.RefundCancelled
+	refund.Note = note
+	refund.UpdatedAt = time.Now().UTC()
+
+	if err := s.repository.SaveRefund(*refund); err != nil {
+		return nil, err
+	}
+
+	return refund, nil
+}
+
+// FindByPayment busca la devolución asociada a un crédito de SyPago
+func (s *Service) FindByPayment(internalId, transactionId string) (*store.Refund, error) {
+	return s.repository.FindRefundByPayment(internalId, transactionId)
+}
+
+// ApplyResult avanza la devolución según el estado del crédito en SyPago: ACCP la completa y RJCT
+// la marca como fallida. Es idempotente: una devolución ya finalizada se devuelve sin cambios.
+func (s *Service) ApplyResult(refundId, status, refIbp, rejectedCode string) (*store.Refund, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	refund, err := s.repository.GetRefund(refundId)
+	if err != nil {
+		return nil, err
+	}
+
+	if refund.Status != store.RefundProcessing {
+		return refund, nil
+	}
+
+	now := time.Now().UTC()
+
+	switch status {
+	case sypago.StatusAccepted:
+		refund.Status = store.RefundCompleted
+		refund.Reference = refIbp
+		refund.CompletedAt = &now
+	case sypago.StatusRejected:
+		refund.Status = store.RefundFailed
+		refund.FailureCode = rejectedCode
+	default:
+		return refund, nil
+	}
+
+	refund.UpdatedAt = now
+
+	if err := s.repository.SaveRefund(*refund); err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("Refund %s finalized as %s\n", refund.ID, refund.Status)
+
+	return refund, nil
+}
+
+// Refresh consulta en SyPago el estado del crédito de una devolución en proceso
+func (s *Service) Refresh(ctx context.Context, refundId string) (*store.Refund, error) {
+	refund, err := s.repository.GetRefund(refundId)
+	if err != nil {
+		return nil, err
+	}
+
+	if refund.Status != store.RefundProcessing {
+		return refund, nil
+	}
+
+	operationSecret, err := s.secrets.Open(refund.OperationSecret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read operation secret: %v", err)
+	}
+
+	status, err := s.client.TransactionStatus(ctx, refund.TransactionId, operationSecret)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrProviderFailed, err)
+	}
+
+	return s.ApplyResult(refund.ID, status.Status, status.RefIbp, status.RejectedCode)
+}
+
+// LostTicketBookings devuelve las reservas pagadas que perdieron tickets y todavía no tienen una
+// devolución registrada
+func (s *Service) LostTicketBookings() ([]store.Booking, error) {
+	bookings, err := s.repository.ListBookingsByStatus(store.BookingPaid)
+	if err != nil {
+		return nil, err
+	}
+
+	pending := make([]store.Booking, 0)
+	for _, booking := range bookings {
+		if len(booking.LostTickets) == 0 {
+			continue
+		}
+
+		refunds, err := s.List(booking.ID, "", "")
+		if err != nil {
+			return nil, err
+		}
+
+		hasRefund := false
+		for _, refund := range refunds {
+			hasRefund = hasRefund || refund.Status.IsActive()
+		}
+
+		if !hasRefund {
+			pending = append(pending, booking)
+		}
+	}
+
+	return pending, nil
+}
+
+// RefundRaffle devuelve el pago de cada reserva pagada de una rifa cancelada. Las reservas sin
+// cuenta del pagador se devuelven con pago manual aunque se pida crédito de SyPago, y las que ya
+// tienen todo su pago devuelto o en devolución se omiten. Se puede volver a ejecutar para incluir
+// los pagos que se confirmen después de la cancelación.
+func (s *Service) RefundRaffle(ctx context.Context, raffleId string, method store.RefundMethod, reason string) (*BulkResult, error) {
+	raffle, err := s.repository.GetRaffle(raffleId)
+	if err != nil {
+		return nil, err
+	}
+
+	if raffle.Status != store.RaffleCancelled {
+		return nil, fmt.Errorf("%w: raffle %s is %s, only cancelled raffles are refunded in bulk", ErrNotRefundable, raffle.ID, raffle.Status)
+	}
+
+	if !method.IsValid() {
+		return nil, fmt.Errorf("%w: invalid method: %s (valid: %s, %s)", ErrInvalidRefund, method, store.RefundSypagoCredit, store.RefundManualPayout)
+	}
+
+	bookings, err := s.repository.ListBookings(raffleId)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &BulkResult{RaffleId: raffleId, Refunds: []store.Refund{}, Skipped: []Skipped{}}
+
+	for _, booking := range bookings {
+		if booking.Status != store.BookingPaid {
+			continue
+		}
+
+		remaining, err := s.refundable(&booking)
+		if err != nil {
+			return nil, err
+		}
+
+		if !remaining.IsPositive() {
+			result.Skipped = append(result.Skipped, Skipped{BookingId: booking.ID, Reason: "already refunded"})
+			continue
+		}
+
+		bookingMethod := method
+		if bookingMethod == store.RefundSypagoCredit && booking.Payer == nil {
+			bookingMethod = store.RefundManualPayout
+		}
+
+		refund, err := s.Request(Request{BookingId: booking.ID, Amount: remaining, Reason: reason, Method: bookingMethod})
+		if err != nil {
+			result.Skipped = append(result.Skipped, Skipped{BookingId: booking.ID, Reason: err.Error()})
+			continue
+		}
+
+		if refund.Method == store.RefundSypagoCredit {
+			executed, err := s.Execute(ctx, refund.ID)
+			if err != nil {
+				// La devolución queda con el error registrado y se puede reintentar
+				fmt.Printf("Error executing refund %s: %v\n", refund.ID, err)
+				if current, getErr := s.repository.GetRefund(refund.ID); getErr == nil {
+					refund = current
+				}
+			} else {
+				refund = executed
+			}
+		}
+
+		result.Refunds = append(result.Refunds, *refund)
+	}
+
+	return result, nil
+}
+
+// pendingCredits devuelve las devoluciones cuyo crédito espera una respuesta de SyPago. Las que
+// están en revisión manual no se reenvían ni se consultan más.
+func (s *Service) pendingCredits() ([]store.Refund, error) {
+	pending := make([]store.Refund, 0)
+
+	for _, status := range []store.RefundStatus{store.RefundSending, store.RefundProcessing} {
+		refunds, err := s.List("", "", status)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, refund := range refunds {
+			if !refund.ManualReview.IsPending() {
+				pending = append(pending, refund)
+			}
+		}
+	}
+
+	return pending, nil
+}
+
+// check reenvía los créditos cuyo envío quedó incierto y consulta los que están en proceso,
+// cada uno cuando le toca según su backoff
+func (s *Service) check(ctx context.Context) {
+	refunds, err := s.pendingCredits()
+	if err != nil {
+		fmt.Printf("Error listing refunds in process: %v\n", err)
+		return
+	}
+
+	settings := backoff.SettingsFromConfig()
+	now := time.Now().UTC()
+
+	for _, refund := range refunds {
+		if ctx.Err() != nil {
+			return
+		}
+
+		if !s.schedule.Due(refund.ID, now) {
+			continue
+		}
+
+		var current *store.Refund
+		if refund.Status == store.RefundSending {
+			// El mismo internal_id permite reenviar el crédito sin pagarlo dos veces
+			current, err = s.Execute(ctx, refund.ID)
+		} else {
+			current, err = s.Refresh(ctx, refund.ID)
+		}
+
+		// Un cambio de estado reinicia el backoff
+		if err == nil && current.Status != refund.Status {
+			s.schedule.Forget(refund.ID)
+			continue
+		}
+
+		if err != nil {
+			fmt.Printf("Error checking refund %s: %v\n", refund.ID, err)
+		}
+
+		s.schedule.Retry(refund.ID, now, settings)
+	}
+
+	// Olvidar las devoluciones que ya se finalizaron por otra vía (webhook o administrador)
+	s.schedule.Sweep()
+}
+
+// Run consulta periódicamente los créditos pendientes hasta que se cancele el contexto
+func (s *Service) Run(ctx context.Context) {
+	ticker := time.NewTicker(backoff.SettingsFromConfig().Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.check(ctx)
+
+			ticker.Reset(backoff.SettingsFromConfig().Interval)
+		}
+	}
+}
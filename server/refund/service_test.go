@@ -0,0 +1,260 @@
+package refund
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"raffle_web_server/store"
+	"raffle_web_server/store/storetest"
+	"raffle_web_server/sypago"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// stubClient responde los créditos con credit y los estados con status
+type stubClient struct {
+	sypago.Client
+
+	mu      sync.Mutex
+	credit  func(request sypago.TransactionCreditRequest) (*sypago.TransactionOtpResponse, error)
+	status  string
+	queries int
+}
+
+func (c *stubClient) TransactionCredit(ctx context.Context, request sypago.TransactionCreditRequest) (*sypago.TransactionOtpResponse, error) {
+	return c.credit(request)
+}
+
+func (c *stubClient) TransactionStatus(ctx context.Context, transactionId, operationSecret string) (*sypago.TransactionStatus, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.queries++
+	return &sypago.TransactionStatus{TransactionId: transactionId, Status: c.status}, nil
+}
+
+func accepted(request sypago.TransactionCreditRequest) (*sypago.TransactionOtpResponse, error) {
+	return &sypago.TransactionOtpResponse{TransactionId: "TX-" + request.InternalId, OperationSecret: "secret"}, nil
+}
+
+func newTestService(t *testing.T, client *stubClient) *Service {
+	t.Helper()
+
+	repository := storetest.NewRepository(t)
+	secrets := storetest.NewSecretBox(t)
+
+	if err := repository.SaveRaffle(store.Raffle{ID: "raffle-test", Status: store.RaffleCancelled, Price: 10, Currency: "VES"}); err != nil {
+		t.Fatalf("SaveRaffle: %v", err)
+	}
+
+	return NewService(repository, secrets, client, func() string { return "" })
+}
+
+// requestRefund registra una reserva pagada y su devolución por crédito de SyPago
+func requestRefund(t *testing.T, service *Service, bookingId string) *store.Refund {
+	t.Helper()
+
+	now := time.Now().UTC()
+	err := service.repository.SaveBooking(store.Booking{
+		ID:       bookingId,
+		RaffleId: "raffle-test",
+		Tickets:  []int{1},
+		Status:   store.BookingPaid,
+		Amount:   decimal.NewFromInt(10),
+		Currency: "VES",
+		Payer: &store.PaymentAccount{
+			Name:           "Comprador",
+			DocumentType:   "V",
+			DocumentNumber: "12345678",
+			BankCode:       "0134",
+			AccountNumber:  "04141234567",
+		},
+		CreatedAt: now,
+		UpdatedAt: now,
+	})
+	if err != nil {
+		t.Fatalf("SaveBooking: %v", err)
+	}
+
+	refund, err := service.Request(Request{BookingId: bookingId, Method: store.RefundSypagoCredit})
+	if err != nil {
+		t.Fatalf("Request: %v", err)
+	}
+
+	return refund
+}
+
+func TestExecuteReleasesTheLockDuringTheCredit(t *testing.T) {
+	sent := make(chan struct{})
+	release := make(chan struct{})
+
+	client := &stubClient{credit: func(request sypago.TransactionCreditRequest) (*sypago.TransactionOtpResponse, error) {
+		close(sent)
+		<-release
+		return accepted(request)
+	}}
+	service := newTestService(t, client)
+
+	sending := requestRefund(t, service, "BK-A")
+	other := requestRefund(t, service, "BK-B")
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := service.Execute(context.Background(), sending.ID)
+		done <- err
+	}()
+	<-sent
+
+	// Mientras SyPago responde, las demás devoluciones se pueden administrar
+	if _, err := service.Cancel(other.ID, ""); err != nil {
+		t.Fatalf("Cancel of another refund during the credit: %v", err)
+	}
+
+	// La devolución en envío no se cancela ni se envía dos veces
+	if _, err := service.Cancel(sending.ID, ""); !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("Cancel during the credit error = %v, want %v", err, ErrInvalidTransition)
+	}
+	if _, err := service.Execute(context.Background(), sending.ID); !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("second Execute error = %v, want %v", err, ErrInvalidTransition)
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+
+	processing, err := service.Get(sending.ID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if processing.Status != store.RefundProcessing || processing.TransactionId == "" {
+		t.Fatalf("refund = %s with transaction %q, want %s with a transaction", processing.Status, processing.TransactionId, store.RefundProcessing)
+	}
+}
+
+func TestCreditWithUnknownOutcomeCannotBeCancelled(t *testing.T) {
+	var internalIds []string
+	client := &stubClient{credit: func(request sypago.TransactionCreditRequest) (*sypago.TransactionOtpResponse, error) {
+		internalIds = append(internalIds, request.InternalId)
+		if len(internalIds) == 1 {
+			return nil, context.DeadlineExceeded
+		}
+		return nil, fmt.Errorf("SyPago TransactionCredit API: %w", sypago.ErrDuplicate)
+	}}
+	service := newTestService(t, client)
+
+	refund := requestRefund(t, service, "BK-A")
+
+	if _, err := service.Execute(context.Background(), refund.ID); !errors.Is(err, ErrProviderFailed) {
+		t.Fatalf("Execute error = %v, want %v", err, ErrProviderFailed)
+	}
+
+	current, err := service.Get(refund.ID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if current.Status != store.RefundSending {
+		t.Fatalf("status after a timeout = %s, want %s", current.Status, store.RefundSending)
+	}
+	if _, err := service.Cancel(refund.ID, ""); !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("Cancel error = %v, want %v", err, ErrInvalidTransition)
+	}
+
+	// El reenvío lleva el mismo internal_id; SyPago ya lo tenía registrado
+	if _, err := service.Execute(context.Background(), refund.ID); !errors.Is(err, ErrProviderFailed) {
+		t.Fatalf("second Execute error = %v, want %v", err, ErrProviderFailed)
+	}
+	if len(internalIds) != 2 || internalIds[0] != internalIds[1] {
+		t.Fatalf("internal ids = %v, want the same id twice", internalIds)
+	}
+
+	// Después del duplicado la devolución espera la revisión manual y no se reenvía más
+	duplicated, err := service.Get(refund.ID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if duplicated.Status != store.RefundSending || !duplicated.ManualReview.IsPending() {
+		t.Fatalf("refund = %s, review %v, want it in %s pending manual review", duplicated.Status, duplicated.ManualReview, store.RefundSending)
+	}
+
+	service.check(context.Background())
+	if _, err := service.Execute(context.Background(), refund.ID); !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("Execute after the duplicate error = %v, want %v", err, ErrInvalidTransition)
+	}
+	if len(internalIds) != 2 {
+		t.Fatalf("credits sent = %d, want 2", len(internalIds))
+	}
+
+	// El back office verifica el crédito en SyPago y cierra la devolución con su referencia
+	completed, err := service.Complete(refund.ID, "REF-1", "verified in SyPago")
+	if err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if completed.Status != store.RefundCompleted || completed.Reference != "REF-1" {
+		t.Fatalf("refund = %s with reference %q, want %s with REF-1", completed.Status, completed.Reference, store.RefundCompleted)
+	}
+	if completed.ManualReview.IsPending() || completed.ManualReview.Note != "verified in SyPago" {
+		t.Fatalf("review = %v, want it reviewed with the note", completed.ManualReview)
+	}
+}
+
+func TestRefusedCreditCanBeCancelled(t *testing.T) {
+	client := &stubClient{credit: func(request sypago.TransactionCreditRequest) (*sypago.TransactionOtpResponse, error) {
+		return nil, fmt.Errorf("SyPago TransactionCredit API returned status code 400: %w", sypago.ErrRefused)
+	}}
+	service := newTestService(t, client)
+
+	refund := requestRefund(t, service, "BK-A")
+
+	if _, err := service.Execute(context.Background(), refund.ID); !errors.Is(err, ErrProviderFailed) {
+		t.Fatalf("Execute error = %v, want %v", err, ErrProviderFailed)
+	}
+
+	current, err := service.Get(refund.ID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if current.Status != store.RefundRequested || current.LastError == "" {
+		t.Fatalf("refund = %s with error %q, want %s with the error", current.Status, current.LastError, store.RefundRequested)
+	}
+
+	if _, err := service.Cancel(refund.ID, ""); err != nil {
+		t.Fatalf("Cancel: %v", err)
+	}
+}
+
+func TestCheckBacksOffPendingCredits(t *testing.T) {
+	client := &stubClient{credit: accepted, status: sypago.StatusPending}
+	service := newTestService(t, client)
+
+	refund := requestRefund(t, service, "BK-A")
+	if _, err := service.Execute(context.Background(), refund.ID); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+
+	service.check(context.Background())
+	service.check(context.Background())
+
+	if client.queries != 1 {
+		t.Fatalf("status queries = %d, want 1 before the backoff elapses", client.queries)
+	}
+
+	// Cuando llega el intento siguiente, el ACCP completa la devolución y se olvida su backoff
+	client.status = sypago.StatusAccepted
+	service.schedule.Forget(refund.ID)
+	service.check(context.Background())
+
+	completed, err := service.Get(refund.ID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if completed.Status != store.RefundCompleted {
+		t.Fatalf("status = %s, want %s", completed.Status, store.RefundCompleted)
+	}
+	if service.schedule.Scheduled(refund.ID) {
+		t.Fatal("the completed refund is still scheduled")
+	}
+}
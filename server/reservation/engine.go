@@ -0,0 +1,208 @@
+package reservation
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"raffle_web_server/config"
+	"raffle_web_server/store"
+	"sort"
+	"time"
+)
+
+const defaultHoldTTL = 10 * time.Minute
+const defaultPaymentHoldTTL = 30 * time.Minute
+const defaultReviewHoldTTL = 72 * time.Hour
+const defaultSweepInterval = 30 * time.Second
+
+// ErrInvalidTickets se devuelve cuando la lista de tickets solicitada no es válida para la rifa
+var ErrInvalidTickets = errors.New("invalid tickets")
+
+// ErrRaffleClosed se devuelve cuando la rifa ya terminó y no admite nuevas reservas
+var ErrRaffleClosed = errors.New("raffle closed")
+
+// ConflictError indica que algunos tickets ya están vendidos o retenidos por otra reserva
+type ConflictError struct {
+	Tickets []int
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("tickets not available: %v", e.Tickets)
+}
+
+// Reservation representa una retención de tickets exitosa
+type Reservation struct {
+	RaffleId  string
+	BookingId string
+	Tickets   []int
+	HeldUntil time.Time
+}
+
+// Engine administra la retención temporal de tickets mientras se completa el pago.
+// La atomicidad la garantiza el repositorio; el motor calcula los vencimientos
+// y libera periódicamente las retenciones vencidas.
+type Engine struct {
+	repository store.RaffleRepository
+}
+
+func NewEngine(repository store.RaffleRepository) *Engine {
+	return &Engine{repository: repository}
+}
+
+// HoldTTL devuelve la duración configurada de una retención
+func HoldTTL() time.Duration {
+	seconds := config.GetConfig().ReservationConfig.HoldTTLSeconds
+	if seconds <= 0 {
+		return defaultHoldTTL
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// PaymentHoldTTL devuelve cuánto se extiende la retención una vez enviado el débito,
+// mientras se espera la confirmación del pago
+func PaymentHoldTTL() time.Duration {
+	seconds := config.GetConfig().ReservationConfig.PaymentHoldTTLSeconds
+	if seconds <= 0 {
+		return defaultPaymentHoldTTL
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// ReviewHoldTTL devuelve cuánto se extiende la retención cuando el comprador envía el
+// comprobante de un pago manual, mientras un administrador lo revisa
+func ReviewHoldTTL() time.Duration {
+	seconds := config.GetConfig().ReservationConfig.ReviewHoldTTLSeconds
+	if seconds <= 0 {
+		return defaultReviewHoldTTL
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+func sweepInterval() time.Duration {
+	seconds := config.GetConfig().ReservationConfig.SweepIntervalSeconds
+	if seconds <= 0 {
+		return defaultSweepInterval
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// isOpen indica si la rifa está publicada y no ha llegado a su fecha de cierre
+func isOpen(raffle *store.Raffle) bool {
+	return raffle.Status == store.RafflePublished && time.Now().Before(raffle.EndsAt)
+}
+
+// Reserve retiene los tickets indicados para la reserva. Devuelve *ConflictError
+// con la lista real de tickets ocupados si alguno no está disponible.
+func (e *Engine) Reserve(raffle *store.Raffle, bookingId string, numbers []int) (*Reservation, error) {
+	if !isOpen(raffle) {
+		return nil, ErrRaffleClosed
+	}
+
+	if len(numbers) == 0 {
+		return nil, fmt.Errorf("%w: at least one ticket is required", ErrInvalidTickets)
+	}
+
+	seen := make(map[int]bool, len(numbers))
+	for _, number := range numbers {
+		if seen[number] {
+			return nil, fmt.Errorf("%w: ticket number %d is duplicated", ErrInvalidTickets, number)
+		}
+		seen[number] = true
+
+		if !raffle.ContainsTicket(number) {
+			return nil, fmt.Errorf("%w: ticket number %d is not valid for raffle %s. Valid range: %d-%d",
+				ErrInvalidTickets, number, raffle.ID, raffle.InitialTicket, raffle.LastTicket())
+		}
+	}
+
+	heldUntil := time.Now().UTC().Add(HoldTTL())
+
+	conflicts, err := e.repository.HoldTickets(raffle.ID, bookingId, numbers, heldUntil)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(conflicts) > 0 {
+		return nil, &ConflictError{Tickets: conflicts}
+	}
+
+	tickets := append([]int(nil), numbers...)
+	sort.Ints(tickets)
+
+	return &Reservation{
+		RaffleId:  raffle.ID,
+		BookingId: bookingId,
+		Tickets:   tickets,
+		HeldUntil: heldUntil,
+	}, nil
+}
+
+// ReserveRandom elige al azar quantity tickets libres de la rifa y los retiene
+// para la reserva en un único paso atómico. Usa un generador criptográficamente seguro.
+func (e *Engine) ReserveRandom(raffle *store.Raffle, bookingId string, quantity int) (*Reservation, error) {
+	if !isOpen(raffle) {
+		return nil, ErrRaffleClosed
+	}
+
+	if quantity <= 0 {
+		return nil, fmt.Errorf("%w: ticket quantity must be greater than 0", ErrInvalidTickets)
+	}
+
+	if quantity > raffle.TicketsTotal {
+		return nil, fmt.Errorf("%w: ticket quantity %d exceeds the %d tickets of raffle %s",
+			ErrInvalidTickets, quantity, raffle.TicketsTotal, raffle.ID)
+	}
+
+	heldUntil := time.Now().UTC().Add(HoldTTL())
+
+	tickets, err := e.repository.HoldRandomTickets(*raffle, bookingId, quantity, heldUntil, secureRandomIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Reservation{
+		RaffleId:  raffle.ID,
+		BookingId: bookingId,
+		Tickets:   tickets,
+		HeldUntil: heldUntil,
+	}, nil
+}
+
+// secureRandomIndex devuelve un índice uniforme en [0, n) usando crypto/rand
+func secureRandomIndex(n int) (int, error) {
+	index, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, fmt.Errorf("error generating random ticket index: %v", err)
+	}
+	return int(index.Int64()), nil
+}
+
+// Release libera los tickets retenidos por una reserva que no completó el pago
+func (e *Engine) Release(raffleId, bookingId string) ([]int, error) {
+	return e.repository.ReleaseTickets(raffleId, bookingId)
+}
+
+// Run libera periódicamente las retenciones vencidas hasta que se cancele el contexto
+func (e *Engine) Run(ctx context.Context) {
+	ticker := time.NewTicker(sweepInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			released, err := e.repository.ReleaseExpiredHolds(time.Now().UTC())
+			if err != nil {
+				fmt.Printf("Error releasing expired ticket holds: %v\n", err)
+				continue
+			}
+			if len(released) > 0 {
+				fmt.Printf("Released %d expired ticket holds\n", len(released))
+			}
+			ticker.Reset(sweepInterval())
+		}
+	}
+}
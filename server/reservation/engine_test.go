@@ -0,0 +1,207 @@
+package reservation
+
+import (
+	"errors"
+	"fmt"
+	"raffle_web_server/store"
+	"raffle_web_server/store/storetest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestEngine(t *testing.T) (*Engine, *store.FileRepository, *store.Raffle) {
+	t.Helper()
+
+	repository := storetest.NewRepository(t)
+
+	now := time.Now().UTC()
+	raffle := store.Raffle{
+		ID:            "raffle-test",
+		Title:         "Test",
+		Price:         1,
+		Currency:      "USD",
+		InitialTicket: 1,
+		TicketsTotal:  100,
+		EndsAt:        now.Add(time.Hour),
+		Status:        store.RafflePublished,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if err := repository.SaveRaffle(raffle); err != nil {
+		t.Fatalf("SaveRaffle: %v", err)
+	}
+
+	return NewEngine(repository), repository, &raffle
+}
+
+func TestReserveSameTicketConcurrently(t *testing.T) {
+	engine, repository, raffle := newTestEngine(t)
+
+	const attempts = 20
+
+	var wg sync.WaitGroup
+	results := make(chan error, attempts)
+	start := make(chan struct{})
+
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			_, err := engine.Reserve(raffle, fmt.Sprintf("BK-%d", i), []int{7, 8})
+			results <- err
+		}(i)
+	}
+
+	close(start)
+	wg.Wait()
+	close(results)
+
+	successes := 0
+	for err := range results {
+		var conflictErr *ConflictError
+		switch {
+		case err == nil:
+			successes++
+		case errors.As(err, &conflictErr):
+			if len(conflictErr.Tickets) != 2 {
+				t.Errorf("conflict tickets = %v, want [7 8]", conflictErr.Tickets)
+			}
+		default:
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+
+	if successes != 1 {
+		t.Fatalf("successful reservations = %d, want 1", successes)
+	}
+
+	for _, number := range []int{7, 8} {
+		ticket, err := repository.GetTicket(raffle.ID, number)
+		if err != nil {
+			t.Fatalf("GetTicket(%d): %v", number, err)
+		}
+		if ticket.Status != store.TicketReserved {
+			t.Errorf("ticket %d status = %s, want %s", number, ticket.Status, store.TicketReserved)
+		}
+	}
+}
+
+func TestReserveRandomConcurrentlyDoesNotOverlap(t *testing.T) {
+	engine, _, raffle := newTestEngine(t)
+
+	const attempts = 10
+	const quantity = 10
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	owners := make(map[int]string)
+
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func(bookingId string) {
+			defer wg.Done()
+
+			hold, err := engine.ReserveRandom(raffle, bookingId, quantity)
+			if err != nil {
+				t.Errorf("ReserveRandom(%s): %v", bookingId, err)
+				return
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			for _, number := range hold.Tickets {
+				if owner, taken := owners[number]; taken {
+					t.Errorf("ticket %d held by %s and %s", number, owner, bookingId)
+				}
+				owners[number] = bookingId
+			}
+		}(fmt.Sprintf("BK-%d", i))
+	}
+
+	wg.Wait()
+
+	if len(owners) != attempts*quantity {
+		t.Fatalf("held tickets = %d, want %d", len(owners), attempts*quantity)
+	}
+
+	_, err := engine.ReserveRandom(raffle, "BK-extra", 1)
+	if !errors.Is(err, store.ErrNotEnoughTickets) {
+		t.Fatalf("ReserveRandom on a full raffle: err = %v, want %v", err, store.ErrNotEnoughTickets)
+	}
+}
+
+func TestReserveRandomOnlyPicksFreeTickets(t *testing.T) {
+	engine, repository, raffle := newTestEngine(t)
+
+	sold := make([]int, 0, 60)
+	for number := 1; number <= 60; number++ {
+		sold = append(sold, number)
+	}
+	if _, err := repository.SellTickets(raffle.ID, "BK-sold", sold); err != nil {
+		t.Fatalf("SellTickets: %v", err)
+	}
+
+	held := make([]int, 0, 35)
+	for number := 61; number <= 95; number++ {
+		held = append(held, number)
+	}
+	if _, err := engine.Reserve(raffle, "BK-held", held); err != nil {
+		t.Fatalf("Reserve: %v", err)
+	}
+
+	// Solo quedan libres los tickets 96 a 100
+	hold, err := engine.ReserveRandom(raffle, "BK-random", 5)
+	if err != nil {
+		t.Fatalf("ReserveRandom: %v", err)
+	}
+	if want := []int{96, 97, 98, 99, 100}; fmt.Sprint(hold.Tickets) != fmt.Sprint(want) {
+		t.Fatalf("random tickets = %v, want %v", hold.Tickets, want)
+	}
+
+	for _, number := range hold.Tickets {
+		ticket, err := repository.GetTicket(raffle.ID, number)
+		if err != nil {
+			t.Fatalf("GetTicket(%d): %v", number, err)
+		}
+		if ticket.Status != store.TicketReserved || ticket.BookingId != "BK-random" {
+			t.Errorf("ticket %d = %s for %s, want %s for BK-random", number, ticket.Status, ticket.BookingId, store.TicketReserved)
+		}
+	}
+
+	if _, err := engine.ReserveRandom(raffle, "BK-extra", 1); !errors.Is(err, store.ErrNotEnoughTickets) {
+		t.Fatalf("ReserveRandom without free tickets: err = %v, want %v", err, store.ErrNotEnoughTickets)
+	}
+}
+
+func TestReserveRandomPicksWithinTheRaffleRange(t *testing.T) {
+	engine, repository, raffle := newTestEngine(t)
+
+	raffle.InitialTicket = 500
+	raffle.TicketsTotal = 20
+	if err := repository.SaveRaffle(*raffle); err != nil {
+		t.Fatalf("SaveRaffle: %v", err)
+	}
+
+	hold, err := engine.ReserveRandom(raffle, "BK-random", 20)
+	if err != nil {
+		t.Fatalf("ReserveRandom: %v", err)
+	}
+
+	for i, number := range hold.Tickets {
+		if number != raffle.InitialTicket+i {
+			t.Fatalf("random tickets = %v, want every ticket from %d to %d", hold.Tickets, raffle.InitialTicket, raffle.LastTicket())
+		}
+	}
+}
+
+func TestReserveRandomValidatesTheQuantity(t *testing.T) {
+	engine, _, raffle := newTestEngine(t)
+
+	for _, quantity := range []int{0, -1, raffle.TicketsTotal + 1} {
+		if _, err := engine.ReserveRandom(raffle, "BK-random", quantity); !errors.Is(err, ErrInvalidTickets) {
+			t.Errorf("ReserveRandom(%d): err = %v, want %v", quantity, err, ErrInvalidTickets)
+		}
+	}
+}
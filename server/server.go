@@ -1,14 +1,25 @@
 package main
 
 import (
+	"context"
 	"fmt"
-	// "net/http"
+	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"raffle_web_server/admin"
+	"raffle_web_server/booking"
 	"raffle_web_server/config"
+	"raffle_web_server/draw"
+	"raffle_web_server/exchange"
 	// "raffle_web_server/middlewares"
 	"raffle_web_server/mock"
+	"raffle_web_server/payment"
+	"raffle_web_server/refund"
+	"raffle_web_server/reservation"
+	"raffle_web_server/store"
+	"raffle_web_server/sypago"
+	"sync"
 	"syscall"
 
 	"github.com/gin-gonic/gin"
@@ -30,7 +41,7 @@ func SetCORSHeaders() gin.HandlerFunc {
 		}
 
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, Idempotency-Key")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
 
 		if c.Request.Method == "OPTIONS" {
@@ -56,7 +67,25 @@ func SecurityHeaders() gin.HandlerFunc {
 	}
 }
 
+// resolveDataPath devuelve la ruta absoluta de un archivo o directorio de datos; sin ruta
+// configurada usa data/defaultName junto al ejecutable
+func resolveDataPath(execPath, configuredPath, defaultName string) string {
+	dataPath := configuredPath
+
+	if dataPath == "" {
+		dataPath = filepath.Join("data", defaultName)
+	}
+
+	if !filepath.IsAbs(dataPath) {
+		dataPath = filepath.Join(execPath, dataPath)
+	}
+
+	return dataPath
+}
+
 func main() {
+	config.Load()
+
 	decimal.MarshalJSONWithoutQuotes = true
 
 	execPath, err := os.Executable()
@@ -70,10 +99,30 @@ func main() {
 
 	signal.Notify(done, syscall.SIGINT, syscall.SIGTERM)
 
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+
+	// workers agrupa los procesos en segundo plano para esperarlos al apagar el servidor
+	var workers sync.WaitGroup
+
+	startWorker := func(run func(ctx context.Context)) {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			run(ctx)
+		}()
+	}
+
 	gin.SetMode(gin.ReleaseMode)
 
 	router := gin.Default()
 
+	// Solo se acepta X-Forwarded-For de los proxies configurados; sin proxies ClientIP usa la
+	// dirección de la conexión, para que los límites por IP no se puedan evadir con el header
+	if err := router.SetTrustedProxies(config.GetConfig().ServiceInfo.TrustedProxies); err != nil {
+		panic(err)
+	}
+
 	//router.Use(SecurityHeaders())
 
 	router.Use(SetCORSHeaders())
@@ -90,7 +139,98 @@ func main() {
 	// 	NewStaticAssetsConfig(webAssetsDir, "/", "index.html", []string{}, []string{}, nil)))
 
 	if config.GetConfig().MockConfig.Enabled {
-		mock.ActivateRoutesForMock(router)
+
+		storeConfig := config.GetConfig().StoreConfig
+
+		repository, err := store.NewFileRepository(resolveDataPath(execPath, storeConfig.Path, "store.json"))
+		if err != nil {
+			panic(err)
+		}
+
+		secrets, err := store.LoadSecretBox(resolveDataPath(execPath, storeConfig.SecretKeyPath, "secret.key"))
+		if err != nil {
+			panic(err)
+		}
+
+		reservations := reservation.NewEngine(repository)
+		startWorker(reservations.Run)
+
+		draws := draw.NewService(repository, secrets, draw.BeaconFromConfig())
+		startWorker(draws.Run)
+
+		rateProvider, err := exchange.ProviderFromConfig()
+		if err != nil {
+			panic(err)
+		}
+
+		rates := exchange.NewService(repository, rateProvider)
+
+		bookings := booking.NewService(repository, secrets, rates.CurrentRate)
+
+		// Con SypagoConfig.Fake los pagos se procesan contra un SyPago simulado, sin conexión
+		sypagoSettings := sypago.SettingsFromConfig
+		if config.GetConfig().SypagoConfig.Fake {
+			fake := sypago.NewFakeServer()
+			defer fake.Close()
+
+			sypagoSettings = fake.Settings
+			fmt.Println("Using fake SyPago server at", fake.URL())
+		}
+
+		sypagoClient := sypago.NewClient(&http.Client{}, sypagoSettings)
+
+		banks := sypago.NewBankCatalog(sypagoClient)
+		startWorker(banks.Run)
+
+		rejectCodes, err := sypago.LoadRejectCatalog(config.GetConfig().SypagoConfig.RejectCodesPath())
+		if err != nil {
+			panic(err)
+		}
+
+		receipts, err := payment.NewReceiptStore(resolveDataPath(execPath, config.GetConfig().PaymentConfig.ReceiptsDir, "receipts"))
+		if err != nil {
+			panic(err)
+		}
+
+		refunds := refund.NewService(repository, secrets, sypagoClient, mock.WebhookEndpoint)
+		startWorker(refunds.Run)
+
+		sypagoDebit := payment.NewSypago(bookings, refunds, sypagoClient, rejectCodes, mock.WebhookEndpoint)
+
+		providers := []payment.Provider{sypagoDebit, payment.NewManual(bookings, refunds)}
+
+		// Pago Móvil solo se ofrece si hay de dónde leer los movimientos para verificar las referencias
+		if movements := payment.MovementsFromConfig(); movements != nil {
+			providers = append(providers, payment.NewPagoMovil(bookings, refunds, movements))
+		}
+
+		payments := payment.NewRegistry(providers...)
+
+		reconciler := booking.NewReconciler(bookings, payments.FetchPaymentResult)
+		startWorker(reconciler.Run)
+
+		mock.ActivateRoutesForMock(router, mock.Services{
+			Repository:   repository,
+			Reservations: reservations,
+			Draws:        draws,
+			Bookings:     bookings,
+			SypagoDebit:  sypagoDebit,
+			Banks:        banks,
+			RejectCodes:  rejectCodes,
+			Payments:     payments,
+			Receipts:     receipts,
+			Refunds:      refunds,
+		})
+
+		admin.ActivateRoutes(router, admin.Services{
+			Repository: repository,
+			Draws:      draws,
+			Exchange:   rates,
+			Payments:   payments,
+			Bookings:   bookings,
+			Receipts:   receipts,
+			Refunds:    refunds,
+		})
 	}
 
 	fmt.Println("Starting REST API server on port", config.GetConfig().ServiceInfo.HttpPort)
@@ -119,4 +259,9 @@ func main() {
 	}(router)
 
 	<-done
+
+	fmt.Println("Shutting down, waiting for background workers")
+
+	stop()
+	workers.Wait()
 }
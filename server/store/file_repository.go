@@ -0,0 +1,864 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// fileState es el contenido completo que se guarda en disco
+type fileState struct {
+	Raffles      map[string]*Raffle             `json:"raffles"`
+	Tickets      map[string]map[int]*Ticket     `json:"tickets"`
+	Participants map[string]*Participant        `json:"participants"`
+	Bookings     map[string]*Booking            `json:"bookings"`
+	Draws        map[string]*Draw               `json:"draws"`
+	Prizes       map[string]*Prize              `json:"prizes"`
+	Idempotency  map[string]*IdempotentResponse `json:"idempotency"`
+	Rates        map[string]*ExchangeRate       `json:"rates"`
+	Refunds      map[string]*Refund             `json:"refunds"`
+}
+
+func newFileState() *fileState {
+	return &fileState{
+		Raffles:      make(map[string]*Raffle),
+		Tickets:      make(map[string]map[int]*Ticket),
+		Participants: make(map[string]*Participant),
+		Bookings:     make(map[string]*Booking),
+		Draws:        make(map[string]*Draw),
+		Prizes:       make(map[string]*Prize),
+		Idempotency:  make(map[string]*IdempotentResponse),
+		Rates:        make(map[string]*ExchangeRate),
+		Refunds:      make(map[string]*Refund),
+	}
+}
+
+// FileRepository implementa RaffleRepository sobre un archivo JSON.
+// Todo el estado vive en memoria y se escribe completo en disco en cada
+// modificación, usando un archivo temporal + fsync + rename para que la escritura sea atómica.
+// Si la escritura falla, el estado en memoria vuelve a lo último que quedó en disco.
+type FileRepository struct {
+	path  string
+	state *fileState
+	saved []byte
+	mu    sync.RWMutex
+}
+
+// NewFileRepository abre (o crea) el archivo de datos en la ruta indicada.
+// Si el archivo no existe se inicializa con las rifas por defecto.
+func NewFileRepository(path string) (*FileRepository, error) {
+	repo := &FileRepository{
+		path:  path,
+		state: newFileState(),
+	}
+
+	content, err := os.ReadFile(path)
+
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("error reading store file %s: %v", path, err)
+	}
+
+	if len(content) > 0 {
+		if err := json.Unmarshal(content, repo.state); err != nil {
+			return nil, fmt.Errorf("error parsing store file %s: %v", path, err)
+		}
+		repo.state.ensureMaps()
+		repo.state.applyDefaults()
+		repo.saved = content
+		return repo, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("error creating store directory: %v", err)
+	}
+
+	now := time.Now()
+
+	for _, raffle := range defaultRaffles(now) {
+		r := raffle
+		repo.state.Raffles[r.ID] = &r
+	}
+
+	for _, prize := range defaultPrizes(now) {
+		p := prize
+		repo.state.Prizes[p.ID] = &p
+	}
+
+	if err := repo.persist(); err != nil {
+		return nil, err
+	}
+
+	return repo, nil
+}
+
+// ensureMaps inicializa los mapas que no vinieron en el archivo
+func (s *fileState) ensureMaps() {
+	if s.Raffles == nil {
+		s.Raffles = make(map[string]*Raffle)
+	}
+	if s.Tickets == nil {
+		s.Tickets = make(map[string]map[int]*Ticket)
+	}
+	if s.Participants == nil {
+		s.Participants = make(map[string]*Participant)
+	}
+	if s.Bookings == nil {
+		s.Bookings = make(map[string]*Booking)
+	}
+	if s.Draws == nil {
+		s.Draws = make(map[string]*Draw)
+	}
+	if s.Prizes == nil {
+		s.Prizes = make(map[string]*Prize)
+	}
+	if s.Idempotency == nil {
+		s.Idempotency = make(map[string]*IdempotentResponse)
+	}
+	if s.Rates == nil {
+		s.Rates = make(map[string]*ExchangeRate)
+	}
+	if s.Refunds == nil {
+		s.Refunds = make(map[string]*Refund)
+	}
+}
+
+// applyDefaults completa los campos agregados después de creado el archivo
+func (s *fileState) applyDefaults() {
+	for _, raffle := range s.Raffles {
+		if raffle.Status == "" {
+			raffle.Status = RafflePublished
+		}
+	}
+	for _, booking := range s.Bookings {
+		if booking.Status == "" {
+			booking.Status = BookingReserved
+		}
+
+		// Antes se expiraban también las reservas con un pago en curso; vuelven a esperar su resultado
+		if booking.Status == BookingExpired && booking.TransactionId != "" {
+			booking.Status = BookingDebitSubmitted
+		}
+		if booking.Status == BookingExpired && booking.Proof != nil && booking.Proof.ReviewedAt == nil {
+			booking.Status = BookingPendingReview
+		}
+	}
+}
+
+// persist escribe el estado en disco. Debe llamarse con el lock de escritura tomado.
+// Si no se puede escribir, descarta los cambios en memoria para que no queden
+// tickets retenidos o reservas modificadas que no sobrevivirían a un reinicio.
+func (f *FileRepository) persist() error {
+	content, err := json.Marshal(f.state)
+	if err != nil {
+		f.rollback()
+		return fmt.Errorf("error marshaling store state: %v", err)
+	}
+
+	if err := f.write(content); err != nil {
+		f.rollback()
+		return err
+	}
+
+	// El archivo ya tiene el estado nuevo: aunque falle la sincronización del
+	// directorio, la memoria debe coincidir con lo que se leería del disco
+	f.saved = content
+	return f.syncDir()
+}
+
+// write reemplaza el archivo de datos con content y espera a que quede en el disco
+func (f *FileRepository) write(content []byte) error {
+	tmpPath := f.path + ".tmp"
+
+	file, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("error writing store file: %v", err)
+	}
+
+	if _, err := file.Write(content); err != nil {
+		file.Close()
+		return fmt.Errorf("error writing store file: %v", err)
+	}
+
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return fmt.Errorf("error syncing store file: %v", err)
+	}
+
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("error writing store file: %v", err)
+	}
+
+	if err := os.Rename(tmpPath, f.path); err != nil {
+		return fmt.Errorf("error replacing store file: %v", err)
+	}
+
+	return nil
+}
+
+// syncDir hace durable el rename sincronizando el directorio que contiene el archivo
+func (f *FileRepository) syncDir() error {
+	dir, err := os.Open(filepath.Dir(f.path))
+	if err != nil {
+		return fmt.Errorf("error syncing store directory: %v", err)
+	}
+	defer dir.Close()
+
+	if err := dir.Sync(); err != nil {
+		return fmt.Errorf("error syncing store directory: %v", err)
+	}
+
+	return nil
+}
+
+// rollback devuelve el estado en memoria a la última versión guardada en disco
+func (f *FileRepository) rollback() {
+	state := newFileState()
+	if len(f.saved) > 0 {
+		if err := json.Unmarshal(f.saved, state); err != nil {
+			return
+		}
+		state.ensureMaps()
+		state.applyDefaults()
+	}
+
+	f.state = state
+}
+
+func (f *FileRepository) ListRaffles() ([]Raffle, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	raffles := make([]Raffle, 0, len(f.state.Raffles))
+	for _, raffle := range f.state.Raffles {
+		raffles = append(raffles, *raffle)
+	}
+
+	sort.Slice(raffles, func(i, j int) bool {
+		return raffles[i].InitialTicket < raffles[j].InitialTicket
+	})
+
+	return raffles, nil
+}
+
+func (f *FileRepository) GetRaffle(id string) (*Raffle, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	raffle, exists := f.state.Raffles[id]
+	if !exists {
+		return nil, ErrNotFound
+	}
+
+	copied := *raffle
+	return &copied, nil
+}
+
+func (f *FileRepository) SaveRaffle(raffle Raffle) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.state.Raffles[raffle.ID] = &raffle
+	return f.persist()
+}
+
+func (f *FileRepository) DeleteRaffle(id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, exists := f.state.Raffles[id]; !exists {
+		return ErrNotFound
+	}
+
+	delete(f.state.Raffles, id)
+	delete(f.state.Tickets, id)
+	delete(f.state.Draws, id)
+
+	for prizeId, prize := range f.state.Prizes {
+		if prize.RaffleId == id {
+			delete(f.state.Prizes, prizeId)
+		}
+	}
+
+	return f.persist()
+}
+
+func (f *FileRepository) ListTickets(raffleId string) ([]Ticket, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	raffleTickets := f.state.Tickets[raffleId]
+	tickets := make([]Ticket, 0, len(raffleTickets))
+	for _, ticket := range raffleTickets {
+		tickets = append(tickets, *ticket)
+	}
+
+	sort.Slice(tickets, func(i, j int) bool {
+		return tickets[i].Number < tickets[j].Number
+	})
+
+	return tickets, nil
+}
+
+func (f *FileRepository) GetTicket(raffleId string, number int) (*Ticket, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	ticket, exists := f.state.Tickets[raffleId][number]
+	if !exists {
+		return nil, ErrNotFound
+	}
+
+	copied := *ticket
+	return &copied, nil
+}
+
+func (f *FileRepository) CountTickets(raffleId string, status TicketStatus) (int, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	count := 0
+	for _, ticket := range f.state.Tickets[raffleId] {
+		if ticket.Status == status {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func (f *FileRepository) SaveTickets(tickets []Ticket) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, ticket := range tickets {
+		t := ticket
+		if f.state.Tickets[t.RaffleId] == nil {
+			f.state.Tickets[t.RaffleId] = make(map[int]*Ticket)
+		}
+		f.state.Tickets[t.RaffleId][t.Number] = &t
+	}
+
+	return f.persist()
+}
+
+func (f *FileRepository) GetParticipant(id string) (*Participant, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	participant, exists := f.state.Participants[id]
+	if !exists {
+		return nil, ErrNotFound
+	}
+
+	copied := *participant
+	return &copied, nil
+}
+
+func (f *FileRepository) SaveParticipant(participant Participant) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.state.Participants[participant.ID] = &participant
+	return f.persist()
+}
+
+func (f *FileRepository) GetBooking(id string) (*Booking, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	booking, exists := f.state.Bookings[id]
+	if !exists {
+		return nil, ErrNotFound
+	}
+
+	copied := *booking
+	copied.Tickets = append([]int(nil), booking.Tickets...)
+	return &copied, nil
+}
+
+func (f *FileRepository) SaveBooking(booking Booking) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	booking.Tickets = append([]int(nil), booking.Tickets...)
+	f.state.Bookings[booking.ID] = &booking
+	return f.persist()
+}
+
+func (f *FileRepository) ListBookings(raffleId string) ([]Booking, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	bookings := make([]Booking, 0)
+	for _, booking := range f.state.Bookings {
+		if booking.RaffleId != raffleId {
+			continue
+		}
+		copied := *booking
+		copied.Tickets = append([]int(nil), booking.Tickets...)
+		bookings = append(bookings, copied)
+	}
+
+	sort.Slice(bookings, func(i, j int) bool {
+		return bookings[i].CreatedAt.Before(bookings[j].CreatedAt)
+	})
+
+	return bookings, nil
+}
+
+func (f *FileRepository) ListBookingsByStatus(status BookingStatus) ([]Booking, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	bookings := make([]Booking, 0)
+	for _, booking := range f.state.Bookings {
+		if booking.Status != status {
+			continue
+		}
+		copied := *booking
+		copied.Tickets = append([]int(nil), booking.Tickets...)
+		bookings = append(bookings, copied)
+	}
+
+	sort.Slice(bookings, func(i, j int) bool {
+		return bookings[i].CreatedAt.Before(bookings[j].CreatedAt)
+	})
+
+	return bookings, nil
+}
+
+func (f *FileRepository) FindBookingByPayment(internalId, transactionId string) (*Booking, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	for _, booking := range f.state.Bookings {
+		if (internalId != "" && booking.InternalId == internalId) ||
+			(transactionId != "" && booking.TransactionId == transactionId) {
+			copied := *booking
+			copied.Tickets = append([]int(nil), booking.Tickets...)
+			return &copied, nil
+		}
+	}
+
+	return nil, ErrNotFound
+}
+
+func (f *FileRepository) HoldTickets(raffleId, bookingId string, numbers []int, heldUntil time.Time) ([]int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := time.Now().UTC()
+	raffleTickets := f.state.Tickets[raffleId]
+
+	conflicts := make([]int, 0)
+	for _, number := range numbers {
+		ticket, exists := raffleTickets[number]
+		if !exists || !ticket.IsHeld(now) {
+			continue
+		}
+		if ticket.Status == TicketReserved && ticket.BookingId == bookingId {
+			continue
+		}
+		conflicts = append(conflicts, number)
+	}
+
+	if len(conflicts) > 0 {
+		sort.Ints(conflicts)
+		return conflicts, nil
+	}
+
+	if raffleTickets == nil {
+		raffleTickets = make(map[int]*Ticket)
+		f.state.Tickets[raffleId] = raffleTickets
+	}
+
+	for _, number := range numbers {
+		raffleTickets[number] = &Ticket{
+			RaffleId:  raffleId,
+			Number:    number,
+			Status:    TicketReserved,
+			BookingId: bookingId,
+			HeldUntil: heldUntil,
+			UpdatedAt: now,
+		}
+	}
+
+	return conflicts, f.persist()
+}
+
+func (f *FileRepository) ExtendHold(raffleId, bookingId string, numbers []int, heldUntil time.Time) ([]int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := time.Now().UTC()
+	raffleTickets := f.state.Tickets[raffleId]
+	if raffleTickets == nil {
+		raffleTickets = make(map[int]*Ticket)
+		f.state.Tickets[raffleId] = raffleTickets
+	}
+
+	conflicts := make([]int, 0)
+	for _, number := range numbers {
+		ticket, exists := raffleTickets[number]
+		if exists && ticket.IsHeld(now) && (ticket.Status == TicketSold || ticket.BookingId != bookingId) {
+			conflicts = append(conflicts, number)
+			continue
+		}
+
+		raffleTickets[number] = &Ticket{
+			RaffleId:  raffleId,
+			Number:    number,
+			Status:    TicketReserved,
+			BookingId: bookingId,
+			HeldUntil: heldUntil,
+			UpdatedAt: now,
+		}
+	}
+
+	sort.Ints(conflicts)
+	return conflicts, f.persist()
+}
+
+func (f *FileRepository) HoldRandomTickets(raffle Raffle, bookingId string, quantity int, heldUntil time.Time, randomIndex func(n int) (int, error)) ([]int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := time.Now().UTC()
+	raffleTickets := f.state.Tickets[raffle.ID]
+
+	free := make([]int, 0, raffle.TicketsTotal-len(raffleTickets))
+	for number := raffle.InitialTicket; number <= raffle.LastTicket(); number++ {
+		if ticket, exists := raffleTickets[number]; exists && ticket.IsHeld(now) {
+			continue
+		}
+		free = append(free, number)
+	}
+
+	if len(free) < quantity {
+		return nil, ErrNotEnoughTickets
+	}
+
+	// Fisher-Yates parcial: solo se mezclan las primeras quantity posiciones
+	for i := 0; i < quantity; i++ {
+		j, err := randomIndex(len(free) - i)
+		if err != nil {
+			return nil, err
+		}
+		free[i], free[i+j] = free[i+j], free[i]
+	}
+
+	picked := append([]int(nil), free[:quantity]...)
+	sort.Ints(picked)
+
+	if raffleTickets == nil {
+		raffleTickets = make(map[int]*Ticket)
+		f.state.Tickets[raffle.ID] = raffleTickets
+	}
+
+	for _, number := range picked {
+		raffleTickets[number] = &Ticket{
+			RaffleId:  raffle.ID,
+			Number:    number,
+			Status:    TicketReserved,
+			BookingId: bookingId,
+			HeldUntil: heldUntil,
+			UpdatedAt: now,
+		}
+	}
+
+	return picked, f.persist()
+}
+
+func (f *FileRepository) ReleaseTickets(raffleId, bookingId string) ([]int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	released := make([]int, 0)
+	for number, ticket := range f.state.Tickets[raffleId] {
+		if ticket.Status == TicketReserved && ticket.BookingId == bookingId {
+			delete(f.state.Tickets[raffleId], number)
+			released = append(released, number)
+		}
+	}
+
+	if len(released) == 0 {
+		return released, nil
+	}
+
+	sort.Ints(released)
+	return released, f.persist()
+}
+
+func (f *FileRepository) ReleaseExpiredHolds(now time.Time) ([]Ticket, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	released := make([]Ticket, 0)
+	for _, raffleTickets := range f.state.Tickets {
+		for number, ticket := range raffleTickets {
+			if ticket.Status == TicketReserved && !ticket.IsHeld(now) {
+				released = append(released, *ticket)
+				delete(raffleTickets, number)
+			}
+		}
+	}
+
+	if len(released) == 0 {
+		return released, nil
+	}
+
+	// Las reservas que no enviaron el pago expiran junto con sus tickets. Las que esperan el
+	// resultado de un débito o la revisión de un comprobante siguen esperándolo: si el pago
+	// se confirma después, se vende lo que siga libre y se devuelve el resto.
+	for _, ticket := range released {
+		booking, exists := f.state.Bookings[ticket.BookingId]
+		if exists && booking.Status.CanTransitionTo(BookingExpired) {
+			booking.Status = BookingExpired
+			booking.UpdatedAt = now
+		}
+	}
+
+	return released, f.persist()
+}
+
+func (f *FileRepository) SellTickets(raffleId, bookingId string, numbers []int) ([]int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := time.Now().UTC()
+	raffleTickets := f.state.Tickets[raffleId]
+	if raffleTickets == nil {
+		raffleTickets = make(map[int]*Ticket)
+		f.state.Tickets[raffleId] = raffleTickets
+	}
+
+	conflicts := make([]int, 0)
+	for _, number := range numbers {
+		ticket, exists := raffleTickets[number]
+		if exists && ticket.BookingId != bookingId && ticket.IsHeld(now) {
+			conflicts = append(conflicts, number)
+			continue
+		}
+
+		raffleTickets[number] = &Ticket{
+			RaffleId:  raffleId,
+			Number:    number,
+			Status:    TicketSold,
+			BookingId: bookingId,
+			UpdatedAt: now,
+		}
+	}
+
+	sort.Ints(conflicts)
+	return conflicts, f.persist()
+}
+
+func (f *FileRepository) GetDraw(raffleId string) (*Draw, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	draw, exists := f.state.Draws[raffleId]
+	if !exists {
+		return nil, ErrNotFound
+	}
+
+	copied := *draw
+	copied.SoldTickets = append([]int(nil), draw.SoldTickets...)
+	copied.MainWinners = append([]int(nil), draw.MainWinners...)
+	copied.BlessWinners = append([]int(nil), draw.BlessWinners...)
+	return &copied, nil
+}
+
+func (f *FileRepository) SaveDraw(draw Draw) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.state.Draws[draw.RaffleId] = &draw
+	return f.persist()
+}
+
+// prizeKindOrder define el orden de los grupos dentro del catálogo
+var prizeKindOrder = map[PrizeKind]int{
+	PrizeMain:      0,
+	PrizeSecondary: 1,
+	PrizeBless:     2,
+}
+
+func (f *FileRepository) ListPrizes(raffleId string) ([]Prize, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	prizes := make([]Prize, 0)
+	for _, prize := range f.state.Prizes {
+		if prize.RaffleId == raffleId {
+			prizes = append(prizes, *prize)
+		}
+	}
+
+	sort.Slice(prizes, func(i, j int) bool {
+		if prizes[i].Kind != prizes[j].Kind {
+			return prizeKindOrder[prizes[i].Kind] < prizeKindOrder[prizes[j].Kind]
+		}
+		if prizes[i].Rank != prizes[j].Rank {
+			return prizes[i].Rank < prizes[j].Rank
+		}
+		return prizes[i].ID < prizes[j].ID
+	})
+
+	return prizes, nil
+}
+
+func (f *FileRepository) GetPrize(id string) (*Prize, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	prize, exists := f.state.Prizes[id]
+	if !exists {
+		return nil, ErrNotFound
+	}
+
+	copied := *prize
+	return &copied, nil
+}
+
+func (f *FileRepository) SavePrize(prize Prize) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.state.Prizes[prize.ID] = &prize
+	return f.persist()
+}
+
+func (f *FileRepository) DeletePrize(id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, exists := f.state.Prizes[id]; !exists {
+		return ErrNotFound
+	}
+
+	delete(f.state.Prizes, id)
+	return f.persist()
+}
+
+func (f *FileRepository) GetIdempotentResponse(key string, now time.Time) (*IdempotentResponse, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	response, exists := f.state.Idempotency[key]
+	if !exists || !now.Before(response.ExpiresAt) {
+		return nil, ErrNotFound
+	}
+
+	copied := *response
+	return &copied, nil
+}
+
+func (f *FileRepository) SaveIdempotentResponse(response IdempotentResponse) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for key, stored := range f.state.Idempotency {
+		if !response.CreatedAt.Before(stored.ExpiresAt) {
+			delete(f.state.Idempotency, key)
+		}
+	}
+
+	f.state.Idempotency[response.Key] = &response
+	return f.persist()
+}
+
+func (f *FileRepository) GetExchangeRate(date, from, to string) (*ExchangeRate, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	rate, exists := f.state.Rates[ExchangeRateKey(date, from, to)]
+	if !exists {
+		return nil, ErrNotFound
+	}
+
+	copied := *rate
+	return &copied, nil
+}
+
+func (f *FileRepository) SaveExchangeRate(rate ExchangeRate) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.state.Rates[ExchangeRateKey(rate.Date, rate.From, rate.To)] = &rate
+	return f.persist()
+}
+
+func (f *FileRepository) ListExchangeRates(from, to string) ([]ExchangeRate, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	rates := []ExchangeRate{}
+	for _, rate := range f.state.Rates {
+		if rate.From == from && rate.To == to {
+			rates = append(rates, *rate)
+		}
+	}
+
+	sort.Slice(rates, func(i, j int) bool {
+		return rates[i].Date > rates[j].Date
+	})
+
+	return rates, nil
+}
+
+func (f *FileRepository) GetRefund(id string) (*Refund, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	refund, exists := f.state.Refunds[id]
+	if !exists {
+		return nil, ErrNotFound
+	}
+
+	copied := *refund
+	return &copied, nil
+}
+
+func (f *FileRepository) SaveRefund(refund Refund) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.state.Refunds[refund.ID] = &refund
+	return f.persist()
+}
+
+func (f *FileRepository) ListRefunds() ([]Refund, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	refunds := make([]Refund, 0, len(f.state.Refunds))
+	for _, refund := range f.state.Refunds {
+		refunds = append(refunds, *refund)
+	}
+
+	sort.Slice(refunds, func(i, j int) bool {
+		return refunds[i].RequestedAt.Before(refunds[j].RequestedAt)
+	})
+
+	return refunds, nil
+}
+
+func (f *FileRepository) FindRefundByPayment(internalId, transactionId string) (*Refund, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	for _, refund := range f.state.Refunds {
+		if (internalId != "" && refund.InternalId == internalId) ||
+			(transactionId != "" && refund.TransactionId == transactionId) {
+			copied := *refund
+			return &copied, nil
+		}
+	}
+
+	return nil, ErrNotFound
+}
@@ -0,0 +1,130 @@
+package store
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFailedWriteRollsBackTheState(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "data")
+	repository, err := NewFileRepository(filepath.Join(dir, "store.json"))
+	if err != nil {
+		t.Fatalf("NewFileRepository: %v", err)
+	}
+
+	if err := repository.SaveBooking(Booking{ID: "BK-A", RaffleId: "raffle-test", Status: BookingReserved}); err != nil {
+		t.Fatalf("SaveBooking: %v", err)
+	}
+
+	// Sin el directorio no se puede escribir el archivo temporal
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatalf("RemoveAll: %v", err)
+	}
+
+	heldUntil := time.Now().Add(time.Minute)
+	if _, err := repository.HoldTickets("raffle-test", "BK-A", []int{7}, heldUntil); err == nil {
+		t.Fatal("HoldTickets without a store directory succeeded")
+	}
+	if _, err := repository.GetTicket("raffle-test", 7); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("GetTicket after the failed hold error = %v, want %v", err, ErrNotFound)
+	}
+
+	// Lo que ya estaba guardado sigue disponible
+	if _, err := repository.GetBooking("BK-A"); err != nil {
+		t.Fatalf("GetBooking: %v", err)
+	}
+}
+
+func TestReleaseExpiredHoldsKeepsPaymentsInFlight(t *testing.T) {
+	repository, err := NewFileRepository(filepath.Join(t.TempDir(), "store.json"))
+	if err != nil {
+		t.Fatalf("NewFileRepository: %v", err)
+	}
+
+	now := time.Now().UTC()
+	statuses := map[string]BookingStatus{
+		"BK-reserved": BookingOtpRequested,
+		"BK-debit":    BookingDebitSubmitted,
+		"BK-review":   BookingPendingReview,
+	}
+
+	number := 1
+	for bookingId, status := range statuses {
+		if _, err := repository.HoldTickets("raffle-test", bookingId, []int{number}, now.Add(time.Minute)); err != nil {
+			t.Fatalf("HoldTickets(%s): %v", bookingId, err)
+		}
+		if err := repository.SaveBooking(Booking{ID: bookingId, RaffleId: "raffle-test", Tickets: []int{number}, Status: status}); err != nil {
+			t.Fatalf("SaveBooking(%s): %v", bookingId, err)
+		}
+		number++
+	}
+
+	released, err := repository.ReleaseExpiredHolds(now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("ReleaseExpiredHolds: %v", err)
+	}
+	if len(released) != len(statuses) {
+		t.Fatalf("released tickets = %d, want %d", len(released), len(statuses))
+	}
+
+	// Solo expira la reserva que no envió el pago; las demás siguen esperando su resultado
+	want := map[string]BookingStatus{
+		"BK-reserved": BookingExpired,
+		"BK-debit":    BookingDebitSubmitted,
+		"BK-review":   BookingPendingReview,
+	}
+	for bookingId, status := range want {
+		booking, err := repository.GetBooking(bookingId)
+		if err != nil {
+			t.Fatalf("GetBooking(%s): %v", bookingId, err)
+		}
+		if booking.Status != status {
+			t.Errorf("booking %s = %s, want %s", bookingId, booking.Status, status)
+		}
+	}
+}
+
+func TestExpiredPaymentsInFlightAreRestoredOnLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.json")
+	repository, err := NewFileRepository(path)
+	if err != nil {
+		t.Fatalf("NewFileRepository: %v", err)
+	}
+
+	now := time.Now().UTC()
+	bookings := []Booking{
+		{ID: "BK-debit", Status: BookingExpired, TransactionId: "TX-1"},
+		{ID: "BK-review", Status: BookingExpired, Proof: &PaymentProof{SubmittedAt: now}},
+		{ID: "BK-reviewed", Status: BookingExpired, Proof: &PaymentProof{SubmittedAt: now, ReviewedAt: &now}},
+		{ID: "BK-unpaid", Status: BookingExpired},
+	}
+	for _, booking := range bookings {
+		if err := repository.SaveBooking(booking); err != nil {
+			t.Fatalf("SaveBooking(%s): %v", booking.ID, err)
+		}
+	}
+
+	reopened, err := NewFileRepository(path)
+	if err != nil {
+		t.Fatalf("NewFileRepository: %v", err)
+	}
+
+	want := map[string]BookingStatus{
+		"BK-debit":    BookingDebitSubmitted,
+		"BK-review":   BookingPendingReview,
+		"BK-reviewed": BookingExpired,
+		"BK-unpaid":   BookingExpired,
+	}
+	for bookingId, status := range want {
+		booking, err := reopened.GetBooking(bookingId)
+		if err != nil {
+			t.Fatalf("GetBooking(%s): %v", bookingId, err)
+		}
+		if booking.Status != status {
+			t.Errorf("booking %s = %s, want %s", bookingId, booking.Status, status)
+		}
+	}
+}
@@ -0,0 +1,384 @@
+package store
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// RaffleStatus representa la etapa del ciclo de vida de una rifa
+type RaffleStatus string
+
+const (
+	RaffleDraft     RaffleStatus = "draft"
+	RafflePublished RaffleStatus = "published"
+	RaffleClosed    RaffleStatus = "closed"
+	RaffleDrawn     RaffleStatus = "drawn"
+	RaffleArchived  RaffleStatus = "archived"
+	RaffleCancelled RaffleStatus = "cancelled"
+)
+
+// raffleTransitions define los cambios de estado permitidos
+var raffleTransitions = map[RaffleStatus][]RaffleStatus{
+	RaffleDraft:     {RafflePublished, RaffleArchived},
+	RafflePublished: {RaffleClosed, RaffleCancelled},
+	RaffleClosed:    {RaffleDrawn, RaffleCancelled},
+	RaffleDrawn:     {RaffleArchived},
+}
+
+// IsValid indica si el estado es uno de los conocidos
+func (s RaffleStatus) IsValid() bool {
+	switch s {
+	case RaffleDraft, RafflePublished, RaffleClosed, RaffleDrawn, RaffleArchived, RaffleCancelled:
+		return true
+	}
+	return false
+}
+
+// CanTransitionTo indica si se permite pasar del estado actual al indicado
+func (s RaffleStatus) CanTransitionTo(next RaffleStatus) bool {
+	for _, allowed := range raffleTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// IsPublic indica si la rifa se muestra en los endpoints públicos
+func (s RaffleStatus) IsPublic() bool {
+	return s == RafflePublished || s == RaffleClosed || s == RaffleDrawn
+}
+
+// Raffle representa una rifa persistida
+type Raffle struct {
+	ID               string       `json:"id"`
+	Title            string       `json:"title"`
+	ShortDescription string       `json:"shortDescription"`
+	CoverImageUrl    string       `json:"coverImageUrl"`
+	Price            float64      `json:"price"`
+	Currency         string       `json:"currency"`
+	InitialTicket    int          `json:"initialTicket"`
+	TicketsTotal     int          `json:"ticketsTotal"`
+	EndsAt           time.Time    `json:"endsAt"`
+	IsMain           bool         `json:"isMain"`
+	PaymentProviders []string     `json:"paymentProviders,omitempty"` // vacío usa los proveedores por defecto
+	Status           RaffleStatus `json:"status"`
+	CreatedAt        time.Time    `json:"createdAt"`
+	UpdatedAt        time.Time    `json:"updatedAt"`
+}
+
+// LastTicket devuelve el último número de ticket válido de la rifa
+func (r *Raffle) LastTicket() int {
+	return r.InitialTicket + r.TicketsTotal - 1
+}
+
+// OverlapsWith indica si los rangos de tickets de ambas rifas se solapan
+func (r *Raffle) OverlapsWith(other *Raffle) bool {
+	return r.InitialTicket <= other.LastTicket() && other.InitialTicket <= r.LastTicket()
+}
+
+// ContainsTicket indica si el número está dentro del rango de la rifa
+func (r *Raffle) ContainsTicket(number int) bool {
+	return number >= r.InitialTicket && number <= r.LastTicket()
+}
+
+// TicketStatus representa el estado de un ticket ocupado
+type TicketStatus string
+
+const (
+	TicketReserved TicketStatus = "reserved"
+	TicketSold     TicketStatus = "sold"
+)
+
+// Ticket representa un número ocupado (reservado o vendido) de una rifa.
+// Los números que no tienen registro están disponibles.
+type Ticket struct {
+	RaffleId  string       `json:"raffleId"`
+	Number    int          `json:"number"`
+	Status    TicketStatus `json:"status"`
+	BookingId string       `json:"bookingId"`
+	HeldUntil time.Time    `json:"heldUntil"`
+	UpdatedAt time.Time    `json:"updatedAt"`
+}
+
+// IsHeld indica si el ticket está ocupado en el instante indicado.
+// Un ticket reservado cuya retención ya venció se considera disponible.
+func (t *Ticket) IsHeld(now time.Time) bool {
+	if t.Status == TicketSold {
+		return true
+	}
+	return t.Status == TicketReserved && now.Before(t.HeldUntil)
+}
+
+// Participant representa a un comprador de una rifa
+type Participant struct {
+	ID         string    `json:"id"`
+	RaffleId   string    `json:"raffleId"`
+	DocumentId string    `json:"documentId"`
+	Name       string    `json:"name"`
+	Email      string    `json:"email"`
+	Phone      string    `json:"phone"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// BookingStatus representa la etapa del pago de una reserva
+type BookingStatus string
+
+const (
+	BookingReserved       BookingStatus = "RESERVED"
+	BookingOtpRequested   BookingStatus = "OTP_REQUESTED"
+	BookingDebitSubmitted BookingStatus = "DEBIT_SUBMITTED"
+	BookingPendingReview  BookingStatus = "PENDING_REVIEW"
+	BookingPaid           BookingStatus = "PAID"
+	BookingRejected       BookingStatus = "REJECTED"
+	BookingExpired        BookingStatus = "EXPIRED"
+)
+
+// bookingTransitions define los cambios de estado permitidos. Se puede volver a
+// solicitar el OTP mientras no se haya enviado el débito, y reemplazar el comprobante
+// de un pago manual mientras no se haya revisado.
+var bookingTransitions = map[BookingStatus][]BookingStatus{
+	BookingReserved:       {BookingOtpRequested, BookingPendingReview, BookingExpired},
+	BookingOtpRequested:   {BookingOtpRequested, BookingDebitSubmitted, BookingPendingReview, BookingExpired},
+	BookingDebitSubmitted: {BookingPaid, BookingRejected},
+	BookingPendingReview:  {BookingPendingReview, BookingPaid, BookingRejected},
+}
+
+// CanTransitionTo indica si se permite pasar del estado actual al indicado
+func (s BookingStatus) CanTransitionTo(next BookingStatus) bool {
+	for _, allowed := range bookingTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// IsFinal indica si la reserva ya no admite cambios de estado
+func (s BookingStatus) IsFinal() bool {
+	return s == BookingPaid || s == BookingRejected || s == BookingExpired
+}
+
+// IsAwaitingPayment indica si la reserva todavía puede expirar por vencimiento de la retención
+func (s BookingStatus) IsAwaitingPayment() bool {
+	return s == BookingReserved || s == BookingOtpRequested
+}
+
+// Booking representa una reserva de tickets hecha por un participante
+type Booking struct {
+	ID               string          `json:"id"`
+	RaffleId         string          `json:"raffleId"`
+	ParticipantId    string          `json:"participantId"`
+	DocumentId       string          `json:"documentId,omitempty"` // documento de quien reservó, usado para verificar la propiedad de los tickets
+	Tickets          []int           `json:"tickets"`
+	Amount           decimal.Decimal `json:"amount"`             // monto a cobrar, calculado por el servidor
+	Currency         string          `json:"currency,omitempty"` // moneda del pago
+	Rate             decimal.Decimal `json:"rate"`               // tasa usada para convertir el precio de la rifa a la moneda del pago
+	RateDate         string          `json:"rateDate,omitempty"` // día de la tasa usada (YYYY-MM-DD)
+	QuoteExpiresAt   *time.Time      `json:"quoteExpiresAt,omitempty"`
+	Provider         string          `json:"provider,omitempty"` // proveedor de pago elegido
+	Status           BookingStatus   `json:"status"`
+	ExpiresAt        time.Time       `json:"expiresAt"`
+	InternalId       string          `json:"internalId,omitempty"`      // internal_id enviado a SyPago
+	TransactionId    string          `json:"transactionId,omitempty"`   // transaction_id devuelto por SyPago
+	OperationSecret  string          `json:"operationSecret,omitempty"` // cifrado con SecretBox, nunca se envía al navegador
+	RefIbp           string          `json:"refIbp,omitempty"`          // referencia del pago confirmado
+	RejectedCode     string          `json:"rejectedCode,omitempty"`
+	LostTickets      []int           `json:"lostTickets,omitempty"` // tickets que otra reserva tomó antes de retenerlos para el pago o de venderlos
+	Payer            *PaymentAccount `json:"payer,omitempty"`       // cuenta desde la que se pagó, para devolver el dinero
+	Proof            *PaymentProof   `json:"proof,omitempty"`       // comprobante de un pago manual
+	ManualReview     *ManualReview   `json:"manualReview,omitempty"`
+	DebitSubmittedAt *time.Time      `json:"debitSubmittedAt,omitempty"` // cuándo se envió el débito a SyPago
+	PaidAt           *time.Time      `json:"paidAt,omitempty"`
+	CreatedAt        time.Time       `json:"createdAt"`
+	UpdatedAt        time.Time       `json:"updatedAt"`
+}
+
+// PaymentAccount es una cuenta bancaria de una persona, identificada por su documento.
+// Para Pago Móvil el número es el teléfono y el tipo CELE; para cuentas bancarias es CNTA.
+type PaymentAccount struct {
+	Name           string `json:"name,omitempty"`
+	DocumentType   string `json:"documentType"`
+	DocumentNumber string `json:"documentNumber"`
+	BankCode       string `json:"bankCode"`
+	AccountType    string `json:"accountType"`
+	AccountNumber  string `json:"accountNumber"`
+}
+
+// ManualReview registra por qué una operación sin resultado de SyPago pasó a revisión de un
+// administrador, que la verifica en SyPago y la cierra. Mientras tanto no se vuelve a consultar.
+type ManualReview struct {
+	Reason      string     `json:"reason"`
+	RequestedAt time.Time  `json:"requestedAt"`
+	ReviewedAt  *time.Time `json:"reviewedAt,omitempty"`
+	Note        string     `json:"note,omitempty"`
+}
+
+// IsPending indica si la revisión todavía no tiene decisión
+func (r *ManualReview) IsPending() bool {
+	return r != nil && r.ReviewedAt == nil
+}
+
+// PaymentProof es el comprobante de un pago manual enviado por el comprador. El pago queda
+// pendiente hasta que un administrador lo aprueba o lo rechaza.
+type PaymentProof struct {
+	Reference   string          `json:"reference"`
+	Amount      decimal.Decimal `json:"amount"`
+	Currency    string          `json:"currency"`
+	Bank        string          `json:"bank"`
+	ReceiptFile string          `json:"receiptFile"` // nombre del archivo en el directorio de comprobantes
+	ReceiptType string          `json:"receiptType"` // content type de la imagen
+	SubmittedAt time.Time       `json:"submittedAt"`
+	ReviewedAt  *time.Time      `json:"reviewedAt,omitempty"`
+	ReviewNote  string          `json:"reviewNote,omitempty"`
+}
+
+// Draw representa el sorteo de una rifa con esquema commit-reveal.
+// SeedHash se publica antes del sorteo y la semilla se guarda cifrada en SealedSeed; al cerrar
+// las ventas se fijan los vendidos (SoldTicketsHash) y la ronda del beacon público que se mezcla
+// con la semilla. Seed y BeaconRandomness solo tienen valor cuando DrawnAt lo tiene.
+type Draw struct {
+	RaffleId         string     `json:"raffleId"`
+	Algorithm        string     `json:"algorithm"`
+	SeedHash         string     `json:"seedHash"`
+	SealedSeed       string     `json:"sealedSeed,omitempty"`
+	Seed             string     `json:"seed"`
+	CommittedAt      time.Time  `json:"committedAt"`
+	MainCount        int        `json:"mainCount"`
+	BlessCount       int        `json:"blessCount"`
+	ClosedAt         *time.Time `json:"closedAt,omitempty"`
+	SoldTickets      []int      `json:"soldTickets"`
+	SoldTicketsHash  string     `json:"soldTicketsHash,omitempty"`
+	BeaconUrl        string     `json:"beaconUrl,omitempty"`
+	BeaconRound      uint64     `json:"beaconRound,omitempty"`
+	BeaconRandomness string     `json:"beaconRandomness,omitempty"`
+	MainWinners      []int      `json:"mainWinners"`
+	BlessWinners     []int      `json:"blessWinners"`
+	DrawnAt          *time.Time `json:"drawnAt,omitempty"`
+}
+
+// IsClosed indica si ya se fijaron los tickets vendidos que entran en el sorteo
+func (d *Draw) IsClosed() bool {
+	return d.ClosedAt != nil
+}
+
+// IsDrawn indica si el sorteo ya se ejecutó
+func (d *Draw) IsDrawn() bool {
+	return d.DrawnAt != nil
+}
+
+// PrizeKind representa el tipo de premio dentro del catálogo de una rifa
+type PrizeKind string
+
+const (
+	PrizeMain      PrizeKind = "main"
+	PrizeSecondary PrizeKind = "secondary"
+	PrizeBless     PrizeKind = "bless"
+)
+
+// IsValid indica si el tipo de premio es uno de los conocidos
+func (k PrizeKind) IsValid() bool {
+	return k == PrizeMain || k == PrizeSecondary || k == PrizeBless
+}
+
+// Prize representa un premio del catálogo de una rifa.
+// Los premios main y secondary se asignan, ordenados por Rank, a los ganadores
+// principales del sorteo; los premios bless a los números bendecidos.
+type Prize struct {
+	ID               string    `json:"id"`
+	RaffleId         string    `json:"raffleId"`
+	Kind             PrizeKind `json:"kind"`
+	Rank             int       `json:"rank"`
+	Title            string    `json:"title"`
+	ShortDescription string    `json:"shortDescription"`
+	ImageUrl         string    `json:"imageUrl"`
+	CreatedAt        time.Time `json:"createdAt"`
+	UpdatedAt        time.Time `json:"updatedAt"`
+}
+
+// IsMainTrack indica si el premio se asigna a los ganadores principales
+func (p *Prize) IsMainTrack() bool {
+	return p.Kind == PrizeMain || p.Kind == PrizeSecondary
+}
+
+// IdempotentResponse es la primera respuesta exitosa a una petición enviada con
+// Idempotency-Key; se repite para las peticiones duplicadas hasta ExpiresAt
+type IdempotentResponse struct {
+	Key         string    `json:"key"`         // huella de la ruta, la clave y la reserva
+	RequestHash string    `json:"requestHash"` // sha256 del body de la petición original
+	StatusCode  int       `json:"statusCode"`
+	ContentType string    `json:"contentType"`
+	Body        string    `json:"body"`
+	CreatedAt   time.Time `json:"createdAt"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+}
+
+// ExchangeRate es la tasa de cambio de un día: cuántas unidades de To equivalen a una de From
+type ExchangeRate struct {
+	Date      string          `json:"date"` // YYYY-MM-DD en hora de Venezuela
+	From      string          `json:"from"`
+	To        string          `json:"to"`
+	Rate      decimal.Decimal `json:"rate"`
+	Source    string          `json:"source"` // proveedor que la informó o "admin"
+	CreatedAt time.Time       `json:"createdAt"`
+}
+
+// ExchangeRateKey identifica la tasa de un par de monedas en un día
+func ExchangeRateKey(date, from, to string) string {
+	return date + "_" + from + "_" + to
+}
+
+// RefundStatus representa la etapa de una devolución
+type RefundStatus string
+
+const (
+	RefundRequested  RefundStatus = "REQUESTED"  // registrada, pendiente de ejecutar
+	RefundSending    RefundStatus = "SENDING"    // crédito enviado a SyPago sin respuesta confirmada
+	RefundProcessing RefundStatus = "PROCESSING" // crédito registrado en SyPago, esperando confirmación
+	RefundCompleted  RefundStatus = "COMPLETED"
+	RefundFailed     RefundStatus = "FAILED"    // SyPago rechazó el crédito; se puede volver a solicitar
+	RefundCancelled  RefundStatus = "CANCELLED" // descartada por un administrador antes de ejecutarse
+)
+
+// IsActive indica si la devolución cuenta contra el monto reembolsable de la reserva
+func (s RefundStatus) IsActive() bool {
+	return s == RefundRequested || s == RefundSending || s == RefundProcessing || s == RefundCompleted
+}
+
+// RefundMethod representa cómo se devuelve el dinero
+type RefundMethod string
+
+const (
+	RefundSypagoCredit RefundMethod = "sypago_credit" // crédito inmediato de SyPago a la cuenta del beneficiario
+	RefundManualPayout RefundMethod = "manual_payout" // transferencia hecha a mano por el back office
+)
+
+// IsValid indica si el método es uno de los conocidos
+func (m RefundMethod) IsValid() bool {
+	return m == RefundSypagoCredit || m == RefundManualPayout
+}
+
+// Refund representa la devolución de todo o parte del pago de una reserva
+type Refund struct {
+	ID              string          `json:"id"`
+	BookingId       string          `json:"bookingId"`
+	RaffleId        string          `json:"raffleId"`
+	Amount          decimal.Decimal `json:"amount"`
+	Currency        string          `json:"currency"`
+	Reason          string          `json:"reason"`
+	Method          RefundMethod    `json:"method"`
+	Status          RefundStatus    `json:"status"`
+	Beneficiary     *PaymentAccount `json:"beneficiary,omitempty"`     // obligatoria para sypago_credit
+	InternalId      string          `json:"internalId,omitempty"`      // internal_id enviado a SyPago
+	TransactionId   string          `json:"transactionId,omitempty"`   // transaction_id devuelto por SyPago
+	OperationSecret string          `json:"operationSecret,omitempty"` // cifrado con SecretBox
+	Reference       string          `json:"reference,omitempty"`       // ref_ibp del crédito o referencia del pago manual
+	FailureCode     string          `json:"failureCode,omitempty"`
+	LastError       string          `json:"lastError,omitempty"` // último error al ejecutarla; se puede reintentar
+	ManualReview    *ManualReview   `json:"manualReview,omitempty"`
+	Note            string          `json:"note,omitempty"`
+	RequestedAt     time.Time       `json:"requestedAt"`
+	CompletedAt     *time.Time      `json:"completedAt,omitempty"`
+	UpdatedAt       time.Time       `json:"updatedAt"`
+}
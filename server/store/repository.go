@@ -0,0 +1,81 @@
+package store
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrNotFound se devuelve cuando la entidad solicitada no existe
+var ErrNotFound = errors.New("not found")
+
+// ErrNotEnoughTickets se devuelve cuando no quedan suficientes tickets libres en la rifa
+var ErrNotEnoughTickets = errors.New("not enough available tickets")
+
+// RaffleRepository define el acceso a los datos persistidos de las rifas
+type RaffleRepository interface {
+	ListRaffles() ([]Raffle, error)
+	GetRaffle(id string) (*Raffle, error)
+	SaveRaffle(raffle Raffle) error
+	DeleteRaffle(id string) error
+
+	ListTickets(raffleId string) ([]Ticket, error)
+	GetTicket(raffleId string, number int) (*Ticket, error)
+	CountTickets(raffleId string, status TicketStatus) (int, error)
+	SaveTickets(tickets []Ticket) error
+
+	// HoldTickets reserva atómicamente los números para la reserva indicada
+	// hasta heldUntil. Si algún número está vendido o retenido por otra reserva
+	// no se reserva ninguno y se devuelven los números en conflicto.
+	HoldTickets(raffleId, bookingId string, numbers []int, heldUntil time.Time) ([]int, error)
+	// HoldRandomTickets elige quantity tickets libres del rango de la rifa usando
+	// randomIndex (que devuelve un índice en [0, n)) y los reserva en el mismo paso atómico.
+	HoldRandomTickets(raffle Raffle, bookingId string, quantity int, heldUntil time.Time, randomIndex func(n int) (int, error)) ([]int, error)
+	// ExtendHold retiene hasta heldUntil cada número libre o ya retenido por la reserva. Los
+	// números vendidos o retenidos por otra reserva no se modifican y se devuelven como conflictos.
+	ExtendHold(raffleId, bookingId string, numbers []int, heldUntil time.Time) ([]int, error)
+	// ReleaseTickets libera los tickets reservados (no vendidos) de una reserva
+	ReleaseTickets(raffleId, bookingId string) ([]int, error)
+	// ReleaseExpiredHolds libera todas las retenciones vencidas en el instante indicado
+	// y marca como expiradas las reservas sin finalizar a las que pertenecían
+	ReleaseExpiredHolds(now time.Time) ([]Ticket, error)
+	// SellTickets marca como vendidos los tickets de la reserva. Los que fueron tomados
+	// por otra reserva o vendidos a otra no se modifican y se devuelven como conflictos.
+	SellTickets(raffleId, bookingId string, numbers []int) ([]int, error)
+
+	GetParticipant(id string) (*Participant, error)
+	SaveParticipant(participant Participant) error
+
+	GetBooking(id string) (*Booking, error)
+	SaveBooking(booking Booking) error
+	ListBookings(raffleId string) ([]Booking, error)
+	ListBookingsByStatus(status BookingStatus) ([]Booking, error)
+	// FindBookingByPayment busca la reserva por el internal_id o el transaction_id de SyPago
+	FindBookingByPayment(internalId, transactionId string) (*Booking, error)
+
+	GetDraw(raffleId string) (*Draw, error)
+	SaveDraw(draw Draw) error
+
+	// ListPrizes devuelve el catálogo de la rifa: primero el premio principal,
+	// luego los secundarios y los bendecidos, cada grupo ordenado por Rank
+	ListPrizes(raffleId string) ([]Prize, error)
+	GetPrize(id string) (*Prize, error)
+	SavePrize(prize Prize) error
+	DeletePrize(id string) error
+
+	// GetIdempotentResponse devuelve la respuesta guardada para la clave si no ha expirado
+	GetIdempotentResponse(key string, now time.Time) (*IdempotentResponse, error)
+	// SaveIdempotentResponse guarda la respuesta y descarta las que ya expiraron
+	SaveIdempotentResponse(response IdempotentResponse) error
+
+	GetExchangeRate(date, from, to string) (*ExchangeRate, error)
+	SaveExchangeRate(rate ExchangeRate) error
+	// ListExchangeRates devuelve el historial del par de monedas, del día más reciente al más antiguo
+	ListExchangeRates(from, to string) ([]ExchangeRate, error)
+
+	GetRefund(id string) (*Refund, error)
+	SaveRefund(refund Refund) error
+	// ListRefunds devuelve las devoluciones, de la más antigua a la más reciente
+	ListRefunds() ([]Refund, error)
+	// FindRefundByPayment busca la devolución por el internal_id o el transaction_id de SyPago
+	FindRefundByPayment(internalId, transactionId string) (*Refund, error)
+}
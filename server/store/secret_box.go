@@ -0,0 +1,116 @@
+package store
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const secretKeySize = 32
+const sealedPrefix = "v1:"
+
+// SecretBox cifra con AES-256-GCM los datos sensibles que se guardan en el almacén
+type SecretBox struct {
+	aead cipher.AEAD
+}
+
+func NewSecretBox(key []byte) (*SecretBox, error) {
+	if len(key) != secretKeySize {
+		return nil, fmt.Errorf("secret key must be %d bytes, got %d", secretKeySize, len(key))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("error creating cipher: %v", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("error creating GCM: %v", err)
+	}
+
+	return &SecretBox{aead: aead}, nil
+}
+
+// LoadSecretBox lee la clave (hex) del archivo indicado. Si no existe genera una
+// clave aleatoria y la guarda con permisos de solo lectura para el usuario.
+func LoadSecretBox(path string) (*SecretBox, error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		key := make([]byte, secretKeySize)
+		if _, err := rand.Read(key); err != nil {
+			return nil, fmt.Errorf("error generating secret key: %v", err)
+		}
+
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return nil, fmt.Errorf("error creating secret key directory: %v", err)
+		}
+
+		if err := os.WriteFile(path, []byte(hex.EncodeToString(key)), 0o600); err != nil {
+			return nil, fmt.Errorf("error writing secret key: %v", err)
+		}
+
+		return NewSecretBox(key)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading secret key: %v", err)
+	}
+
+	key, err := hex.DecodeString(strings.TrimSpace(string(content)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid secret key file %s: %v", path, err)
+	}
+
+	return NewSecretBox(key)
+}
+
+// Seal cifra el texto y devuelve "v1:" seguido del nonce y el cifrado en base64
+func (b *SecretBox) Seal(plaintext string) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("error generating nonce: %v", err)
+	}
+
+	sealed := b.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return sealedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Open descifra un valor generado por Seal
+func (b *SecretBox) Open(sealed string) (string, error) {
+	if sealed == "" {
+		return "", nil
+	}
+
+	encoded, found := strings.CutPrefix(sealed, sealedPrefix)
+	if !found {
+		return "", fmt.Errorf("unsupported sealed value format")
+	}
+
+	content, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("error decoding sealed value: %v", err)
+	}
+
+	nonceSize := b.aead.NonceSize()
+	if len(content) < nonceSize {
+		return "", fmt.Errorf("sealed value too short")
+	}
+
+	plaintext, err := b.aead.Open(nil, content[:nonceSize], content[nonceSize:], nil)
+	if err != nil {
+		return "", fmt.Errorf("error decrypting sealed value: %v", err)
+	}
+
+	return string(plaintext), nil
+}
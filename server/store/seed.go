@@ -0,0 +1,118 @@
+package store
+
+import (
+	"fmt"
+	"time"
+)
+
+// defaultRaffles devuelve las rifas con las que se inicializa un almacén vacío.
+// Las fechas se calculan una sola vez al crear el archivo y luego quedan fijas.
+func defaultRaffles(now time.Time) []Raffle {
+	now = now.UTC().Truncate(time.Second)
+
+	return []Raffle{
+		{
+			ID:               "raffle-001",
+			Title:            "iPhone 15 Pro Max",
+			ShortDescription: "Último modelo de iPhone con 256GB de almacenamiento",
+			CoverImageUrl:    "https://images.unsplash.com/photo-1592750475338-74b7b21085ab?w=400",
+			Price:            25.00,
+			Currency:         "USD",
+			InitialTicket:    1,
+			TicketsTotal:     1000,
+			EndsAt:           now.AddDate(0, 0, 15),
+			IsMain:           true,
+			Status:           RafflePublished,
+			CreatedAt:        now,
+			UpdatedAt:        now,
+		},
+		{
+			ID:               "raffle-002",
+			Title:            "PlayStation 5",
+			ShortDescription: "Consola de videojuegos de última generación",
+			CoverImageUrl:    "https://images.unsplash.com/photo-1606813907291-d86efa9b94db?w=400",
+			Price:            15.00,
+			Currency:         "USD",
+			InitialTicket:    1001,
+			TicketsTotal:     800,
+			EndsAt:           now.AddDate(0, 0, 22),
+			Status:           RafflePublished,
+			CreatedAt:        now,
+			UpdatedAt:        now,
+		},
+		{
+			ID:               "raffle-003",
+			Title:            "MacBook Air M2",
+			ShortDescription: "Laptop ultradelgada con chip M2 y 512GB SSD",
+			CoverImageUrl:    "https://images.unsplash.com/photo-1541807084-5c52b6b3adef?w=400",
+			Price:            30.00,
+			Currency:         "USD",
+			InitialTicket:    1801,
+			TicketsTotal:     500,
+			EndsAt:           now.AddDate(0, 1, 5),
+			Status:           RafflePublished,
+			CreatedAt:        now,
+			UpdatedAt:        now,
+		},
+		{
+			ID:               "raffle-004",
+			Title:            "Tesla Model 3",
+			ShortDescription: "Vehículo eléctrico premium con autopilot",
+			CoverImageUrl:    "https://images.unsplash.com/photo-1560958089-b8a1929cea89?w=400",
+			Price:            100.00,
+			Currency:         "USD",
+			InitialTicket:    2301,
+			TicketsTotal:     2000,
+			EndsAt:           now.AddDate(0, 2, 0),
+			Status:           RafflePublished,
+			CreatedAt:        now,
+			UpdatedAt:        now,
+		},
+	}
+}
+
+// defaultPrizes devuelve el catálogo inicial de las rifas por defecto:
+// el premio principal de cada rifa y cinco premios para números bendecidos
+func defaultPrizes(now time.Time) []Prize {
+	now = now.UTC().Truncate(time.Second)
+
+	blessImages := []string{
+		"https://images.unsplash.com/photo-1606107557195-0e29a4b5b4aa?w=400",
+		"https://images.unsplash.com/photo-1513475382585-d06e58bcb0e0?w=400",
+		"https://images.unsplash.com/photo-1607082349566-187342175e2f?w=400",
+		"https://images.unsplash.com/photo-1579621970563-ebec7560ff3e?w=400",
+		"https://images.unsplash.com/photo-1556740758-90de374c12ad?w=400",
+	}
+
+	prizes := make([]Prize, 0)
+
+	for _, raffle := range defaultRaffles(now) {
+		prizes = append(prizes, Prize{
+			ID:               raffle.ID + "-main",
+			RaffleId:         raffle.ID,
+			Kind:             PrizeMain,
+			Rank:             1,
+			Title:            raffle.Title,
+			ShortDescription: "Premio principal de la rifa " + raffle.Title,
+			ImageUrl:         raffle.CoverImageUrl,
+			CreatedAt:        now,
+			UpdatedAt:        now,
+		})
+
+		for i, image := range blessImages {
+			prizes = append(prizes, Prize{
+				ID:               fmt.Sprintf("%s-bless-%d", raffle.ID, i+1),
+				RaffleId:         raffle.ID,
+				Kind:             PrizeBless,
+				Rank:             i + 1,
+				Title:            fmt.Sprintf("Número bendecido #%d", i+1),
+				ShortDescription: "Premio para número bendecido de la rifa " + raffle.Title,
+				ImageUrl:         image,
+				CreatedAt:        now,
+				UpdatedAt:        now,
+			})
+		}
+	}
+
+	return prizes
+}
@@ -0,0 +1,39 @@
+// Package storetest prepara almacenes temporales para las pruebas de los paquetes que usan store
+package storetest
+
+import (
+	"path/filepath"
+	"raffle_web_server/store"
+	"testing"
+)
+
+// NewRepository abre un almacén vacío, salvo las rifas por defecto, en un directorio temporal de la prueba
+func NewRepository(t testing.TB) *store.FileRepository {
+	t.Helper()
+
+	return OpenRepository(t, filepath.Join(t.TempDir(), "store.json"))
+}
+
+// OpenRepository abre el almacén guardado en path, para las pruebas que revisan el archivo
+func OpenRepository(t testing.TB, path string) *store.FileRepository {
+	t.Helper()
+
+	repository, err := store.NewFileRepository(path)
+	if err != nil {
+		t.Fatalf("NewFileRepository: %v", err)
+	}
+
+	return repository
+}
+
+// NewSecretBox devuelve un SecretBox con una clave fija
+func NewSecretBox(t testing.TB) *store.SecretBox {
+	t.Helper()
+
+	secrets, err := store.NewSecretBox(make([]byte, 32))
+	if err != nil {
+		t.Fatalf("NewSecretBox: %v", err)
+	}
+
+	return secrets
+}
@@ -0,0 +1,107 @@
+package sypago
+
+import (
+	"context"
+	"fmt"
+	"raffle_web_server/config"
+	"sync"
+	"time"
+)
+
+// BankCatalog mantiene en memoria la lista de bancos de SyPago. La lista se renueva en
+// segundo plano y, si SyPago no responde, se sigue sirviendo la última lista obtenida.
+type BankCatalog struct {
+	client Client
+
+	mutex     sync.RWMutex
+	banks     []Bank
+	fetchedAt time.Time
+
+	// refreshing evita consultar SyPago varias veces a la vez
+	refreshing sync.Mutex
+}
+
+func NewBankCatalog(client Client) *BankCatalog {
+	return &BankCatalog{client: client}
+}
+
+func banksCacheTTL() time.Duration {
+	return config.GetConfig().SypagoConfig.BanksCacheTTL()
+}
+
+func (b *BankCatalog) snapshot() ([]Bank, time.Time) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	return b.banks, b.fetchedAt
+}
+
+// Banks devuelve la lista de bancos. Si la cache expiró se consulta SyPago; si la consulta
+// falla y hay una lista anterior, se devuelve esa lista.
+func (b *BankCatalog) Banks(ctx context.Context) ([]Bank, error) {
+	banks, fetchedAt := b.snapshot()
+	if !fetchedAt.IsZero() && time.Since(fetchedAt) < banksCacheTTL() {
+		return banks, nil
+	}
+
+	if err := b.Refresh(ctx); err != nil {
+		if fetchedAt.IsZero() {
+			return nil, err
+		}
+
+		fmt.Printf("Serving SyPago banks cached at %s: %v\n", fetchedAt.Format(time.RFC3339), err)
+		return banks, nil
+	}
+
+	banks, _ = b.snapshot()
+	return banks, nil
+}
+
+// Refresh consulta la lista de bancos en SyPago y reemplaza la cache
+func (b *BankCatalog) Refresh(ctx context.Context) error {
+	requestedAt := time.Now()
+
+	b.refreshing.Lock()
+	defer b.refreshing.Unlock()
+
+	// Otra petición renovó la lista mientras se esperaba
+	if _, fetchedAt := b.snapshot(); fetchedAt.After(requestedAt) {
+		return nil
+	}
+
+	banks, err := b.client.Banks(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to fetch banks from SyPago: %w", err)
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.banks = banks
+	b.fetchedAt = time.Now()
+
+	return nil
+}
+
+// Run carga la lista al iniciar y la renueva a la mitad de su vigencia, para que las
+// peticiones no tengan que esperar a SyPago, hasta que se cancele el contexto
+func (b *BankCatalog) Run(ctx context.Context) {
+	if err := b.Refresh(ctx); err != nil {
+		fmt.Printf("Error loading SyPago banks: %v\n", err)
+	}
+
+	ticker := time.NewTicker(banksCacheTTL() / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := b.Refresh(ctx); err != nil {
+				fmt.Printf("Error refreshing SyPago banks: %v\n", err)
+			}
+			ticker.Reset(banksCacheTTL() / 2)
+		}
+	}
+}
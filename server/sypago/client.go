@@ -0,0 +1,335 @@
+package sypago
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"raffle_web_server/config"
+	"time"
+)
+
+// ErrUnauthorized indica que SyPago rechazó las credenciales o el token
+var ErrUnauthorized = errors.New("SyPago API returned 401 Unauthorized")
+
+// ErrNotFound indica que SyPago no encontró el recurso consultado
+var ErrNotFound = errors.New("not found in SyPago")
+
+// ErrDuplicate indica que SyPago ya registró una transacción con el mismo internal_id
+var ErrDuplicate = errors.New("duplicated transaction in SyPago")
+
+// ErrRefused indica que SyPago rechazó la petición sin procesarla (respuesta 4xx)
+var ErrRefused = errors.New("request refused by SyPago")
+
+// Client es la API de SyPago que usan los flujos de pago
+type Client interface {
+	// Banks devuelve los bancos registrados en SyPago
+	Banks(ctx context.Context) ([]Bank, error)
+	// RequestOtp solicita al banco del deudor el envío del OTP para el débito
+	RequestOtp(ctx context.Context, request RequestOtpRequest) error
+	// TransactionOtp ejecuta el débito autorizado con el OTP
+	TransactionOtp(ctx context.Context, request TransactionOtpRequest) (*TransactionOtpResponse, error)
+	// TransactionCredit envía un crédito inmediato a la cuenta de un beneficiario
+	TransactionCredit(ctx context.Context, request TransactionCreditRequest) (*TransactionOtpResponse, error)
+	// TransactionStatus consulta el estado de una transacción
+	TransactionStatus(ctx context.Context, transactionId, operationSecret string) (*TransactionStatus, error)
+}
+
+// Settings agrupa los datos de conexión con SyPago
+type Settings struct {
+	BaseUrl        string
+	ClientId       string
+	ApiKey         string
+	RequestTimeout time.Duration
+	DebitTimeout   time.Duration // las operaciones de débito suelen tardar más
+}
+
+// fingerprint devuelve una huella que cambia cuando se modifica la URL o las credenciales
+func (s Settings) fingerprint() string {
+	hash := sha256.Sum256([]byte(s.BaseUrl + "\x00" + s.ClientId + "\x00" + s.ApiKey))
+	return hex.EncodeToString(hash[:])
+}
+
+// SettingsFromConfig lee los datos de conexión de la configuración vigente, de modo
+// que los cambios en el archivo se aplican en la siguiente petición
+func SettingsFromConfig() (Settings, error) {
+	sypagoConfig := config.GetConfig().SypagoConfig
+
+	apiKey, err := sypagoConfig.ResolveApiKey()
+	if err != nil {
+		return Settings{}, fmt.Errorf("failed to load SyPago credentials: %v", err)
+	}
+
+	return Settings{
+		BaseUrl:        sypagoConfig.BaseUrl,
+		ClientId:       sypagoConfig.ClientId,
+		ApiKey:         apiKey,
+		RequestTimeout: sypagoConfig.RequestTimeout(),
+		DebitTimeout:   sypagoConfig.DebitTimeout(),
+	}, nil
+}
+
+// HTTPClient implementa Client sobre la API REST de SyPago
+type HTTPClient struct {
+	http     *http.Client
+	settings func() (Settings, error)
+	tokens   tokenCache
+}
+
+// NewClient crea el cliente de SyPago. El http.Client se comparte entre peticiones;
+// los timeouts se aplican por petición a través del contexto.
+func NewClient(httpClient *http.Client, settings func() (Settings, error)) *HTTPClient {
+	if httpClient == nil {
+		httpClient = &http.Client{}
+	}
+
+	return &HTTPClient{
+		http:     httpClient,
+		settings: settings,
+	}
+}
+
+// call describe una petición a la API de SyPago
+type call struct {
+	operation     string // nombre de la operación para los logs y errores
+	method        string
+	path          string
+	timeout       time.Duration
+	headers       map[string]string
+	payload       interface{}
+	authenticated bool
+}
+
+func isSuccessResponse(statusCode int) bool {
+	return statusCode > 199 && statusCode < 300
+}
+
+// send ejecuta la petición y decodifica la respuesta exitosa en out. Si SyPago responde
+// 401 a una petición autenticada, se descarta el token y se reintenta una sola vez con
+// un token nuevo. El body de las respuestas exitosas no se registra porque puede incluir
+// tokens o el operation_secret.
+func (c *HTTPClient) send(ctx context.Context, settings Settings, request call, out interface{}) error {
+	var payload []byte
+	if request.payload != nil {
+		jsonPayload, err := json.Marshal(request.payload)
+		if err != nil {
+			return fmt.Errorf("error marshaling request payload: %v", err)
+		}
+		payload = jsonPayload
+	}
+
+	statusCode, content, err := c.attempt(ctx, settings, request, payload)
+	if err == nil && statusCode == http.StatusUnauthorized && request.authenticated {
+		fmt.Printf("SyPago %s returned 401, retrying with a new token\n", request.operation)
+		statusCode, content, err = c.attempt(ctx, settings, request, payload)
+	}
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case statusCode == http.StatusUnauthorized:
+		return fmt.Errorf("SyPago %s API: %w", request.operation, ErrUnauthorized)
+	case statusCode == http.StatusNotFound:
+		return fmt.Errorf("SyPago %s API: %w", request.operation, ErrNotFound)
+	case statusCode == http.StatusConflict:
+		return fmt.Errorf("SyPago %s API: %w: %s", request.operation, ErrDuplicate, string(content))
+	case statusCode >= 400 && statusCode < 500:
+		fmt.Printf("SyPago %s response body: %s\n", request.operation, string(content))
+		return fmt.Errorf("SyPago %s API returned status code %d: %w: %s", request.operation, statusCode, ErrRefused, string(content))
+	case !isSuccessResponse(statusCode):
+		fmt.Printf("SyPago %s response body: %s\n", request.operation, string(content))
+		return fmt.Errorf("SyPago %s API returned status code %d: %s", request.operation, statusCode, string(content))
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	if err := json.Unmarshal(content, out); err != nil {
+		return fmt.Errorf("error parsing SyPago %s response: %v", request.operation, err)
+	}
+
+	return nil
+}
+
+// attempt ejecuta un intento de la petición y devuelve el código y el body de la respuesta.
+// Un 401 descarta el token usado para que el siguiente intento se autentique de nuevo.
+func (c *HTTPClient) attempt(ctx context.Context, settings Settings, request call, payload []byte) (int, []byte, error) {
+	endpoint := settings.BaseUrl + request.path
+
+	if request.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, request.timeout)
+		defer cancel()
+	}
+
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, request.method, endpoint, body)
+	if err != nil {
+		return 0, nil, fmt.Errorf("error creating request: %v", err)
+	}
+
+	req.Header.Set("Accept", "application/json")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for name, value := range request.headers {
+		req.Header.Set(name, value)
+	}
+
+	var token string
+	if request.authenticated {
+		token, err = c.token(ctx, settings)
+		if err != nil {
+			return 0, nil, fmt.Errorf("failed to get SyPago auth token: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	fmt.Printf("Making SyPago %s request to: %s\n", request.operation, endpoint)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return 0, nil, fmt.Errorf("error making request to SyPago %s API: %w", request.operation, err)
+	}
+	defer resp.Body.Close()
+
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, fmt.Errorf("error reading response body: %v", err)
+	}
+
+	fmt.Printf("SyPago %s response status: %d\n", request.operation, resp.StatusCode)
+
+	if resp.StatusCode == http.StatusUnauthorized && request.authenticated {
+		// Token expirado; solo se descarta si nadie lo reemplazó mientras tanto
+		c.tokens.discard(token)
+	}
+
+	return resp.StatusCode, content, nil
+}
+
+// Banks consulta la lista de bancos
+func (c *HTTPClient) Banks(ctx context.Context) ([]Bank, error) {
+	settings, err := c.settings()
+	if err != nil {
+		return nil, err
+	}
+
+	var banks []Bank
+	err = c.send(ctx, settings, call{
+		operation:     "banks",
+		method:        http.MethodGet,
+		path:          "/api/v1/banks",
+		timeout:       settings.RequestTimeout,
+		authenticated: true,
+	}, &banks)
+	if err != nil {
+		return nil, err
+	}
+
+	return banks, nil
+}
+
+// RequestOtp solicita un OTP para realizar un débito
+func (c *HTTPClient) RequestOtp(ctx context.Context, request RequestOtpRequest) error {
+	settings, err := c.settings()
+	if err != nil {
+		return err
+	}
+
+	return c.send(ctx, settings, call{
+		operation:     "RequestOtp",
+		method:        http.MethodPost,
+		path:          "/api/v1/request/otp",
+		timeout:       settings.DebitTimeout,
+		payload:       request,
+		authenticated: true,
+	}, nil)
+}
+
+// TransactionOtp ejecuta una transacción OTP
+func (c *HTTPClient) TransactionOtp(ctx context.Context, request TransactionOtpRequest) (*TransactionOtpResponse, error) {
+	settings, err := c.settings()
+	if err != nil {
+		return nil, err
+	}
+
+	var response TransactionOtpResponse
+	err = c.send(ctx, settings, call{
+		operation:     "TransactionOtp",
+		method:        http.MethodPost,
+		path:          "/api/v1/transaction/otp",
+		timeout:       settings.DebitTimeout,
+		payload:       request,
+		authenticated: true,
+	}, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	return &response, nil
+}
+
+// TransactionCredit envía un crédito inmediato; la respuesta tiene el mismo formato que la del
+// débito y su estado se consulta igual con TransactionStatus
+func (c *HTTPClient) TransactionCredit(ctx context.Context, request TransactionCreditRequest) (*TransactionOtpResponse, error) {
+	settings, err := c.settings()
+	if err != nil {
+		return nil, err
+	}
+
+	var response TransactionOtpResponse
+	err = c.send(ctx, settings, call{
+		operation:     "TransactionCredit",
+		method:        http.MethodPost,
+		path:          "/api/v1/transaction/credit",
+		timeout:       settings.DebitTimeout,
+		payload:       request,
+		authenticated: true,
+	}, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	return &response, nil
+}
+
+// TransactionStatus consulta el estado de una transacción con su secreto de operación
+func (c *HTTPClient) TransactionStatus(ctx context.Context, transactionId, operationSecret string) (*TransactionStatus, error) {
+	settings, err := c.settings()
+	if err != nil {
+		return nil, err
+	}
+
+	headers := map[string]string{}
+	if operationSecret != "" {
+		headers["Operation-Secret"] = operationSecret
+	}
+
+	var status TransactionStatus
+	err = c.send(ctx, settings, call{
+		operation:     "transaction status",
+		method:        http.MethodGet,
+		path:          "/api/v1/transaction/" + url.PathEscape(transactionId),
+		timeout:       settings.RequestTimeout,
+		headers:       headers,
+		authenticated: true,
+	}, &status)
+	if err != nil {
+		return nil, err
+	}
+
+	return &status, nil
+}
@@ -0,0 +1,171 @@
+package sypago
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func newFakeClient(t *testing.T) (*FakeServer, *HTTPClient) {
+	t.Helper()
+
+	fake := NewFakeServer()
+	t.Cleanup(fake.Close)
+
+	return fake, NewClient(&http.Client{}, fake.Settings)
+}
+
+func debitRequest(internalId, otp string) TransactionOtpRequest {
+	return TransactionOtpRequest{
+		InternalId: internalId,
+		GroupId:    "raffle-test",
+		Account:    Account{BankCode: "0102", Type: "CNTA", Number: "01020000000000000001"},
+		Amount:     AmountWithRate{Amt: 1825, Currency: "VES"},
+		Concept:    "Tickets raffle-test",
+		ReceivingUser: ReceivingUser{
+			Name:         "Comprador",
+			Otp:          otp,
+			DocumentInfo: DocumentInfo{Type: "V", Number: "12345678"},
+			Account:      Account{BankCode: "0134", Type: "CELE", Number: "04141234567"},
+		},
+	}
+}
+
+// pollStatus consulta la transacción hasta que sea final y devuelve cuántas consultas quedó en PEND
+func pollStatus(t *testing.T, client *HTTPClient, response *TransactionOtpResponse) (*TransactionStatus, int) {
+	t.Helper()
+
+	pending := 0
+	for range 10 {
+		status, err := client.TransactionStatus(context.Background(), response.TransactionId, response.OperationSecret)
+		if err != nil {
+			t.Fatalf("TransactionStatus: %v", err)
+		}
+		if status.IsFinal() {
+			return status, pending
+		}
+		if status.Status != StatusPending {
+			t.Fatalf("status = %s, want %s", status.Status, StatusPending)
+		}
+		pending++
+	}
+
+	t.Fatalf("transaction %s never reached a final status", response.TransactionId)
+	return nil, 0
+}
+
+func TestTokenIsReusedAndRenewedAfter401(t *testing.T) {
+	fake, client := newFakeClient(t)
+	ctx := context.Background()
+
+	for range 3 {
+		if _, err := client.Banks(ctx); err != nil {
+			t.Fatalf("Banks: %v", err)
+		}
+	}
+	if got := fake.AuthRequests(); got != 1 {
+		t.Fatalf("auth requests = %d, want 1 while the token is valid", got)
+	}
+
+	// SyPago invalida el token antes de lo previsto: el 401 provoca una autenticación y un reintento
+	fake.ExpireTokens()
+
+	if _, err := client.Banks(ctx); err != nil {
+		t.Fatalf("Banks after the token expired: %v", err)
+	}
+	if got := fake.AuthRequests(); got != 2 {
+		t.Fatalf("auth requests = %d, want 2 after the retry", got)
+	}
+}
+
+func TestInvalidCredentialsAreNotRetried(t *testing.T) {
+	fake := NewFakeServer()
+	t.Cleanup(fake.Close)
+
+	client := NewClient(&http.Client{}, func() (Settings, error) {
+		settings, err := fake.Settings()
+		settings.ApiKey = "wrong-key"
+		return settings, err
+	})
+
+	_, err := client.Banks(context.Background())
+	if !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("Banks error = %v, want %v", err, ErrUnauthorized)
+	}
+	if got := fake.AuthRequests(); got != 1 {
+		t.Fatalf("auth requests = %d, want 1", got)
+	}
+}
+
+func TestDebitIsAcceptedAfterPolling(t *testing.T) {
+	fake, client := newFakeClient(t)
+	fake.SetPendingPolls(2)
+
+	response, err := client.TransactionOtp(context.Background(), debitRequest("BK-ACCP", "123456"))
+	if err != nil {
+		t.Fatalf("TransactionOtp: %v", err)
+	}
+
+	status, pending := pollStatus(t, client, response)
+	if pending != 2 {
+		t.Errorf("pending polls = %d, want 2", pending)
+	}
+	if status.Status != StatusAccepted || status.RefIbp == "" {
+		t.Fatalf("status = %s with ref %q, want %s with a reference", status.Status, status.RefIbp, StatusAccepted)
+	}
+	if status.InternalId != "BK-ACCP" || status.Amount.Amt != 1825 {
+		t.Fatalf("transaction = %s for %v, want BK-ACCP for 1825", status.InternalId, status.Amount.Amt)
+	}
+}
+
+func TestDebitIsRejectedAfterPolling(t *testing.T) {
+	fake, client := newFakeClient(t)
+	fake.SetPendingPolls(1)
+
+	response, err := client.TransactionOtp(context.Background(), debitRequest("BK-RJCT", FakeRejectOtp))
+	if err != nil {
+		t.Fatalf("TransactionOtp: %v", err)
+	}
+
+	status, pending := pollStatus(t, client, response)
+	if pending != 1 {
+		t.Errorf("pending polls = %d, want 1", pending)
+	}
+	if status.Status != StatusRejected || status.RejectedCode != FakeRejectedCode {
+		t.Fatalf("status = %s with code %q, want %s with %s", status.Status, status.RejectedCode, StatusRejected, FakeRejectedCode)
+	}
+}
+
+func TestTransactionStatusRequiresOperationSecret(t *testing.T) {
+	_, client := newFakeClient(t)
+
+	response, err := client.TransactionOtp(context.Background(), debitRequest("BK-SECRET", "123456"))
+	if err != nil {
+		t.Fatalf("TransactionOtp: %v", err)
+	}
+
+	if _, err := client.TransactionStatus(context.Background(), response.TransactionId, "wrong-secret"); !errors.Is(err, ErrRefused) {
+		t.Fatalf("TransactionStatus with a wrong operation secret error = %v, want %v", err, ErrRefused)
+	}
+}
+
+func TestDuplicateInternalIdIsRejected(t *testing.T) {
+	_, client := newFakeClient(t)
+	ctx := context.Background()
+
+	if _, err := client.TransactionOtp(ctx, debitRequest("BK-DUP", "123456")); err != nil {
+		t.Fatalf("TransactionOtp: %v", err)
+	}
+
+	_, err := client.TransactionOtp(ctx, debitRequest("BK-DUP", "123456"))
+	if !errors.Is(err, ErrDuplicate) {
+		t.Fatalf("second TransactionOtp error = %v, want %v", err, ErrDuplicate)
+	}
+
+	// El internal_id tampoco se puede reutilizar en un crédito
+	_, err = client.TransactionCredit(ctx, TransactionCreditRequest(debitRequest("BK-DUP", "")))
+	if !errors.Is(err, ErrDuplicate) {
+		t.Fatalf("TransactionCredit error = %v, want %v", err, ErrDuplicate)
+	}
+}
@@ -0,0 +1,336 @@
+package sypago
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Credenciales que acepta el servidor falso
+const (
+	FakeClientId = "fake-client"
+	FakeApiKey   = "fake-api-key"
+)
+
+// FakeRejectOtp es el OTP con el que el servidor falso rechaza el débito
+const FakeRejectOtp = "000000"
+
+// FakeRejectCreditAccount es la cuenta de beneficiario con la que el servidor falso rechaza el crédito
+const FakeRejectCreditAccount = "00000000000000000000"
+
+// FakeRejectedCode es el código de rechazo de los débitos rechazados por el servidor falso
+const FakeRejectedCode = "AM04"
+
+const defaultFakeTokenTTL = time.Hour
+const defaultFakePendingPolls = 2
+
+// fakeTransaction es una transacción registrada en el servidor falso
+type fakeTransaction struct {
+	status          TransactionStatus
+	operationSecret string
+	finalStatus     string
+	polls           int
+}
+
+// FakeServer simula la API de SyPago en el mismo proceso para probar los pagos sin conexión.
+// Emite tokens que expiran, acepta cualquier solicitud de OTP y finaliza los débitos en ACCP,
+// o en RJCT si el OTP es FakeRejectOtp, después de algunas consultas en PEND. Los créditos se
+// finalizan igual, en RJCT si la cuenta del beneficiario es FakeRejectCreditAccount.
+type FakeServer struct {
+	server *httptest.Server
+
+	mutex        sync.Mutex
+	tokenTTL     time.Duration
+	pendingPolls int
+	tokens       map[string]time.Time
+	transactions map[string]*fakeTransaction
+	internalIds  map[string]bool
+	authRequests int
+}
+
+// NewFakeServer inicia el servidor falso; se debe cerrar con Close
+func NewFakeServer() *FakeServer {
+	fake := &FakeServer{
+		tokenTTL:     defaultFakeTokenTTL,
+		pendingPolls: defaultFakePendingPolls,
+		tokens:       make(map[string]time.Time),
+		transactions: make(map[string]*fakeTransaction),
+		internalIds:  make(map[string]bool),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v1/auth/token", fake.handleToken)
+	mux.HandleFunc("GET /api/v1/banks", fake.authorized(fake.handleBanks))
+	mux.HandleFunc("POST /api/v1/request/otp", fake.authorized(fake.handleRequestOtp))
+	mux.HandleFunc("POST /api/v1/transaction/otp", fake.authorized(fake.handleTransactionOtp))
+	mux.HandleFunc("POST /api/v1/transaction/credit", fake.authorized(fake.handleTransactionCredit))
+	mux.HandleFunc("GET /api/v1/transaction/{id}", fake.authorized(fake.handleTransactionStatus))
+
+	fake.server = httptest.NewServer(mux)
+
+	return fake
+}
+
+// URL devuelve la URL base del servidor falso
+func (f *FakeServer) URL() string {
+	return f.server.URL
+}
+
+// Close detiene el servidor falso
+func (f *FakeServer) Close() {
+	f.server.Close()
+}
+
+// Settings devuelve los datos de conexión con el servidor falso
+func (f *FakeServer) Settings() (Settings, error) {
+	return Settings{
+		BaseUrl:        f.server.URL,
+		ClientId:       FakeClientId,
+		ApiKey:         FakeApiKey,
+		RequestTimeout: 5 * time.Second,
+		DebitTimeout:   5 * time.Second,
+	}, nil
+}
+
+// SetTokenTTL cambia la duración de los tokens que se emitan desde ahora
+func (f *FakeServer) SetTokenTTL(ttl time.Duration) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.tokenTTL = ttl
+}
+
+// SetPendingPolls cambia cuántas consultas quedan las transacciones nuevas en PEND
+func (f *FakeServer) SetPendingPolls(polls int) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.pendingPolls = polls
+}
+
+// ExpireTokens invalida todos los tokens emitidos, como si hubieran expirado en SyPago
+func (f *FakeServer) ExpireTokens() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.tokens = make(map[string]time.Time)
+}
+
+// AuthRequests devuelve cuántas veces se pidió un token
+func (f *FakeServer) AuthRequests() int {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.authRequests
+}
+
+func randomHex(size int) string {
+	content := make([]byte, size)
+	if _, err := rand.Read(content); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(content)
+}
+
+func writeFakeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func writeFakeError(w http.ResponseWriter, status int, message string) {
+	writeFakeJSON(w, status, map[string]string{"message": message})
+}
+
+// authorized rechaza con 401 las peticiones sin un token vigente
+func (f *FakeServer) authorized(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+		f.mutex.Lock()
+		expiresAt, exists := f.tokens[token]
+		f.mutex.Unlock()
+
+		if !found || !exists || time.Now().After(expiresAt) {
+			writeFakeError(w, http.StatusUnauthorized, "invalid or expired token")
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+func (f *FakeServer) handleToken(w http.ResponseWriter, r *http.Request) {
+	var request TokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeFakeError(w, http.StatusBadRequest, "invalid request")
+		return
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.authRequests++
+
+	if request.ClientId != FakeClientId || request.ApiKey != FakeApiKey {
+		writeFakeError(w, http.StatusUnauthorized, "invalid client credentials")
+		return
+	}
+
+	token := randomHex(16)
+	f.tokens[token] = time.Now().Add(f.tokenTTL)
+
+	writeFakeJSON(w, http.StatusOK, TokenResponse{
+		AccessToken: token,
+		ExpiresIn:   int(f.tokenTTL / time.Second),
+		TokenType:   "Bearer",
+	})
+}
+
+func (f *FakeServer) handleBanks(w http.ResponseWriter, r *http.Request) {
+	writeFakeJSON(w, http.StatusOK, []Bank{
+		{Code: "0102", Name: "Banco de Venezuela", Active: true, SypagoClient: true, VerifyType: 1, IsSmsOtp: true, SmsOtpAddress: "2661", SmsOtpText: "SYPAGO", IsDebitOTP: true},
+		{Code: "0105", Name: "Banco Mercantil", Active: true, SypagoClient: true, VerifyType: 1, IsDebitOTP: true},
+		{Code: "0108", Name: "Banco Provincial", Active: true, VerifyType: 0},
+		{Code: "0134", Name: "Banesco", Active: true, SypagoClient: true, VerifyType: 1, IsSmsOtp: true, SmsOtpAddress: "2846", SmsOtpText: "CLAVE", IsDebitOTP: true},
+	})
+}
+
+func (f *FakeServer) handleRequestOtp(w http.ResponseWriter, r *http.Request) {
+	var request RequestOtpRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeFakeError(w, http.StatusBadRequest, "invalid request")
+		return
+	}
+
+	if request.CreditorAccount.Number == "" || request.DebitorAccount.Number == "" ||
+		request.DebitorDocumentInfo.Number == "" || request.Amount.Amt <= 0 {
+		writeFakeError(w, http.StatusBadRequest, "missing debit data")
+		return
+	}
+
+	writeFakeJSON(w, http.StatusOK, map[string]bool{"success": true})
+}
+
+func (f *FakeServer) handleTransactionOtp(w http.ResponseWriter, r *http.Request) {
+	var request TransactionOtpRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeFakeError(w, http.StatusBadRequest, "invalid request")
+		return
+	}
+
+	if request.InternalId == "" || request.ReceivingUser.Otp == "" || request.Amount.Amt <= 0 {
+		writeFakeError(w, http.StatusBadRequest, "missing transaction data")
+		return
+	}
+
+	finalStatus := StatusAccepted
+	if request.ReceivingUser.Otp == FakeRejectOtp {
+		finalStatus = StatusRejected
+	}
+
+	f.registerTransaction(w, "DEBIT", request.InternalId, request.GroupId, request.Amount, request.ReceivingUser, finalStatus)
+}
+
+func (f *FakeServer) handleTransactionCredit(w http.ResponseWriter, r *http.Request) {
+	var request TransactionCreditRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeFakeError(w, http.StatusBadRequest, "invalid request")
+		return
+	}
+
+	if request.InternalId == "" || request.ReceivingUser.Account.Number == "" || request.Amount.Amt <= 0 {
+		writeFakeError(w, http.StatusBadRequest, "missing transaction data")
+		return
+	}
+
+	finalStatus := StatusAccepted
+	if request.ReceivingUser.Account.Number == FakeRejectCreditAccount {
+		finalStatus = StatusRejected
+	}
+
+	f.registerTransaction(w, "CREDIT", request.InternalId, request.GroupId, request.Amount, request.ReceivingUser, finalStatus)
+}
+
+// registerTransaction registra una transacción pendiente que finalizará en finalStatus y
+// responde con su transaction_id y operation_secret
+func (f *FakeServer) registerTransaction(w http.ResponseWriter, kind, internalId, groupId string, amount AmountWithRate, receiving ReceivingUser, finalStatus string) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.internalIds[internalId] {
+		writeFakeError(w, http.StatusConflict, "duplicated internal_id")
+		return
+	}
+	f.internalIds[internalId] = true
+
+	transaction := &fakeTransaction{
+		operationSecret: randomHex(16),
+		finalStatus:     finalStatus,
+		polls:           f.pendingPolls,
+	}
+
+	status := &transaction.status
+	status.InternalId = internalId
+	status.TransactionId = strings.ToUpper(randomHex(16))
+	status.GroupId = groupId
+	status.OperationDate = time.Now().UTC().Format(time.RFC3339)
+	status.Amount.Type = kind
+	status.Amount.Amt = amount.Amt
+	status.Amount.PayAmt = amount.Amt
+	status.Amount.Currency = amount.Currency
+	status.Amount.Rate = 1
+	status.Amount.UseDayRate = amount.UseDayRate
+	status.ReceivingUser.Name = receiving.Name
+	status.ReceivingUser.DocumentInfo.Type = receiving.DocumentInfo.Type
+	status.ReceivingUser.DocumentInfo.Number = receiving.DocumentInfo.Number
+	status.ReceivingUser.Account.BankCode = receiving.Account.BankCode
+	status.ReceivingUser.Account.Type = receiving.Account.Type
+	status.ReceivingUser.Account.Number = receiving.Account.Number
+	status.Status = StatusPending
+
+	f.transactions[status.TransactionId] = transaction
+
+	writeFakeJSON(w, http.StatusOK, TransactionOtpResponse{
+		TransactionId:   status.TransactionId,
+		OperationSecret: transaction.operationSecret,
+	})
+}
+
+func (f *FakeServer) handleTransactionStatus(w http.ResponseWriter, r *http.Request) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	transaction, exists := f.transactions[r.PathValue("id")]
+	if !exists {
+		writeFakeError(w, http.StatusNotFound, "transaction not found")
+		return
+	}
+
+	if r.Header.Get("Operation-Secret") != transaction.operationSecret {
+		writeFakeError(w, http.StatusForbidden, "invalid operation secret")
+		return
+	}
+
+	// La transacción queda pendiente durante algunas consultas antes de finalizar
+	if !transaction.status.IsFinal() {
+		if transaction.polls > 0 {
+			transaction.polls--
+		} else {
+			transaction.status.Status = transaction.finalStatus
+			if transaction.finalStatus == StatusAccepted {
+				transaction.status.RefIbp = fmt.Sprintf("%012d", time.Now().UnixNano()%1_000_000_000_000)
+			} else {
+				transaction.status.RejectedCode = FakeRejectedCode
+			}
+		}
+	}
+
+	writeFakeJSON(w, http.StatusOK, transaction.status)
+}
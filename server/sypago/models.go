@@ -0,0 +1,157 @@
+package sypago
+
+// Estados de una transacción en SyPago
+const (
+	StatusPending    = "PEND"
+	StatusProcessing = "PROC"
+	StatusInProcess  = "AC00"
+	StatusAccepted   = "ACCP"
+	StatusRejected   = "RJCT"
+)
+
+// TokenRequest representa el request del endpoint de autenticación
+type TokenRequest struct {
+	ClientId string `json:"client_id"`
+	ApiKey   string `json:"secret"`
+}
+
+// TokenResponse representa la respuesta del endpoint de autenticación
+type TokenResponse struct {
+	AccessToken      string      `json:"access_token"`
+	ExpiresIn        int         `json:"expires_in"`
+	RefreshExpiresIn int         `json:"refresh_expires_in"`
+	RefreshToken     interface{} `json:"refresh_token"`
+	TokenType        string      `json:"token_type"`
+	NotBeforePolicy  int         `json:"not_before_policy"`
+	SessionState     interface{} `json:"session_state"`
+	Scope            string      `json:"scope"`
+}
+
+// Bank representa la estructura completa del banco desde SyPago API
+type Bank struct {
+	Code                      string `json:"Code"`
+	Name                      string `json:"Name"`
+	Active                    bool   `json:"Active"`
+	SypagoClient              bool   `json:"SypagoClient"`
+	EnableTransitionAccount   bool   `json:"EnableTransitionAccount"`
+	VerifyType                int    `json:"VerifyType"`
+	IsSmsOtp                  bool   `json:"IsSmsOtp"`
+	SmsOtpAddress             string `json:"SmsOtpAddress"`
+	SmsOtpText                string `json:"SmsOtpText"`
+	IsDebitOTP                bool   `json:"IsDebitOTP"`
+	DisabledValidationAccount bool   `json:"DisabledValidationAccount"`
+}
+
+// Account representa una cuenta bancaria
+type Account struct {
+	BankCode string `json:"bank_code"`
+	Type     string `json:"type"`
+	Number   string `json:"number"`
+}
+
+// DocumentInfo representa información del documento de identidad
+type DocumentInfo struct {
+	Type   string `json:"type"`
+	Number string `json:"number"`
+}
+
+// Amount representa un monto con moneda
+type Amount struct {
+	Amt      float64 `json:"amt"`
+	Currency string  `json:"currency"`
+}
+
+// RequestOtpRequest representa el request completo para solicitar OTP
+type RequestOtpRequest struct {
+	CreditorAccount     Account      `json:"creditor_account"`
+	DebitorDocumentInfo DocumentInfo `json:"debitor_document_info"`
+	DebitorAccount      Account      `json:"debitor_account"`
+	Amount              Amount       `json:"amount"`
+}
+
+// AmountWithRate representa un monto con información de tasa
+type AmountWithRate struct {
+	Amt        float64 `json:"amt"`
+	Currency   string  `json:"currency"`
+	UseDayRate bool    `json:"use_day_rate"`
+}
+
+// NotificationUrls representa las URLs de notificación
+type NotificationUrls struct {
+	WebHookEndpoint string `json:"web_hook_endpoint"`
+}
+
+// ReceivingUser representa el usuario receptor de la transacción
+type ReceivingUser struct {
+	Name         string       `json:"name"`
+	Otp          string       `json:"otp,omitempty"` // solo en los débitos
+	DocumentInfo DocumentInfo `json:"document_info"`
+	Account      Account      `json:"account"`
+}
+
+// TransactionOtpRequest representa el request completo para transacción OTP (formato SyPago)
+type TransactionOtpRequest struct {
+	InternalId       string           `json:"internal_id"`
+	GroupId          string           `json:"group_id"`
+	Account          Account          `json:"account"`
+	Amount           AmountWithRate   `json:"amount"`
+	Concept          string           `json:"concept"`
+	NotificationUrls NotificationUrls `json:"notification_urls"`
+	ReceivingUser    ReceivingUser    `json:"receiving_user"`
+}
+
+// TransactionOtpResponse representa la respuesta de SyPago para transaction/otp
+type TransactionOtpResponse struct {
+	TransactionId   string `json:"transaction_id"`
+	OperationSecret string `json:"operation_secret"`
+}
+
+// TransactionCreditRequest representa el request de un crédito inmediato desde la cuenta del
+// comercio (Account) a la cuenta del beneficiario (ReceivingUser, sin OTP)
+type TransactionCreditRequest struct {
+	InternalId       string           `json:"internal_id"`
+	GroupId          string           `json:"group_id"`
+	Account          Account          `json:"account"`
+	Amount           AmountWithRate   `json:"amount"`
+	Concept          string           `json:"concept"`
+	NotificationUrls NotificationUrls `json:"notification_urls"`
+	ReceivingUser    ReceivingUser    `json:"receiving_user"`
+}
+
+// TransactionStatus representa la respuesta completa de SyPago al consultar una transacción.
+// Es también el cuerpo de las notificaciones que SyPago envía al webhook.
+type TransactionStatus struct {
+	InternalId    string `json:"internal_id"`
+	TransactionId string `json:"transaction_id"`
+	RefIbp        string `json:"ref_ibp"`
+	GroupId       string `json:"group_id"`
+	OperationDate string `json:"operation_date"`
+	Amount        struct {
+		Type       string  `json:"type"`
+		Amt        float64 `json:"amt"`
+		PayAmt     float64 `json:"pay_amt"`
+		Currency   string  `json:"currency"`
+		Rate       float64 `json:"rate"`
+		UseDayRate bool    `json:"use_day_rate"`
+	} `json:"amount"`
+	ReceivingUser struct {
+		Name         string `json:"name"`
+		DocumentInfo struct {
+			Type   string `json:"type"`
+			Number string `json:"number"`
+		} `json:"document_info"`
+		Account struct {
+			BankCode string `json:"bank_code"`
+			Type     string `json:"type"`
+			Number   string `json:"number"`
+		} `json:"account"`
+	} `json:"receiving_user"`
+	Status       string `json:"status"`
+	RejectedCode string `json:"rejected_code"`
+	Expiration   int    `json:"expiration"`
+}
+
+// IsFinal indica si SyPago ya aceptó o rechazó la transacción
+func (s TransactionStatus) IsFinal() bool {
+	return s.Status == StatusAccepted || s.Status == StatusRejected
+}
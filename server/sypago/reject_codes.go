@@ -0,0 +1,81 @@
+package sypago
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// bundledRejectCodes son los códigos de rechazo incluidos en el ejecutable
+//
+//go:embed reject_codes.json
+var bundledRejectCodes []byte
+
+// RejectCode es un código de rechazo de SyPago con su descripción para el usuario
+type RejectCode struct {
+	Code        string `json:"code"`
+	Description string `json:"description"`
+}
+
+// RejectCatalog traduce los códigos de rechazo de SyPago a descripciones en español
+type RejectCatalog struct {
+	codes        []RejectCode
+	descriptions map[string]string
+}
+
+// LoadRejectCatalog carga los códigos incluidos en el ejecutable. Si path no está vacío y el
+// archivo existe, sus códigos agregan o reemplazan a los incluidos.
+func LoadRejectCatalog(path string) (*RejectCatalog, error) {
+	var codes []RejectCode
+	if err := json.Unmarshal(bundledRejectCodes, &codes); err != nil {
+		return nil, fmt.Errorf("error parsing bundled reject codes: %v", err)
+	}
+
+	if path != "" {
+		content, err := os.ReadFile(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("error reading reject codes file: %v", err)
+		}
+
+		if err == nil {
+			var overrides []RejectCode
+			if err := json.Unmarshal(content, &overrides); err != nil {
+				return nil, fmt.Errorf("error parsing reject codes file %s: %v", path, err)
+			}
+			codes = append(codes, overrides...)
+		}
+	}
+
+	catalog := &RejectCatalog{descriptions: make(map[string]string)}
+	for _, rejectCode := range codes {
+		code := strings.ToUpper(strings.TrimSpace(rejectCode.Code))
+		if code == "" || rejectCode.Description == "" {
+			continue
+		}
+		catalog.descriptions[code] = rejectCode.Description
+	}
+
+	for code, description := range catalog.descriptions {
+		catalog.codes = append(catalog.codes, RejectCode{Code: code, Description: description})
+	}
+	sort.Slice(catalog.codes, func(i, j int) bool {
+		return catalog.codes[i].Code < catalog.codes[j].Code
+	})
+
+	return catalog, nil
+}
+
+// Codes devuelve todos los códigos ordenados
+func (c *RejectCatalog) Codes() []RejectCode {
+	return c.codes
+}
+
+// Describe devuelve la descripción de un código de rechazo
+func (c *RejectCatalog) Describe(code string) (string, bool) {
+	description, exists := c.descriptions[strings.ToUpper(strings.TrimSpace(code))]
+	return description, exists
+}
@@ -0,0 +1,167 @@
+package sypago
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// tokenRefreshMargin descarta el token un poco antes de su expiración real
+const tokenRefreshMargin = 5 * time.Minute
+
+// tokenRefreshFraction limita el margen a una fracción de la vida del token, para que un
+// token de pocos minutos no se considere expirado desde que se emite
+const tokenRefreshFraction = 4
+
+// refreshMargin devuelve cuánto antes de expirar se descarta un token que dura ttl
+func refreshMargin(ttl time.Duration) time.Duration {
+	return min(tokenRefreshMargin, ttl/tokenRefreshFraction)
+}
+
+// tokenFlight es una autenticación en curso; las peticiones que llegan mientras tanto
+// esperan su resultado en lugar de autenticarse otra vez
+type tokenFlight struct {
+	credentials string
+	done        chan struct{}
+	token       string
+	err         error
+}
+
+// tokenCache almacena el token y su información de expiración
+type tokenCache struct {
+	token       string
+	refreshAt   time.Time // momento en que se descarta el token, antes de su expiración real
+	credentials string    // huella de las credenciales con las que se obtuvo el token
+	inflight    *tokenFlight
+	mutex       sync.Mutex
+}
+
+// valid indica si el token no ha expirado y fue obtenido con las credenciales vigentes.
+// Se llama con el mutex tomado.
+func (tc *tokenCache) valid(credentials string) bool {
+	return tc.token != "" && tc.credentials == credentials &&
+		time.Now().Before(tc.refreshAt)
+}
+
+// acquire devuelve el token vigente o la autenticación en curso a la que hay que unirse.
+// Si no hay ninguna, registra una nueva y devuelve leader en true: quien la recibe debe
+// autenticarse y publicar el resultado con finish.
+func (tc *tokenCache) acquire(credentials string) (token string, flight *tokenFlight, leader bool) {
+	tc.mutex.Lock()
+	defer tc.mutex.Unlock()
+
+	if tc.valid(credentials) {
+		return tc.token, nil, false
+	}
+
+	if tc.inflight != nil && tc.inflight.credentials == credentials {
+		return "", tc.inflight, false
+	}
+
+	tc.inflight = &tokenFlight{
+		credentials: credentials,
+		done:        make(chan struct{}),
+	}
+
+	return "", tc.inflight, true
+}
+
+// finish guarda el token obtenido y despierta a las peticiones que lo esperaban
+func (tc *tokenCache) finish(flight *tokenFlight, response *TokenResponse, err error) {
+	tc.mutex.Lock()
+	defer tc.mutex.Unlock()
+
+	if err == nil {
+		flight.token = response.AccessToken
+		tc.token = response.AccessToken
+		ttl := time.Duration(response.ExpiresIn) * time.Second
+		tc.refreshAt = time.Now().Add(ttl - refreshMargin(ttl))
+		tc.credentials = flight.credentials
+	}
+	flight.err = err
+
+	if tc.inflight == flight {
+		tc.inflight = nil
+	}
+	close(flight.done)
+}
+
+// discard invalida el token solo si sigue siendo el indicado, para no descartar uno
+// que otra petición acaba de renovar
+func (tc *tokenCache) discard(token string) {
+	tc.mutex.Lock()
+	defer tc.mutex.Unlock()
+
+	if tc.token == token {
+		tc.clear()
+	}
+}
+
+// clear invalida el token actual. Se llama con el mutex tomado.
+func (tc *tokenCache) clear() {
+	tc.token = ""
+	tc.refreshAt = time.Time{}
+	tc.credentials = ""
+}
+
+// token obtiene un token válido de SyPago (desde cache o nuevo). Si la configuración
+// cambió, el token anterior se descarta. Cuando el token expira, una ráfaga de
+// peticiones provoca una sola autenticación.
+func (c *HTTPClient) token(ctx context.Context, settings Settings) (string, error) {
+	token, flight, leader := c.tokens.acquire(settings.fingerprint())
+	if flight == nil {
+		return token, nil
+	}
+
+	if leader {
+		// La autenticación no depende de la petición que la inició: si esa petición se
+		// cancela, las demás siguen esperando el token
+		response, err := c.authenticate(context.WithoutCancel(ctx), settings)
+		c.tokens.finish(flight, response, err)
+	}
+
+	select {
+	case <-flight.done:
+		return flight.token, flight.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// authenticate realiza la autenticación con SyPago API
+func (c *HTTPClient) authenticate(ctx context.Context, settings Settings) (*TokenResponse, error) {
+	fmt.Println("Obtaining new SyPago token...")
+
+	var tokenResponse TokenResponse
+	payload := TokenRequest{
+		ClientId: settings.ClientId,
+		ApiKey:   settings.ApiKey,
+	}
+
+	err := c.send(ctx, settings, call{
+		operation: "auth",
+		method:    http.MethodPost,
+		path:      "/api/v1/auth/token",
+		timeout:   settings.RequestTimeout,
+		payload:   payload,
+	}, &tokenResponse)
+	if err != nil {
+		return nil, fmt.Errorf("failed to authenticate with SyPago: %w", err)
+	}
+
+	fmt.Printf("New SyPago token obtained, expires in %d seconds\n", tokenResponse.ExpiresIn)
+
+	return &tokenResponse, nil
+}
+
+// InvalidateToken invalida el token actual para forzar una nueva autenticación
+func (c *HTTPClient) InvalidateToken() {
+	c.tokens.mutex.Lock()
+	defer c.tokens.mutex.Unlock()
+
+	c.tokens.clear()
+
+	fmt.Println("SyPago token invalidated")
+}
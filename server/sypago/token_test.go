@@ -0,0 +1,51 @@
+package sypago
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRefreshMarginIsCappedForShortTokens(t *testing.T) {
+	cases := []struct {
+		ttl  time.Duration
+		want time.Duration
+	}{
+		{time.Hour, tokenRefreshMargin},
+		{20 * time.Minute, tokenRefreshMargin},
+		{5 * time.Minute, 75 * time.Second},
+		{time.Minute, 15 * time.Second},
+		{0, 0},
+	}
+
+	for _, tc := range cases {
+		if got := refreshMargin(tc.ttl); got != tc.want {
+			t.Errorf("refreshMargin(%s) = %s, want %s", tc.ttl, got, tc.want)
+		}
+	}
+}
+
+func TestShortLivedTokenIsReusedUntilItsMargin(t *testing.T) {
+	fake, client := newFakeClient(t)
+	fake.SetTokenTTL(2 * time.Second)
+	ctx := context.Background()
+
+	for range 3 {
+		if _, err := client.Banks(ctx); err != nil {
+			t.Fatalf("Banks: %v", err)
+		}
+	}
+	if got := fake.AuthRequests(); got != 1 {
+		t.Fatalf("auth requests = %d, want 1 while the token is valid", got)
+	}
+
+	// Pasado el margen el token se renueva antes de que SyPago lo rechace
+	time.Sleep(1600 * time.Millisecond)
+
+	if _, err := client.Banks(ctx); err != nil {
+		t.Fatalf("Banks: %v", err)
+	}
+	if got := fake.AuthRequests(); got != 2 {
+		t.Fatalf("auth requests = %d, want 2 after the margin", got)
+	}
+}
@@ -146,6 +146,34 @@ export async function requestDebitOtp(payload: RequestOtpPayload): Promise<void>
   }
 }
 
+// Intentos ante errores de red al enviar el débito y espera base entre ellos
+const DEBIT_NETWORK_ATTEMPTS = 3;
+const DEBIT_RETRY_DELAY_MS = 500;
+
+/**
+ * Envía el débito y lo reintenta con la misma Idempotency-Key solo si falla la red
+ */
+async function postDebit(url: string, body: string, idempotencyKey: string): Promise<Response> {
+  for (let attempt = 1; ; attempt++) {
+    try {
+      return await fetch(url, {
+        method: 'POST',
+        headers: {
+          'Content-Type': 'application/json',
+          'Idempotency-Key': idempotencyKey,
+        },
+        body,
+      });
+    } catch (error) {
+      if (attempt >= DEBIT_NETWORK_ATTEMPTS) {
+        throw error;
+      }
+      logger.error('Error de red al procesar el débito, reintentando', error, { service: 'Payments' });
+      await new Promise(resolve => setTimeout(resolve, DEBIT_RETRY_DELAY_MS * attempt));
+    }
+  }
+}
+
 /**
  * Procesa el débito y retorna el transaction_id generado por SyPago
  * Este transaction_id identifica el intento de débito en SyPago
@@ -154,13 +182,12 @@ export async function processDebit(payload: ProcessDebitPayload): Promise<Proces
   const url = API_ENDPOINTS.payments.processDebit();
   logger.request('POST', url, payload, { service: 'Payments' });
   
-  const response = await fetch(url, {
-    method: 'POST',
-    headers: {
-      'Content-Type': 'application/json',
-    },
-    body: JSON.stringify(payload),
-  });
+  // Cada envío del OTP es un intento nuevo con su propia clave; solo los reintentos de red de este
+  // intento la repiten, así el servidor devuelve la respuesta del primer débito en lugar de cobrar otra vez
+  const idempotencyKey = crypto.randomUUID();
+  const body = JSON.stringify(payload);
+  
+  const response = await postDebit(url, body, idempotencyKey);
   
   const data = await response.json();
   logger.response('POST', url, response.status, data, { service: 'Payments' });
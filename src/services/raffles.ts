@@ -1,4 +1,4 @@
-import type { IRafflesService, RaffleSummary, RaffleParticipant, RaffleParticipantResponse, RaffleVerifyRequest, RaffleVerifyResult } from '../types/raffles';
+import type { IRafflesService, RaffleSummary, RaffleTicket, RaffleParticipant, RaffleParticipantResponse, RaffleVerifyRequest, RaffleVerifyResult } from '../types/raffles';
 import { API_ENDPOINTS } from '../config/api';
 import { logger } from './logger';
 
@@ -20,7 +20,7 @@ export const rafflesService: IRafflesService = {
     return data;
   },
 
-  async getSoldTickets(raffleId: string, signal?: AbortSignal): Promise<number[]> {
+  async getSoldTickets(raffleId: string, signal?: AbortSignal): Promise<RaffleTicket[]> {
     const url = API_ENDPOINTS.raffles.soldTickets(raffleId);
     logger.request('GET', url, undefined, { service: 'Raffles' });
     
@@ -34,7 +34,8 @@ export const rafflesService: IRafflesService = {
       throw new Error(`Error al obtener los tickets vendidos. Código: ${response.status}`);
     }
     
-    return data;
+    // El backend devuelve cada ticket ocupado con su estado (sold | reserved)
+    return data as RaffleTicket[];
   },
 
   async createParticipant(
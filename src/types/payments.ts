@@ -1,6 +1,10 @@
 export type Bank = {
   code: string;
   name: string;
+  verifyType?: number;
+  isSmsOtp?: boolean;
+  smsOtpAddress?: string;
+  smsOtpText?: string;
 };
 
 export type RejectCode = {
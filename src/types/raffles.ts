@@ -55,6 +55,7 @@ export interface RaffleVerifyTicket {
   ticketNumber: number;
   isMainPrize?: boolean;
   isBlessNumber?: boolean;
+  purchasedAt?: string;
 }
 
 export interface RaffleVerifyResult {
@@ -67,7 +68,7 @@ export interface RaffleVerifyResult {
 
 export interface IRafflesService {
   getRaffles(signal?: AbortSignal): Promise<RaffleSummary[]>;
-  getSoldTickets(raffleId: RaffleId, signal?: AbortSignal): Promise<number[]>;
+  getSoldTickets(raffleId: RaffleId, signal?: AbortSignal): Promise<RaffleTicket[]>;
   createParticipant(participant: RaffleParticipant, signal?: AbortSignal): Promise<RaffleParticipantResponse>;
   verifyRaffle(request: RaffleVerifyRequest, signal?: AbortSignal): Promise<RaffleVerifyResult>;
   getMainWinnerTickets(raffleId: RaffleId, signal?: AbortSignal): Promise<number[]>;
@@ -2,18 +2,20 @@ import type { RaffleTicket, RaffleSummary, RaffleDetail, RaffleTicketStatus } fr
 
 /**
  * Genera todos los tickets de una rifa basándose en initialTicket y ticketsTotal
- * y marca como vendidos los números que están en la lista de soldNumbers
+ * y marca cada número ocupado con el estado que devuelve el backend (sold | reserved)
  */
 export function generateRaffleTickets(
   raffle: RaffleSummary,
-  soldNumbers: number[] = []
+  takenTickets: RaffleTicket[] = []
 ): RaffleTicket[] {
   const tickets: RaffleTicket[] = [];
-  const soldSet = new Set(soldNumbers);
+  const takenStatus = new Map<number, RaffleTicketStatus>(
+    takenTickets.map((ticket) => [ticket.number, ticket.status])
+  );
   
   for (let i = 0; i < raffle.ticketsTotal; i++) {
     const ticketNumber = raffle.initialTicket + i;
-    const status: RaffleTicketStatus = soldSet.has(ticketNumber) ? 'sold' : 'available';
+    const status: RaffleTicketStatus = takenStatus.get(ticketNumber) ?? 'available';
     
     tickets.push({
       raffleId: raffle.id,
@@ -30,18 +32,18 @@ export function generateRaffleTickets(
  */
 export function buildRaffleDetail(
   raffle: RaffleSummary,
-  soldNumbers: number[] = []
+  takenTickets: RaffleTicket[] = []
 ): RaffleDetail {
   return {
     ...raffle,
-    tickets: generateRaffleTickets(raffle, soldNumbers),
+    tickets: generateRaffleTickets(raffle, takenTickets),
   };
 }
 
 /**
- * Calcula el número de tickets vendidos basándose en la lista de números vendidos
+ * Calcula el número de tickets vendidos; los reservados todavía pueden liberarse
  */
-export function getTicketsSold(soldNumbers: number[]): number {
-  return soldNumbers.length;
+export function getTicketsSold(takenTickets: RaffleTicket[]): number {
+  return takenTickets.filter((ticket) => ticket.status === 'sold').length;
 }
 